@@ -0,0 +1,526 @@
+package nrpe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // required by the legacy nrpe cipher suites
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net"
+)
+
+/*
+ * legacy nrpe servers without certificates only support the anonymous diffie hellman
+ * cipher suites (ADH), which are not available in the go tls library. This is a minimal
+ * tls 1.2 client implementing the DH_anon key exchange with aes cbc encryption.
+ *
+ * tls 1.2 is explained here:
+ * https://www.rfc-editor.org/rfc/rfc5246
+ */
+
+const (
+	tlsVersion10 = 0x0301
+	tlsVersion12 = 0x0303
+
+	tlsMaxPlaintext  = 16384
+	tlsMaxCiphertext = tlsMaxPlaintext + 2048
+
+	recordTypeChangeCipherSpec = 20
+	recordTypeAlert            = 21
+	recordTypeHandshake        = 22
+	recordTypeApplicationData  = 23
+
+	handshakeTypeClientHello       = 1
+	handshakeTypeServerHello       = 2
+	handshakeTypeServerKeyExchange = 12
+	handshakeTypeServerHelloDone   = 14
+	handshakeTypeClientKeyExchange = 16
+	handshakeTypeFinished          = 20
+
+	alertLevelWarning    = 1
+	alertCloseNotify     = 0
+	tlsRenegotiationSCSV = 0x00ff
+
+	masterSecretLength = 48
+	verifyDataLength   = 12
+)
+
+// adhCipherSuite contains the parameters of a supported anonymous cipher suite.
+type adhCipherSuite struct {
+	id     uint16
+	keyLen int
+	macLen int
+	mac    func() hash.Hash
+}
+
+// adhCipherSuites lists the supported cipher suites in order of preference.
+var adhCipherSuites = []adhCipherSuite{
+	{0x006d, 32, sha256.Size, sha256.New}, // TLS_DH_anon_WITH_AES_256_CBC_SHA256
+	{0x006c, 16, sha256.Size, sha256.New}, // TLS_DH_anon_WITH_AES_128_CBC_SHA256
+	{0x003a, 32, sha1.Size, sha1.New},     // TLS_DH_anon_WITH_AES_256_CBC_SHA
+	{0x0034, 16, sha1.Size, sha1.New},     // TLS_DH_anon_WITH_AES_128_CBC_SHA
+}
+
+// adhHalfConn contains the encryption state of one direction.
+type adhHalfConn struct {
+	block  cipher.Block
+	macKey []byte
+	seq    uint64
+}
+
+// ADHConn is a tls connection using anonymous diffie hellman key exchange.
+// Anonymous connections are encrypted but not authenticated, so they do not
+// protect against man in the middle attacks.
+type ADHConn struct {
+	net.Conn
+
+	suite      *adhCipherSuite
+	transcript []byte
+	handshake  []byte
+	readBuf    []byte
+	in         *adhHalfConn
+	out        *adhHalfConn
+}
+
+// ADHClient returns a new anonymous tls connection and runs the handshake.
+func ADHClient(conn net.Conn) (*ADHConn, error) {
+	adh := &ADHConn{Conn: conn}
+	if err := adh.runHandshake(); err != nil {
+		return nil, fmt.Errorf("adh handshake failed: %s", err.Error())
+	}
+
+	return adh, nil
+}
+
+// Read reads decrypted application data.
+func (c *ADHConn) Read(buf []byte) (int, error) {
+	for len(c.readBuf) == 0 {
+		recordType, data, err := c.readRecord()
+		if err != nil {
+			return 0, err
+		}
+		switch recordType {
+		case recordTypeApplicationData:
+			c.readBuf = data
+		case recordTypeHandshake:
+			// renegotiation is not supported, ignore hello requests
+			continue
+		default:
+			return 0, fmt.Errorf("unexpected tls record type %d", recordType)
+		}
+	}
+
+	n := copy(buf, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+
+	return n, nil
+}
+
+// Write encrypts and sends application data.
+func (c *ADHConn) Write(buf []byte) (int, error) {
+	written := 0
+	for len(buf) > 0 {
+		size := min(len(buf), tlsMaxPlaintext)
+		if err := c.writeRecord(recordTypeApplicationData, buf[:size]); err != nil {
+			return written, err
+		}
+		written += size
+		buf = buf[size:]
+	}
+
+	return written, nil
+}
+
+// Close sends a close notify alert and closes the connection.
+func (c *ADHConn) Close() error {
+	if c.out != nil {
+		_ = c.writeRecord(recordTypeAlert, []byte{alertLevelWarning, alertCloseNotify})
+	}
+
+	return c.Conn.Close()
+}
+
+func (c *ADHConn) runHandshake() error {
+	clientRandom := make([]byte, 32)
+	if _, err := rand.Read(clientRandom); err != nil {
+		return fmt.Errorf("random: %s", err.Error())
+	}
+
+	// client hello
+	hello := binary.BigEndian.AppendUint16(nil, tlsVersion12)
+	hello = append(hello, clientRandom...)
+	hello = append(hello, 0) // empty session id
+
+	// cipher suites plus the renegotiation scsv
+	hello = binary.BigEndian.AppendUint16(hello, uint16(2*(len(adhCipherSuites)+1))) //nolint:gosec // list is short
+	for _, suite := range adhCipherSuites {
+		hello = binary.BigEndian.AppendUint16(hello, suite.id)
+	}
+	hello = binary.BigEndian.AppendUint16(hello, tlsRenegotiationSCSV)
+	hello = append(hello, 1, 0) // null compression only
+	if err := c.writeHandshake(handshakeTypeClientHello, hello); err != nil {
+		return err
+	}
+
+	// server hello
+	msg, err := c.readHandshake(handshakeTypeServerHello)
+	if err != nil {
+		return err
+	}
+	serverRandom, err := c.parseServerHello(msg)
+	if err != nil {
+		return err
+	}
+
+	// server key exchange with the dh parameters
+	msg, err = c.readHandshake(handshakeTypeServerKeyExchange)
+	if err != nil {
+		return err
+	}
+	prime, generator, serverPublic, err := parseServerKeyExchange(msg)
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.readHandshake(handshakeTypeServerHelloDone); err != nil {
+		return err
+	}
+
+	// client key exchange
+	private, err := rand.Int(rand.Reader, new(big.Int).Sub(prime, big.NewInt(3)))
+	if err != nil {
+		return fmt.Errorf("random: %s", err.Error())
+	}
+	private.Add(private, big.NewInt(2))
+	clientPublic := new(big.Int).Exp(generator, private, prime).Bytes()
+	preMaster := new(big.Int).Exp(serverPublic, private, prime).Bytes()
+
+	exchange := binary.BigEndian.AppendUint16(nil, uint16(len(clientPublic))) //nolint:gosec // size is checked when parsing the prime
+	exchange = append(exchange, clientPublic...)
+	if err = c.writeHandshake(handshakeTypeClientKeyExchange, exchange); err != nil {
+		return err
+	}
+
+	masterSecret := tlsPRF(preMaster, "master secret", concat(clientRandom, serverRandom), masterSecretLength)
+	keyBlock := tlsPRF(masterSecret, "key expansion", concat(serverRandom, clientRandom), 2*c.suite.macLen+2*c.suite.keyLen)
+	clientMAC, keyBlock := keyBlock[:c.suite.macLen], keyBlock[c.suite.macLen:]
+	serverMAC, keyBlock := keyBlock[:c.suite.macLen], keyBlock[c.suite.macLen:]
+	clientKey, keyBlock := keyBlock[:c.suite.keyLen], keyBlock[c.suite.keyLen:]
+	serverKey := keyBlock[:c.suite.keyLen]
+
+	out, err := newADHHalfConn(clientKey, clientMAC)
+	if err != nil {
+		return err
+	}
+	in, err := newADHHalfConn(serverKey, serverMAC)
+	if err != nil {
+		return err
+	}
+
+	// switch to encryption and send finished message
+	if err = c.writeRecord(recordTypeChangeCipherSpec, []byte{1}); err != nil {
+		return err
+	}
+	c.out = out
+	clientVerify := tlsPRF(masterSecret, "client finished", hashSum(c.transcript), verifyDataLength)
+	if err = c.writeHandshake(handshakeTypeFinished, clientVerify); err != nil {
+		return err
+	}
+
+	// server switches to encryption as well
+	recordType, data, err := c.readRecord()
+	if err != nil {
+		return err
+	}
+	if recordType != recordTypeChangeCipherSpec || len(data) != 1 || data[0] != 1 {
+		return fmt.Errorf("expected change cipher spec, got record type %d", recordType)
+	}
+	c.in = in
+
+	serverVerify := tlsPRF(masterSecret, "server finished", hashSum(c.transcript), verifyDataLength)
+	msg, err = c.readHandshake(handshakeTypeFinished)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(msg, serverVerify) {
+		return fmt.Errorf("server finished verification failed")
+	}
+
+	c.transcript = nil
+
+	return nil
+}
+
+func (c *ADHConn) parseServerHello(msg []byte) (serverRandom []byte, err error) {
+	if len(msg) < 38 {
+		return nil, fmt.Errorf("server hello too short")
+	}
+	if version := binary.BigEndian.Uint16(msg[0:2]); version != tlsVersion12 {
+		return nil, fmt.Errorf("unsupported tls version 0x%04x, anonymous diffie hellman requires tls 1.2", version)
+	}
+	serverRandom = msg[2:34]
+	sessionLength := int(msg[34])
+	if len(msg) < 35+sessionLength+3 {
+		return nil, fmt.Errorf("server hello too short")
+	}
+	suiteID := binary.BigEndian.Uint16(msg[35+sessionLength:])
+	for i := range adhCipherSuites {
+		if adhCipherSuites[i].id == suiteID {
+			c.suite = &adhCipherSuites[i]
+		}
+	}
+	if c.suite == nil {
+		return nil, fmt.Errorf("server selected unsupported cipher suite 0x%04x", suiteID)
+	}
+	if msg[35+sessionLength+2] != 0 {
+		return nil, fmt.Errorf("server selected unsupported compression")
+	}
+
+	return serverRandom, nil
+}
+
+func parseServerKeyExchange(msg []byte) (prime, generator, public *big.Int, err error) {
+	values := make([]*big.Int, 0, 3)
+	for range 3 {
+		if len(msg) < 2 {
+			return nil, nil, nil, fmt.Errorf("server key exchange too short")
+		}
+		size := int(binary.BigEndian.Uint16(msg))
+		if len(msg) < 2+size {
+			return nil, nil, nil, fmt.Errorf("server key exchange too short")
+		}
+		values = append(values, new(big.Int).SetBytes(msg[2:2+size]))
+		msg = msg[2+size:]
+	}
+	prime, generator, public = values[0], values[1], values[2]
+
+	if prime.BitLen() < 512 {
+		return nil, nil, nil, fmt.Errorf("dh prime too small: %d bits", prime.BitLen())
+	}
+	limit := new(big.Int).Sub(prime, big.NewInt(1))
+	if public.Cmp(big.NewInt(1)) <= 0 || public.Cmp(limit) >= 0 {
+		return nil, nil, nil, fmt.Errorf("invalid dh public key")
+	}
+
+	return prime, generator, public, nil
+}
+
+// writeHandshake sends a handshake message and adds it to the transcript.
+func (c *ADHConn) writeHandshake(msgType byte, body []byte) error {
+	msg := make([]byte, 4, 4+len(body))
+	msg[0] = msgType
+	putUint24(msg[1:4], len(body))
+	msg = append(msg, body...)
+	c.transcript = append(c.transcript, msg...)
+
+	return c.writeRecord(recordTypeHandshake, msg)
+}
+
+// readHandshake returns the next handshake message which must be of given type.
+func (c *ADHConn) readHandshake(msgType byte) ([]byte, error) {
+	for len(c.handshake) < 4 || len(c.handshake) < 4+getUint24(c.handshake[1:4]) {
+		recordType, data, err := c.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		if recordType != recordTypeHandshake {
+			return nil, fmt.Errorf("expected handshake, got record type %d", recordType)
+		}
+		c.handshake = append(c.handshake, data...)
+	}
+
+	size := 4 + getUint24(c.handshake[1:4])
+	msg := c.handshake[:size]
+	c.handshake = c.handshake[size:]
+	c.transcript = append(c.transcript, msg...)
+
+	if msg[0] != msgType {
+		return nil, fmt.Errorf("expected handshake message %d, got %d", msgType, msg[0])
+	}
+
+	return msg[4:], nil
+}
+
+// readRecord reads and decrypts the next record.
+func (c *ADHConn) readRecord() (recordType byte, data []byte, err error) {
+	header := make([]byte, 5)
+	if _, err = io.ReadFull(c.Conn, header); err != nil {
+		return 0, nil, fmt.Errorf("reading tls record failed: %w", err)
+	}
+	recordType = header[0]
+	size := int(binary.BigEndian.Uint16(header[3:5]))
+	if size > tlsMaxCiphertext {
+		return 0, nil, fmt.Errorf("tls record too large: %d", size)
+	}
+	data = make([]byte, size)
+	if _, err = io.ReadFull(c.Conn, data); err != nil {
+		return 0, nil, fmt.Errorf("reading tls record failed: %w", err)
+	}
+
+	if c.in != nil {
+		data, err = c.in.decrypt(c.suite, recordType, data)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if recordType == recordTypeAlert {
+		if len(data) == 2 && data[1] == alertCloseNotify {
+			return 0, nil, io.EOF
+		}
+		if len(data) == 2 {
+			return 0, nil, fmt.Errorf("received tls alert %d", data[1])
+		}
+
+		return 0, nil, fmt.Errorf("received invalid tls alert")
+	}
+
+	return recordType, data, nil
+}
+
+// writeRecord encrypts (if active) and sends a record.
+func (c *ADHConn) writeRecord(recordType byte, data []byte) error {
+	version := uint16(tlsVersion12)
+	if c.suite == nil {
+		// initial client hello uses the lowest version for compatibility
+		version = tlsVersion10
+	}
+
+	if c.out != nil {
+		var err error
+		data, err = c.out.encrypt(c.suite, recordType, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, 5, 5+len(data))
+	record[0] = recordType
+	binary.BigEndian.PutUint16(record[1:3], version)
+	binary.BigEndian.PutUint16(record[3:5], uint16(len(data))) //nolint:gosec // size is limited by tlsMaxCiphertext
+	record = append(record, data...)
+
+	if _, err := c.Conn.Write(record); err != nil {
+		return fmt.Errorf("writing tls record failed: %w", err)
+	}
+
+	return nil
+}
+
+func newADHHalfConn(key, macKey []byte) (*adhHalfConn, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes: %s", err.Error())
+	}
+
+	return &adhHalfConn{block: block, macKey: macKey}, nil
+}
+
+// mac returns the record mac over sequence number, header and payload.
+func (h *adhHalfConn) mac(suite *adhCipherSuite, recordType byte, payload []byte) []byte {
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint64(header[0:8], h.seq)
+	header[8] = recordType
+	binary.BigEndian.PutUint16(header[9:11], tlsVersion12)
+	binary.BigEndian.PutUint16(header[11:13], uint16(len(payload))) //nolint:gosec // size is limited by tlsMaxPlaintext
+
+	mac := hmac.New(suite.mac, h.macKey)
+	mac.Write(header)
+	mac.Write(payload)
+	h.seq++
+
+	return mac.Sum(nil)
+}
+
+func (h *adhHalfConn) encrypt(suite *adhCipherSuite, recordType byte, payload []byte) ([]byte, error) {
+	blockSize := h.block.BlockSize()
+	plain := append(append([]byte{}, payload...), h.mac(suite, recordType, payload)...)
+	padding := (blockSize - (len(plain)+1)%blockSize) % blockSize
+	for range padding + 1 {
+		plain = append(plain, byte(padding))
+	}
+
+	// tls 1.2 uses an explicit random iv for each record
+	record := make([]byte, blockSize+len(plain))
+	if _, err := rand.Read(record[:blockSize]); err != nil {
+		return nil, fmt.Errorf("random: %s", err.Error())
+	}
+	cipher.NewCBCEncrypter(h.block, record[:blockSize]).CryptBlocks(record[blockSize:], plain)
+
+	return record, nil
+}
+
+func (h *adhHalfConn) decrypt(suite *adhCipherSuite, recordType byte, record []byte) ([]byte, error) {
+	blockSize := h.block.BlockSize()
+	if len(record) < 2*blockSize || len(record)%blockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted tls record size %d", len(record))
+	}
+
+	plain := make([]byte, len(record)-blockSize)
+	cipher.NewCBCDecrypter(h.block, record[:blockSize]).CryptBlocks(plain, record[blockSize:])
+
+	padding := int(plain[len(plain)-1])
+	if padding+1+suite.macLen > len(plain) {
+		return nil, fmt.Errorf("bad tls record mac")
+	}
+	for _, b := range plain[len(plain)-padding-1:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("bad tls record mac")
+		}
+	}
+	plain = plain[:len(plain)-padding-1]
+	payload, recordMAC := plain[:len(plain)-suite.macLen], plain[len(plain)-suite.macLen:]
+	if !hmac.Equal(recordMAC, h.mac(suite, recordType, payload)) {
+		return nil, fmt.Errorf("bad tls record mac")
+	}
+
+	return payload, nil
+}
+
+// tlsPRF implements the tls 1.2 pseudo random function using sha256.
+func tlsPRF(secret []byte, label string, seed []byte, length int) []byte {
+	labelSeed := concat([]byte(label), seed)
+	result := make([]byte, 0, length+sha256.Size)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(labelSeed)
+	chain := mac.Sum(nil)
+	for len(result) < length {
+		mac.Reset()
+		mac.Write(chain)
+		mac.Write(labelSeed)
+		result = mac.Sum(result)
+
+		mac.Reset()
+		mac.Write(chain)
+		chain = mac.Sum(nil)
+	}
+
+	return result[:length]
+}
+
+func hashSum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+
+	return sum[:]
+}
+
+func concat(a, b []byte) []byte {
+	return append(append(make([]byte, 0, len(a)+len(b)), a...), b...)
+}
+
+func putUint24(buf []byte, val int) {
+	buf[0] = byte(val >> 16)
+	buf[1] = byte(val >> 8)
+	buf[2] = byte(val)
+}
+
+func getUint24(buf []byte) int {
+	return int(buf[0])<<16 | int(buf[1])<<8 | int(buf[2])
+}
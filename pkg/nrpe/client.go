@@ -0,0 +1,225 @@
+package nrpe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPort is the default nrpe port.
+	DefaultPort = "5666"
+
+	// DefaultTimeout sets the default connect and read timeout.
+	DefaultTimeout = 10 * time.Second
+)
+
+// ADHMode sets if anonymous diffie hellman may be used for ssl connections.
+type ADHMode uint8
+
+const (
+	// ADHDisabled uses regular tls only.
+	ADHDisabled ADHMode = iota
+
+	// ADHAllowed tries regular tls first and falls back to anonymous diffie hellman.
+	// The fallback is only used if the tls config does not verify the server and
+	// does not contain client certificates.
+	ADHAllowed
+
+	// ADHForced always uses anonymous diffie hellman.
+	ADHForced
+)
+
+// Client sends nrpe queries to a remote agent.
+type Client struct {
+	// Address is the host:port of the remote agent, port defaults to 5666.
+	Address string
+
+	// Version sets the packet version (2, 3 or 4). Zero tries v4 and falls back to v2.
+	Version uint16
+
+	// Timeout is used for connecting and reading the response.
+	Timeout time.Duration
+
+	// UseSSL enables tls, TLSConfig is used for regular tls connections.
+	UseSSL    bool
+	TLSConfig *tls.Config
+
+	// ADH sets if anonymous diffie hellman may be used, required for legacy nrpe servers without certificates.
+	// The ADH client supports TLS 1.2 only.
+	ADH ADHMode
+}
+
+// Response contains the result of a nrpe query.
+type Response struct {
+	Version uint16
+	State   uint16
+	Output  string
+}
+
+// NewClient returns a new client with default settings.
+func NewClient(address string) *Client {
+	return &Client{
+		Address: address,
+		Timeout: DefaultTimeout,
+		UseSSL:  true,
+		ADH:     ADHAllowed,
+	}
+}
+
+// Query runs given command with arguments on the remote agent.
+func (c *Client) Query(ctx context.Context, command string, args []string) (*Response, error) {
+	version := c.Version
+	if version == 0 {
+		version = NrpeV4PacketVersion
+	}
+
+	res, sent, err := c.query(ctx, version, command, args)
+	if err != nil && sent && c.Version == 0 && ctx.Err() == nil {
+		// older servers do not understand v4 packets and simply close the connection
+		res, _, err = c.query(ctx, NrpeV2PacketVersion, command, args)
+	}
+
+	return res, err
+}
+
+// query sends a single request, sent is true if the request has been sent successfully.
+func (c *Client) query(ctx context.Context, version uint16, command string, args []string) (res *Response, sent bool, err error) {
+	conn, err := c.Dial(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer conn.Close()
+
+	// BuildPacket answers v3 requests with v2 packets, but a v3 query must use the v3 layout
+	query := []byte(strings.Join(append([]string{command}, args...), "!"))
+	request := BuildPacket(version, NrpeQueryPacket, 0, query)
+	if version == NrpeV3PacketVersion {
+		request = BuildPacketV3(NrpeQueryPacket, 0, query)
+	}
+	if request == nil {
+		return nil, false, fmt.Errorf("nrpe: unsupported packet version %d", version)
+	}
+	if err = request.Write(conn); err != nil {
+		return nil, false, err
+	}
+
+	response, err := ReadNrpePacket(conn)
+	if err != nil {
+		return nil, true, fmt.Errorf("nrpe: reading response failed: %s", err.Error())
+	}
+	if err = response.Verify(NrpeResponsePacket); err != nil {
+		return nil, true, err
+	}
+
+	output, _ := response.Data()
+
+	return &Response{
+		Version: response.Version(),
+		State:   response.StatusCode(),
+		Output:  output,
+	}, true, nil
+}
+
+// Dial connects to the remote agent and runs the ssl handshake if enabled.
+func (c *Client) Dial(ctx context.Context) (net.Conn, error) {
+	address := c.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, DefaultPort)
+	}
+
+	conn, err := c.dialTCP(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.UseSSL {
+		return conn, nil
+	}
+
+	if c.ADH != ADHForced {
+		tlsConn, err := c.tlsHandshake(ctx, conn, address)
+		if err == nil {
+			return tlsConn, nil
+		}
+		conn.Close()
+		if c.ADH == ADHDisabled || !c.adhFallbackAllowed(err) {
+			return nil, err
+		}
+
+		// retry with anonymous diffie hellman
+		conn, err = c.dialTCP(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	adhConn, err := ADHClient(conn)
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("nrpe: %s", err.Error())
+	}
+
+	return adhConn, nil
+}
+
+// adhFallbackAllowed returns true if a failed tls handshake may be retried with anonymous diffie hellman.
+// This is never the case if the server certificate is verified or client certificates are used,
+// otherwise a man in the middle could simply downgrade the connection.
+func (c *Client) adhFallbackAllowed(handshakeErr error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(handshakeErr, &verifyErr) {
+		return false
+	}
+
+	if c.TLSConfig == nil || !c.TLSConfig.InsecureSkipVerify {
+		return false
+	}
+
+	if c.TLSConfig.RootCAs != nil || len(c.TLSConfig.Certificates) > 0 || c.TLSConfig.GetClientCertificate != nil {
+		return false
+	}
+
+	return true
+}
+
+func (c *Client) dialTCP(ctx context.Context, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("nrpe: connecting to %s failed: %s", address, err.Error())
+	}
+
+	if c.Timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(c.Timeout)); err != nil {
+			conn.Close()
+
+			return nil, fmt.Errorf("nrpe: setting deadline failed: %s", err.Error())
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Client) tlsHandshake(ctx context.Context, conn net.Conn, address string) (net.Conn, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.TLSConfig != nil {
+		config = c.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		host, _, _ := net.SplitHostPort(address)
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("nrpe: tls handshake failed: %w", err)
+	}
+
+	return tlsConn, nil
+}
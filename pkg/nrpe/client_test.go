@@ -0,0 +1,248 @@
+package nrpe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer starts a simple nrpe server which returns the request as output.
+// Requests with versions from skipVersions will be closed without response.
+func startTestServer(t *testing.T, listener net.Listener, skipVersions ...uint16) {
+	t.Helper()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				request, err := ReadNrpePacket(conn)
+				if err != nil || request.Verify(NrpeQueryPacket) != nil {
+					return
+				}
+				for _, v := range skipVersions {
+					if request.Version() == v {
+						return
+					}
+				}
+				cmd, args := request.Data()
+				output := fmt.Sprintf("v%d %s %s", request.Version(), cmd, strings.Join(args, ","))
+				response := BuildPacket(request.Version(), NrpeResponsePacket, 1, []byte(output))
+				_ = response.Write(conn)
+			}(conn)
+		}
+	}()
+}
+
+func TestClientPlain(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoErrorf(t, err, "listener started")
+	defer listener.Close()
+	startTestServer(t, listener)
+
+	client := NewClient(listener.Addr().String())
+	client.UseSSL = false
+
+	for _, version := range []uint16{0, 2, 3, 4} {
+		client.Version = version
+		res, err := client.Query(context.TODO(), "check_test", []string{"a=1", "b=2"})
+		require.NoErrorf(t, err, "query v%d", version)
+		expect := version
+		if expect == 0 {
+			expect = NrpeV4PacketVersion
+		}
+		// v3 queries are answered with v2 packets
+		expectResponse := expect
+		if expect == NrpeV3PacketVersion {
+			expectResponse = NrpeV2PacketVersion
+		}
+		assert.Equalf(t, &Response{
+			Version: expectResponse,
+			State:   1,
+			Output:  fmt.Sprintf("v%d check_test a=1,b=2", expect),
+		}, res, "response v%d", version)
+	}
+}
+
+func TestClientFallbackV2(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoErrorf(t, err, "listener started")
+	defer listener.Close()
+	startTestServer(t, listener, NrpeV4PacketVersion)
+
+	client := NewClient(listener.Addr().String())
+	client.UseSSL = false
+
+	res, err := client.Query(context.TODO(), "check_test", nil)
+	require.NoErrorf(t, err, "query with fallback")
+	assert.Equalf(t, "v2 check_test ", res.Output, "fallback to v2")
+
+	client.Version = NrpeV4PacketVersion
+	_, err = client.Query(context.TODO(), "check_test", nil)
+	require.Errorf(t, err, "no fallback with fixed version")
+}
+
+func TestClientTLSClientCertificate(t *testing.T) {
+	serverCert, serverPool := testCertificate(t, "server")
+	clientCert, clientPool := testCertificate(t, "client")
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientPool,
+	})
+	require.NoErrorf(t, err, "listener started")
+	defer listener.Close()
+	startTestServer(t, listener)
+
+	client := NewClient(listener.Addr().String())
+	client.ADH = ADHDisabled
+	client.TLSConfig = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		ServerName:   "server",
+		RootCAs:      serverPool,
+		Certificates: []tls.Certificate{clientCert},
+	}
+
+	res, err := client.Query(context.TODO(), "check_tls", nil)
+	require.NoErrorf(t, err, "query with client certificate")
+	assert.Equalf(t, "v4 check_tls ", res.Output, "tls response")
+
+	// without client certificate
+	client.TLSConfig.Certificates = nil
+	_, err = client.Query(context.TODO(), "check_tls", nil)
+	require.Errorf(t, err, "query without client certificate fails")
+}
+
+func TestClientADH(t *testing.T) {
+	opensslPath, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl not found")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoErrorf(t, err, "port found")
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	// openssl server in reverse mode, it returns each line reversed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, opensslPath, "s_server", "-quiet", "-rev", "-nocert", "-tls1_2",
+		"-cipher", "ADH:@SECLEVEL=0", "-accept", fmt.Sprintf("%d", port))
+	require.NoErrorf(t, cmd.Start(), "openssl started")
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+
+	var conn net.Conn
+	require.Eventuallyf(t, func() bool {
+		conn, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond, "openssl server started")
+	defer conn.Close()
+	require.NoErrorf(t, conn.SetDeadline(time.Now().Add(10*time.Second)), "deadline set")
+
+	adh, err := ADHClient(conn)
+	require.NoErrorf(t, err, "adh handshake")
+
+	_, err = adh.Write([]byte("hello nrpe\n"))
+	require.NoErrorf(t, err, "write")
+
+	buf := make([]byte, 11)
+	_, err = io.ReadFull(adh, buf)
+	require.NoErrorf(t, err, "read")
+	assert.Equalf(t, "eprn olleh\n", string(buf), "decrypted response")
+}
+
+func TestTLSPRF(t *testing.T) {
+	// test vector from https://mailarchive.ietf.org/arch/msg/tls/fzVCzk-z3FShgGJ6DOXqM1ydxms/
+	secret := []byte{0x9b, 0xbe, 0x43, 0x6b, 0xa9, 0x40, 0xf0, 0x17, 0xb1, 0x76, 0x52, 0x84, 0x9a, 0x71, 0xdb, 0x35}
+	seed := []byte{0xa0, 0xba, 0x9f, 0x93, 0x6c, 0xda, 0x31, 0x18, 0x27, 0xa6, 0xf7, 0x96, 0xff, 0xd5, 0x19, 0x8c}
+	expect := []byte{0xe3, 0xf2, 0x29, 0xba, 0x72, 0x7b, 0xe1, 0x7b, 0x8d, 0x12, 0x26, 0x20, 0x55, 0x7c, 0xd4, 0x53}
+
+	assert.Equalf(t, expect, tlsPRF(secret, "test label", seed, 100)[:16], "prf output")
+}
+
+// testCertificate returns a self signed certificate for given name.
+func TestClientADHNoFallbackOnVerification(t *testing.T) {
+	serverCert, _ := testCertificate(t, "server")
+	_, otherPool := testCertificate(t, "other")
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+	})
+	require.NoErrorf(t, err, "listener started")
+	defer listener.Close()
+	startTestServer(t, listener)
+
+	client := NewClient(listener.Addr().String())
+	client.ADH = ADHAllowed
+	client.TLSConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: "server",
+		RootCAs:    otherPool,
+	}
+
+	_, err = client.Query(context.TODO(), "check_tls", nil)
+	require.Errorf(t, err, "untrusted server certificate")
+	assert.Contains(t, err.Error(), "tls handshake failed", "no adh fallback")
+
+	assert.Falsef(t, client.adhFallbackAllowed(fmt.Errorf("eof")), "no fallback with ca")
+
+	client.TLSConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // test only
+	assert.Truef(t, client.adhFallbackAllowed(fmt.Errorf("eof")), "fallback without verification")
+
+	client.TLSConfig.Certificates = []tls.Certificate{serverCert}
+	assert.Falsef(t, client.adhFallbackAllowed(fmt.Errorf("eof")), "no fallback with client certificate")
+}
+
+func testCertificate(t *testing.T, name string) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoErrorf(t, err, "key generated")
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoErrorf(t, err, "certificate created")
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoErrorf(t, err, "certificate parsed")
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
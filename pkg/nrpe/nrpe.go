@@ -143,11 +143,27 @@ func BuildPacketV4(packetType, statusCode uint16, statusLine []byte) *Packet {
 	return packet
 }
 
+// BuildPacketV3 creates new v3 packet structure.
+// v3 uses the same layout as v4 but has a different version number.
+func BuildPacketV3(packetType, statusCode uint16, statusLine []byte) *Packet {
+	packet := BuildPacketV4(packetType, statusCode, statusLine)
+
+	binary.BigEndian.PutUint16(packet.packetVersion, NrpeV3PacketVersion)
+	binary.BigEndian.PutUint32(packet.crc32, packet.BuildCRC32())
+
+	return packet
+}
+
 // Version returns nrpe pkg version.
 func (p *Packet) Version() uint16 {
 	return binary.BigEndian.Uint16(p.packetVersion)
 }
 
+// StatusCode returns the result state of a response packet.
+func (p *Packet) StatusCode() uint16 {
+	return binary.BigEndian.Uint16(p.statusCode)
+}
+
 // Data returns nrpe payload.
 func (p *Packet) Data() (cmd string, args []string) {
 	rpt := binary.BigEndian.Uint16(p.packetType)
@@ -167,7 +183,7 @@ func ReadNrpePacket(conn io.Reader) (*Packet, error) {
 	packet := NewNrpePacket()
 
 	// read first 1036 bytes, all packages have at least this size
-	n, err := conn.Read(packet.all)
+	n, err := io.ReadFull(conn, packet.all)
 	if err != nil {
 		return nil, fmt.Errorf("reading request failed: %s", err.Error())
 	}
@@ -0,0 +1,286 @@
+package nsca
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec // des is part of the nsca protocol
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+ * nsca protocol v2 is explained here
+ * https://github.com/NagiosEnterprises/nsca/blob/master/include/common.h
+ *
+ * reference implementation is here:
+ * https://github.com/NagiosEnterprises/nsca/blob/master/src/send_nsca.c
+ * https://github.com/NagiosEnterprises/nsca/blob/master/src/utils.c
+ */
+
+const (
+	// InitPacketIVSize is the size of the transmitted initialization vector.
+	InitPacketIVSize = 128
+	// InitPacketLength is the size of the initial packet sent by the server.
+	InitPacketLength = InitPacketIVSize + 4
+
+	// PacketVersion is the packet version of the nsca v2 protocol.
+	PacketVersion = 3
+
+	MaxHostnameLength = 64
+	MaxServiceLength  = 128
+	MaxOutputLength   = 512
+
+	// PacketLength is the size of a data packet including alignment bytes.
+	PacketLength = 12 + 2 + MaxHostnameLength + MaxServiceLength + MaxOutputLength + 2
+)
+
+// supported encryption methods, numbers match the send_nsca encryption_method
+const (
+	EncryptNone        = 0
+	EncryptXOR         = 1
+	EncryptDES         = 2
+	Encrypt3DES        = 3
+	EncryptRijndael128 = 14
+)
+
+// ParseEncryption returns the encryption method for given name or number.
+func ParseEncryption(name string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "none", "0":
+		return EncryptNone, nil
+	case "xor", "1":
+		return EncryptXOR, nil
+	case "des", "2":
+		return EncryptDES, nil
+	case "3des", "tripledes", "3":
+		return Encrypt3DES, nil
+	case "aes", "aes256", "rijndael-128", "14":
+		return EncryptRijndael128, nil
+	}
+
+	num, err := strconv.Atoi(name)
+	if err == nil {
+		return 0, fmt.Errorf("unsupported encryption method: %d", num)
+	}
+
+	return 0, fmt.Errorf("unknown encryption method: %s (supported are: none, xor, des, 3des, aes)", name)
+}
+
+// InitPacket contains the initialization vector and timestamp sent by the server.
+type InitPacket struct {
+	IV        []byte
+	Timestamp uint32
+}
+
+// ReadInitPacket reads the initial packet from the wire.
+func ReadInitPacket(conn io.Reader) (*InitPacket, error) {
+	buf := make([]byte, InitPacketLength)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, fmt.Errorf("reading nsca init packet failed: %s", err.Error())
+	}
+
+	return &InitPacket{
+		IV:        buf[0:InitPacketIVSize],
+		Timestamp: binary.BigEndian.Uint32(buf[InitPacketIVSize:]),
+	}, nil
+}
+
+// Bytes returns the init packet in wire format.
+func (p *InitPacket) Bytes() []byte {
+	buf := make([]byte, InitPacketLength)
+	copy(buf, p.IV)
+	binary.BigEndian.PutUint32(buf[InitPacketIVSize:], p.Timestamp)
+
+	return buf
+}
+
+// Packet contains a single passive check result.
+type Packet struct {
+	Timestamp uint32
+	State     uint16
+	Hostname  string
+	Service   string
+	Output    string
+}
+
+// Bytes returns the unencrypted packet in wire format.
+func (p *Packet) Bytes() ([]byte, error) {
+	// fill the packet with random data like send_nsca does
+	buf := make([]byte, PacketLength)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to create random data: %s", err.Error())
+	}
+
+	binary.BigEndian.PutUint16(buf[0:2], PacketVersion)
+	binary.BigEndian.PutUint32(buf[4:8], 0)
+	binary.BigEndian.PutUint32(buf[8:12], p.Timestamp)
+	binary.BigEndian.PutUint16(buf[12:14], p.State)
+	putString(buf[14:14+MaxHostnameLength], p.Hostname)
+	putString(buf[78:78+MaxServiceLength], p.Service)
+	putString(buf[206:206+MaxOutputLength], strings.ReplaceAll(p.Output, "\n", `\n`))
+
+	// checksum is build over the whole packet but with the crc bytes nulled
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(buf))
+
+	return buf, nil
+}
+
+// ParsePacket parses a decrypted data packet and verifies its checksum.
+func ParsePacket(buf []byte) (*Packet, error) {
+	if len(buf) != PacketLength {
+		return nil, fmt.Errorf("nsca: invalid packet size %d", len(buf))
+	}
+	if version := binary.BigEndian.Uint16(buf[0:2]); version != PacketVersion {
+		return nil, fmt.Errorf("nsca: unsupported packet version %d", version)
+	}
+
+	data := make([]byte, PacketLength)
+	copy(data, buf)
+	crc := binary.BigEndian.Uint32(data[4:8])
+	binary.BigEndian.PutUint32(data[4:8], 0)
+	if crc != crc32.ChecksumIEEE(data) {
+		return nil, fmt.Errorf("nsca: checksum failed")
+	}
+
+	return &Packet{
+		Timestamp: binary.BigEndian.Uint32(buf[8:12]),
+		State:     binary.BigEndian.Uint16(buf[12:14]),
+		Hostname:  getString(buf[14 : 14+MaxHostnameLength]),
+		Service:   getString(buf[78 : 78+MaxServiceLength]),
+		Output:    getString(buf[206 : 206+MaxOutputLength]),
+	}, nil
+}
+
+// Send reads the init packet from the connection and sends the encrypted check result.
+func Send(conn io.ReadWriter, method int, password string, packet *Packet) error {
+	initPacket, err := ReadInitPacket(conn)
+	if err != nil {
+		return err
+	}
+
+	packet.Timestamp = initPacket.Timestamp
+	data, err := packet.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := Encrypt(data, method, initPacket.IV, password); err != nil {
+		return err
+	}
+
+	n, err := conn.Write(data)
+	if err != nil {
+		return fmt.Errorf("nsca write failed: %s", err.Error())
+	}
+	if n != len(data) {
+		return fmt.Errorf("nsca: incomplete write")
+	}
+
+	return nil
+}
+
+// Encrypt encrypts the buffer in place.
+func Encrypt(buf []byte, method int, iv []byte, password string) error {
+	return crypt(buf, method, iv, password, false)
+}
+
+// Decrypt decrypts the buffer in place.
+func Decrypt(buf []byte, method int, iv []byte, password string) error {
+	return crypt(buf, method, iv, password, true)
+}
+
+func crypt(buf []byte, method int, iv []byte, password string, decrypt bool) error {
+	switch method {
+	case EncryptNone:
+		return nil
+	case EncryptXOR:
+		for i := range buf {
+			buf[i] ^= iv[i%len(iv)]
+		}
+		if password != "" {
+			for i := range buf {
+				buf[i] ^= password[i%len(password)]
+			}
+		}
+
+		return nil
+	}
+
+	block, err := newBlockCipher(method, password)
+	if err != nil {
+		return err
+	}
+	cfb8(block, iv, buf, decrypt)
+
+	return nil
+}
+
+// newBlockCipher returns the cipher with the password used as zero padded key like libmcrypt does.
+func newBlockCipher(method int, password string) (cipher.Block, error) {
+	var block cipher.Block
+	var err error
+	switch method {
+	case EncryptDES:
+		block, err = des.NewCipher(mcryptKey(password, 8))
+	case Encrypt3DES:
+		block, err = des.NewTripleDESCipher(mcryptKey(password, 24))
+	case EncryptRijndael128:
+		block, err = aes.NewCipher(mcryptKey(password, 32))
+	default:
+		return nil, fmt.Errorf("unsupported encryption method: %d", method)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %s", err.Error())
+	}
+
+	return block, nil
+}
+
+func mcryptKey(password string, size int) []byte {
+	key := make([]byte, size)
+	copy(key, password)
+
+	return key
+}
+
+// cfb8 implements the 8bit cipher feedback mode used by libmcrypt's "cfb" mode.
+func cfb8(block cipher.Block, iv, buf []byte, decrypt bool) {
+	blockSize := block.BlockSize()
+	register := make([]byte, blockSize)
+	copy(register, iv)
+	stream := make([]byte, blockSize)
+	for i := range buf {
+		block.Encrypt(stream, register)
+		in := buf[i]
+		buf[i] ^= stream[0]
+		feedback := buf[i]
+		if decrypt {
+			feedback = in
+		}
+		copy(register, register[1:])
+		register[blockSize-1] = feedback
+	}
+}
+
+func putString(buf []byte, str string) {
+	if len(str) >= len(buf) {
+		str = str[:len(buf)-1]
+	}
+	copy(buf, str)
+	buf[len(str)] = 0
+}
+
+func getString(buf []byte) string {
+	for i, b := range buf {
+		if b == 0 {
+			return string(buf[:i])
+		}
+	}
+
+	return string(buf)
+}
@@ -0,0 +1,66 @@
+package nsca
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConn struct {
+	read  *bytes.Reader
+	write bytes.Buffer
+}
+
+func (c *testConn) Read(p []byte) (int, error) {
+	return c.read.Read(p)
+}
+
+func (c *testConn) Write(p []byte) (int, error) {
+	return c.write.Write(p)
+}
+
+func TestNSCAParseEncryption(t *testing.T) {
+	for name, exp := range map[string]int{"": EncryptNone, "none": EncryptNone, "XOR": EncryptXOR, "3des": Encrypt3DES, "14": EncryptRijndael128} {
+		method, err := ParseEncryption(name)
+		require.NoErrorf(t, err, "parsed %s", name)
+		assert.Equalf(t, exp, method, "parsed %s", name)
+	}
+
+	_, err := ParseEncryption("blowfish")
+	require.Errorf(t, err, "unknown method")
+}
+
+func TestNSCASend(t *testing.T) {
+	iv := make([]byte, InitPacketIVSize)
+	for i := range iv {
+		iv[i] = byte(i * 7)
+	}
+	initPacket := &InitPacket{IV: iv, Timestamp: 1700000000}
+
+	for _, method := range []int{EncryptNone, EncryptXOR, EncryptDES, Encrypt3DES, EncryptRijndael128} {
+		conn := &testConn{read: bytes.NewReader(initPacket.Bytes())}
+		err := Send(conn, method, "secret", &Packet{
+			State:    2,
+			Hostname: "host1",
+			Service:  "cpu",
+			Output:   "CRITICAL - load too high\nsecond line",
+		})
+		require.NoErrorf(t, err, "send ok with method %d", method)
+
+		data := conn.write.Bytes()
+		require.Lenf(t, data, PacketLength, "packet length")
+		require.NoErrorf(t, Decrypt(data, method, iv, "secret"), "decrypt ok")
+
+		packet, err := ParsePacket(data)
+		require.NoErrorf(t, err, "parse ok with method %d", method)
+		assert.Equalf(t, &Packet{
+			Timestamp: 1700000000,
+			State:     2,
+			Hostname:  "host1",
+			Service:   "cpu",
+			Output:    `CRITICAL - load too high\nsecond line`,
+		}, packet, "packet content with method %d", method)
+	}
+}
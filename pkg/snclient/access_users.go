@@ -0,0 +1,256 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// AccessRole sets the scope of an user.
+type AccessRole uint8
+
+const (
+	// AccessRoleReadOnly users may run their allowed commands and read the inventory and cached results.
+	AccessRoleReadOnly AccessRole = iota
+
+	// AccessRoleAdmin users may run all commands and use the admin api.
+	AccessRoleAdmin
+)
+
+// String returns the role name as used in the config
+func (r AccessRole) String() string {
+	if r == AccessRoleAdmin {
+		return "admin"
+	}
+
+	return "readonly"
+}
+
+// AccessUser is a named user from /settings/users/<name> with its own credentials and permissions.
+type AccessUser struct {
+	Name             string
+	password         string
+	token            string
+	role             AccessRole
+	allowedCommands  []string
+	allowedArguments []string
+}
+
+// AccessUserList contains all users which may login on a listener.
+type AccessUserList []*AccessUser
+
+// accessUserContextKey is used to store the authenticated user in the request context.
+type accessUserContextKey struct{}
+
+// accessUserHolder is stored in the request context and filled once the user is authenticated.
+type accessUserHolder struct {
+	user *AccessUser
+}
+
+// NewAccessUser creates a user from given config section.
+func NewAccessUser(name string, section *ConfigSection) (*AccessUser, error) {
+	user := &AccessUser{Name: name}
+	user.password, _ = section.GetString("password")
+	user.token, _ = section.GetString("token")
+	if user.password == "" && user.token == "" {
+		return nil, fmt.Errorf("user %s: either password or token is required", name)
+	}
+	if err := checkPasswordConfig(section, user.password); err != nil {
+		return nil, fmt.Errorf("user %s: %s", name, err.Error())
+	}
+
+	role, _ := section.GetString("role")
+	switch strings.ToLower(role) {
+	case "", "readonly", "read-only", "ro":
+		user.role = AccessRoleReadOnly
+	case "admin":
+		user.role = AccessRoleAdmin
+	default:
+		return nil, fmt.Errorf("user %s: unknown role %s (supported are: readonly, admin)", name, role)
+	}
+
+	// admins may run everything unless restricted
+	defaultCommands := ""
+	if user.role == AccessRoleAdmin {
+		defaultCommands = "*"
+	}
+	commands, ok := section.GetString("allowed commands")
+	if !ok {
+		commands = defaultCommands
+	}
+	user.allowedCommands = accessUserList(commands)
+
+	arguments, ok := section.GetString("allowed arguments")
+	if !ok {
+		arguments = "*"
+	}
+	user.allowedArguments = accessUserList(arguments)
+
+	for _, pattern := range append(slices.Clone(user.allowedCommands), user.allowedArguments...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("user %s: invalid pattern %s: %s", name, pattern, err.Error())
+		}
+	}
+
+	return user, nil
+}
+
+// NewAccessUserList returns all users from /settings/users/ which may login on the given listener.
+// The users option of the listener restricts the list, all users are allowed if it is empty.
+func NewAccessUserList(conf *Config, listenerConf *ConfigSection) (AccessUserList, error) {
+	users := AccessUserList{}
+	if conf == nil {
+		return users, nil
+	}
+
+	allowed := []string{}
+	if listenerConf != nil {
+		names, _ := listenerConf.GetString("users")
+		allowed = accessUserList(names)
+	}
+
+	for sectionName := range conf.SectionsByPrefix("/settings/users/") {
+		name := path.Base(sectionName)
+		if name == "default" {
+			continue
+		}
+		if len(allowed) > 0 && !accessUserMatch(allowed, name) {
+			continue
+		}
+		user, err := NewAccessUser(name, conf.Section(sectionName))
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	for _, name := range allowed {
+		if !strings.ContainsAny(name, "*?[") && users.Get(name) == nil {
+			return nil, fmt.Errorf("unknown user: %s (no section /settings/users/%s)", name, name)
+		}
+	}
+
+	return users, nil
+}
+
+// Get returns user by name or nil.
+func (ul AccessUserList) Get(name string) *AccessUser {
+	for _, user := range ul {
+		if user.Name == name {
+			return user
+		}
+	}
+
+	return nil
+}
+
+// Authenticate checks the credentials of given request against the user list.
+// It returns matched=false if the request does not refer to any user, so the listener password
+// should be used instead. If the request refers to a user but the credentials are wrong, the
+// returned user is nil.
+func (ul AccessUserList) Authenticate(snc *Agent, req *http.Request) (user *AccessUser, matched bool) {
+	if len(ul) == 0 {
+		return nil, false
+	}
+
+	// api token
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		token = strings.TrimSpace(token)
+		for _, user := range ul {
+			if user.token != "" && passwordMatches(user.token, token) {
+				return user, true
+			}
+		}
+		log.Warnf("api token mismatch -> 403")
+
+		return nil, true
+	}
+
+	// basic auth
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		return nil, false
+	}
+	user = ul.Get(username)
+	if user == nil {
+		return nil, false
+	}
+	if user.password == "" || !snc.verifyPassword(user.password, password) {
+		return nil, true
+	}
+
+	return user, true
+}
+
+// CommandAllowed returns true if the user may run the given command.
+func (u *AccessUser) CommandAllowed(command string) bool {
+	return accessUserMatch(u.allowedCommands, command)
+}
+
+// ArgumentsAllowed returns an error if any argument is not in the list of allowed arguments.
+// Arguments are compared by their name, ex.: warn for warn=load > 5.
+func (u *AccessUser) ArgumentsAllowed(args []string) error {
+	for _, arg := range args {
+		name, _, _ := strings.Cut(arg, "=")
+		if !accessUserMatch(u.allowedArguments, strings.TrimSpace(name)) {
+			return fmt.Errorf("argument %s is not allowed for user %s", name, u.Name)
+		}
+	}
+
+	return nil
+}
+
+// IsAdmin returns true if the user has the admin role.
+func (u *AccessUser) IsAdmin() bool {
+	return u.role == AccessRoleAdmin
+}
+
+// contextWithAccessUser returns a new context containing the given user.
+func contextWithAccessUser(ctx context.Context, user *AccessUser) context.Context {
+	return context.WithValue(ctx, accessUserContextKey{}, &accessUserHolder{user: user})
+}
+
+// setRequestAccessUser stores the authenticated user in the request context prepared by the listener.
+func setRequestAccessUser(req *http.Request, user *AccessUser) {
+	if holder, ok := req.Context().Value(accessUserContextKey{}).(*accessUserHolder); ok {
+		holder.user = user
+	}
+}
+
+// accessUserFromContext returns the authenticated user or nil if there is none.
+func accessUserFromContext(ctx context.Context) *AccessUser {
+	if ctx == nil {
+		return nil
+	}
+	if holder, ok := ctx.Value(accessUserContextKey{}).(*accessUserHolder); ok {
+		return holder.user
+	}
+
+	return nil
+}
+
+// accessUserList splits a comma separated list
+func accessUserList(str string) (list []string) {
+	for _, entry := range strings.Split(str, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			list = append(list, entry)
+		}
+	}
+
+	return list
+}
+
+// accessUserMatch returns true if name matches any of the wildcard patterns
+func accessUserMatch(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
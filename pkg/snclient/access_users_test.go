@@ -0,0 +1,119 @@
+package snclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessUserConfig(t *testing.T) {
+	cfg := NewConfig(true)
+	require.NoErrorf(t, cfg.ParseINI(`
+[/settings/users/helpdesk]
+password = secret
+allowed commands = check_service, check_drive*
+allowed arguments = service, warn, crit
+
+[/settings/users/deploy]
+token = abc123
+role = admin
+`, "test.ini", nil), "config parsed")
+
+	users, err := NewAccessUserList(cfg, nil)
+	require.NoErrorf(t, err, "users parsed")
+	require.Lenf(t, users, 2, "found all users")
+
+	helpdesk := users.Get("helpdesk")
+	require.NotNilf(t, helpdesk, "helpdesk found")
+	assert.Falsef(t, helpdesk.IsAdmin(), "readonly is default role")
+	assert.Truef(t, helpdesk.CommandAllowed("check_service"), "command allowed")
+	assert.Truef(t, helpdesk.CommandAllowed("check_drivesize"), "wildcard command allowed")
+	assert.Falsef(t, helpdesk.CommandAllowed("check_nsc_web"), "command not allowed")
+	require.NoErrorf(t, helpdesk.ArgumentsAllowed([]string{"service=snclient", "warn=state != 'running'"}), "arguments allowed")
+	require.Errorf(t, helpdesk.ArgumentsAllowed([]string{"service=snclient", "filter=none"}), "argument not allowed")
+
+	deploy := users.Get("deploy")
+	require.NotNilf(t, deploy, "deploy found")
+	assert.Truef(t, deploy.IsAdmin(), "admin role")
+	assert.Truef(t, deploy.CommandAllowed("check_nsc_web"), "admins may run everything")
+
+	// restrict listener to single user
+	listenerConf := cfg.Section("/settings/WEB/server")
+	listenerConf.Set("users", "helpdesk")
+	users, err = NewAccessUserList(cfg, listenerConf)
+	require.NoErrorf(t, err, "users parsed")
+	require.Lenf(t, users, 1, "listener restricted to single user")
+
+	listenerConf.Set("users", "unknown")
+	_, err = NewAccessUserList(cfg, listenerConf)
+	require.Errorf(t, err, "unknown user")
+
+	cfg.Section("/settings/users/broken").Set("role", "root")
+	_, err = NewAccessUserList(cfg, nil)
+	require.Errorf(t, err, "user without credentials and invalid role")
+}
+
+func TestAccessUserWeb(t *testing.T) {
+	config := `
+[/modules]
+WEBServer = enabled
+WEBAdminServer = enabled
+
+[/settings/WEB/server]
+port = 45666
+use ssl = false
+password = test
+
+[/settings/users/helpdesk]
+password = secret
+allowed commands = check_dummy
+allowed arguments = *
+
+[/settings/users/deploy]
+token = abc123
+role = admin
+`
+	snc := StartTestAgent(t, config)
+
+	baseURL := "http://127.0.0.1:45666"
+	request := func(url string, setAuth func(req *http.Request)) int {
+		t.Helper()
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, baseURL+url, http.NoBody)
+		require.NoErrorf(t, err, "request created")
+		setAuth(req)
+		res, err := snc.httpClient(&HTTPClientOptions{reqTimeout: DefaultSocketTimeout}).Do(req)
+		require.NoErrorf(t, err, "request sent")
+		res.Body.Close()
+
+		return res.StatusCode
+	}
+	helpdesk := func(req *http.Request) { req.SetBasicAuth("helpdesk", "secret") }
+	wrongPassword := func(req *http.Request) { req.SetBasicAuth("helpdesk", "test") }
+	deploy := func(req *http.Request) { req.Header.Set("Authorization", "Bearer abc123") }
+	listenerPassword := func(req *http.Request) { req.Header.Set("password", "test") }
+
+	assert.Equalf(t, http.StatusOK, request("/api/v1/queries/check_dummy/commands/execute?0", helpdesk), "allowed command")
+	assert.Equalf(t, http.StatusForbidden, request("/api/v1/queries/check_uptime/commands/execute", helpdesk), "disallowed command")
+	assert.Equalf(t, http.StatusForbidden, request("/api/v1/queries/check_dummy/commands/execute?0", wrongPassword), "wrong password")
+	assert.Equalf(t, http.StatusForbidden, request("/api/v1/admin/unknown", helpdesk), "readonly user on admin api")
+	assert.Equalf(t, http.StatusOK, request("/api/v1/queries/check_uptime/commands/execute", deploy), "admin token")
+	assert.Equalf(t, http.StatusNotFound, request("/api/v1/admin/unknown", deploy), "admin token on admin api")
+	assert.Equalf(t, http.StatusOK, request("/api/v1/queries/check_uptime/commands/execute", listenerPassword), "listener password")
+
+	assert.Equalf(t, http.StatusForbidden, request("/query/check_uptime", helpdesk), "legacy api")
+
+	// commands and arguments are verified again when running the check
+	ctx := contextWithAccessUser(context.TODO(), &AccessUser{Name: "test", allowedCommands: []string{"check_dummy"}, allowedArguments: []string{"0"}})
+	res := snc.RunCheckWithContext(ctx, "check_uptime", []string{}, 0, nil)
+	assert.Equalf(t, CheckExitUnknown, res.State, "command not allowed")
+	assert.Containsf(t, string(res.BuildPluginOutput()), "not allowed to run check_uptime", "error message")
+	res = snc.RunCheckWithContext(ctx, "check_dummy", []string{"1"}, 0, nil)
+	assert.Equalf(t, CheckExitUnknown, res.State, "argument not allowed")
+	res = snc.RunCheckWithContext(ctx, "check_dummy", []string{"0"}, 0, nil)
+	assert.Equalf(t, CheckExitOK, res.State, "allowed argument")
+
+	StopTestAgent(t, snc)
+}
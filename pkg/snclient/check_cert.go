@@ -0,0 +1,441 @@
+package snclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/convert"
+)
+
+func init() {
+	AvailableChecks["check_cert"] = CheckEntry{"check_cert", NewCheckCert}
+}
+
+type CheckCert struct {
+	files      []string
+	fileList   CommaStringList
+	hosts      []string
+	starttls   string
+	serverName string
+	caFile     string
+}
+
+func NewCheckCert() CheckHandler {
+	return &CheckCert{
+		fileList: CommaStringList{},
+	}
+}
+
+func (l *CheckCert) Build() *CheckData {
+	return &CheckData{
+		name:        "check_cert",
+		description: "Checks expiry and chain validity of X.509 certificates from files and tls endpoints.",
+		implemented: ALL,
+		result: &CheckResult{
+			State: CheckExitOK,
+		},
+		args: map[string]CheckArgument{
+			"file":       {value: &l.files, description: "Certificate file (PEM or DER), supports wildcards. Can be used multiple times"},
+			"files":      {value: &l.fileList, description: "A comma separated list of certificate files"},
+			"host":       {value: &l.hosts, description: "Check the certificate chain of host:port (default port: 443). Can be used multiple times"},
+			"starttls":   {value: &l.starttls, description: "Use STARTTLS for host connections: smtp, imap, pop3 or ftp"},
+			"servername": {value: &l.serverName, description: "Server name used for SNI and hostname verification (default: host name)"},
+			"ca-file":    {value: &l.caFile, description: "Additional trusted CA certificates used for chain validation"},
+			"timezone":   {description: "Sets the timezone for time metrics (default is local time)"},
+		},
+		defaultWarning:  "days_left < 30",
+		defaultCritical: "days_left < 7",
+		detailSyntax:    "${cn} expires in ${days_left} days",
+		topSyntax:       "%(status) - ${problem_list}",
+		okSyntax:        "%(status) - All %(count) certificate(s) are ok.",
+		emptySyntax:     "%(status) - No certificates found",
+		emptyState:      CheckExitUnknown,
+		attributes: []CheckAttribute{
+			{name: "source", description: "File name or host:port the certificate was read from"},
+			{name: "index", description: "Position of the certificate in the file or chain, 0 is the first / server certificate"},
+			{name: "cn", description: "Common name of the subject"},
+			{name: "subject", description: "Subject distinguished name"},
+			{name: "issuer", description: "Issuer distinguished name"},
+			{name: "serial", description: "Serial number as hex string"},
+			{name: "not_before", description: "Date when the certificate becomes valid", unit: UDate},
+			{name: "not_after", description: "Date when the certificate expires", unit: UDate},
+			{name: "days_left", description: "Number of days until the certificate expires, negative if already expired"},
+			{name: "sans", description: "Comma separated list of subject alternative names"},
+			{name: "key_type", description: "Public key algorithm (RSA, ECDSA, Ed25519)"},
+			{name: "key_size", description: "Public key size in bits"},
+			{name: "sig_alg", description: "Signature algorithm"},
+			{name: "fingerprint", description: "SHA256 fingerprint"},
+			{name: "is_ca", description: "Flag whether this is a CA certificate (true/false)"},
+			{name: "chain_valid", description: "Flag whether the certificate chain could be verified (true/false)"},
+			{name: "chain_error", description: "Reason why the chain verification failed"},
+		},
+		exampleDefault: `
+    check_cert file=/etc/snclient/server.crt
+    OK - All 1 certificate(s) are ok. |'/etc/snclient/server.crt days_left'=3622;30:;7:
+
+Check certificate chain of a mail server:
+
+    check_cert host=mail.example.com:25 starttls=smtp 'crit=days_left < 7 || chain_valid = false'
+    OK - All 2 certificate(s) are ok. |...
+	`,
+		exampleArgs: `'file=/etc/ssl/certs/*.pem' 'warn=days_left < 30' 'crit=days_left < 7'`,
+	}
+}
+
+func (l *CheckCert) Check(ctx context.Context, _ *Agent, check *CheckData, _ []Argument) (*CheckResult, error) {
+	l.files = append(l.files, l.fileList...)
+	if len(l.files)+len(l.hosts) == 0 {
+		return nil, fmt.Errorf("no file or host specified")
+	}
+
+	switch l.starttls {
+	case "", "smtp", "imap", "pop3", "ftp":
+	default:
+		return nil, fmt.Errorf("unsupported starttls protocol %s, must be one of: smtp, imap, pop3 or ftp", l.starttls)
+	}
+
+	roots, err := l.rootPool()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range l.files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %s: %s", pattern, err.Error())
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no such file: %s", pattern)
+		}
+		for _, file := range matches {
+			certs, err := l.readCertFile(file)
+			if err != nil {
+				return nil, err
+			}
+			l.addCerts(check, file, certs, roots, "")
+		}
+	}
+
+	for _, host := range l.hosts {
+		address := host
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, "443")
+		}
+		hostName, _, _ := net.SplitHostPort(address)
+		serverName := l.serverName
+		if serverName == "" {
+			serverName = hostName
+		}
+
+		certs, err := l.fetchCerts(ctx, check, address, serverName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", address, err.Error())
+		}
+		l.addCerts(check, address, certs, roots, serverName)
+	}
+
+	if check.HasThreshold("days_left") {
+		for _, data := range check.listData {
+			name := data["source"]
+			if data["index"] != "0" {
+				name += ":" + data["index"]
+			}
+			check.result.Metrics = append(check.result.Metrics,
+				&CheckMetric{
+					ThresholdName: "days_left",
+					Name:          name + " days_left",
+					Value:         convert.Int64(data["days_left"]),
+					Warning:       check.warnThreshold,
+					Critical:      check.critThreshold,
+				})
+		}
+	}
+
+	return check.Finalize()
+}
+
+// rootPool returns the system root certificates extended by the ca-file.
+func (l *CheckCert) rootPool() (*x509.CertPool, error) {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		log.Debugf("cannot load system cert pool: %s", err.Error())
+		roots = x509.NewCertPool()
+	}
+
+	if l.caFile == "" {
+		return roots, nil
+	}
+
+	certs, err := l.readCertFile(l.caFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, cert := range certs {
+		roots.AddCert(cert)
+	}
+
+	return roots, nil
+}
+
+// readCertFile reads all certificates from a PEM or DER encoded file.
+func (l *CheckCert) readCertFile(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", file, err.Error())
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", file, err.Error())
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+
+	return certs, nil
+}
+
+// parseCertificates parses PEM blocks or DER data and returns all certificates.
+// Other PEM blocks like private keys are ignored.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if !strings.Contains(string(data), "-----BEGIN") {
+		certs, err := x509.ParseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("der: %s", err.Error())
+		}
+
+		return certs, nil
+	}
+
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("pem: %s", err.Error())
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
+
+// fetchCerts connects to the address and returns the certificate chain sent by the server.
+func (l *CheckCert) fetchCerts(ctx context.Context, check *CheckData, address, serverName string) ([]*x509.Certificate, error) {
+	timeout := time.Duration(check.timeout * float64(time.Second))
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("connect failed: %s", err.Error())
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		LogDebug(conn.SetDeadline(deadline))
+	}
+
+	if l.starttls != "" {
+		if err := startTLS(conn, l.starttls); err != nil {
+			return nil, fmt.Errorf("starttls %s: %s", l.starttls, err.Error())
+		}
+	}
+
+	// verification is done afterwards for each certificate, so all certificates are available even if the chain is invalid
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, //nolint:gosec // certificates are verified in addCerts
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("tls handshake failed: %s", err.Error())
+	}
+
+	return tlsConn.ConnectionState().PeerCertificates, nil
+}
+
+// startTLS runs the plain text part of the protocol until the tls handshake can start.
+func startTLS(conn net.Conn, protocol string) error {
+	reader := bufio.NewReader(conn)
+	send := func(cmd string) error {
+		_, err := conn.Write([]byte(cmd + "\r\n"))
+		if err != nil {
+			return fmt.Errorf("write: %s", err.Error())
+		}
+
+		return nil
+	}
+
+	switch protocol {
+	case "smtp":
+		if err := startTLSExpect(reader, "220"); err != nil {
+			return err
+		}
+		if err := send("EHLO snclient"); err != nil {
+			return err
+		}
+		if err := startTLSExpect(reader, "250"); err != nil {
+			return err
+		}
+		if err := send("STARTTLS"); err != nil {
+			return err
+		}
+
+		return startTLSExpect(reader, "220")
+	case "ftp":
+		if err := startTLSExpect(reader, "220"); err != nil {
+			return err
+		}
+		if err := send("AUTH TLS"); err != nil {
+			return err
+		}
+
+		return startTLSExpect(reader, "234")
+	case "imap":
+		if err := startTLSExpect(reader, "* OK"); err != nil {
+			return err
+		}
+		if err := send("a001 STARTTLS"); err != nil {
+			return err
+		}
+
+		return startTLSExpect(reader, "a001 OK")
+	case "pop3":
+		if err := startTLSExpect(reader, "+OK"); err != nil {
+			return err
+		}
+		if err := send("STLS"); err != nil {
+			return err
+		}
+
+		return startTLSExpect(reader, "+OK")
+	}
+
+	return fmt.Errorf("unsupported protocol %s", protocol)
+}
+
+// startTLSExpect reads a (multiline) response and checks the prefix of the last line.
+func startTLSExpect(reader *bufio.Reader, prefix string) error {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("read: %s", err.Error())
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// smtp and ftp multiline responses continue with a dash after the code
+		if len(line) > 3 && line[3] == '-' {
+			continue
+		}
+		// imap untagged responses
+		if strings.HasPrefix(line, "* ") && prefix != "* OK" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, prefix) {
+			return fmt.Errorf("unexpected response: %s", line)
+		}
+
+		return nil
+	}
+}
+
+// addCerts adds a list entry for each certificate. The other certificates from the same source are used as intermediates.
+func (l *CheckCert) addCerts(check *CheckData, source string, certs []*x509.Certificate, roots *x509.CertPool, serverName string) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs {
+		intermediates.AddCert(cert)
+	}
+
+	now := time.Now()
+	for index, cert := range certs {
+		entry := certEntry(cert, now)
+		entry["source"] = source
+		entry["index"] = fmt.Sprintf("%d", index)
+
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		// verify the host name for the server certificate
+		if index == 0 && serverName != "" {
+			opts.DNSName = serverName
+		}
+
+		entry["chain_valid"] = "true"
+		entry["chain_error"] = ""
+		if _, err := cert.Verify(opts); err != nil {
+			entry["chain_valid"] = "false"
+			entry["chain_error"] = err.Error()
+		}
+
+		check.listData = append(check.listData, entry)
+	}
+}
+
+// certEntry returns the attributes of a certificate.
+func certEntry(cert *x509.Certificate, now time.Time) map[string]string {
+	sans := []string{}
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	keyType := cert.PublicKeyAlgorithm.String()
+	keySize := 0
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		keySize = key.N.BitLen()
+	case *ecdsa.PublicKey:
+		keySize = key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		keySize = len(key) * 8
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	return map[string]string{
+		"cn":          cert.Subject.CommonName,
+		"subject":     cert.Subject.String(),
+		"issuer":      cert.Issuer.String(),
+		"serial":      fmt.Sprintf("%X", cert.SerialNumber),
+		"not_before":  fmt.Sprintf("%d", cert.NotBefore.Unix()),
+		"not_after":   fmt.Sprintf("%d", cert.NotAfter.Unix()),
+		"days_left":   fmt.Sprintf("%d", int64(math.Floor(cert.NotAfter.Sub(now).Hours()/24))),
+		"sans":        strings.Join(sans, ", "),
+		"key_type":    keyType,
+		"key_size":    fmt.Sprintf("%d", keySize),
+		"sig_alg":     cert.SignatureAlgorithm.String(),
+		"fingerprint": strings.ToUpper(hex.EncodeToString(fingerprint[:])),
+		"is_ca":       fmt.Sprintf("%t", cert.IsCA),
+	}
+}
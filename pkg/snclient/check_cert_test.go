@@ -0,0 +1,166 @@
+package snclient
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCertFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	caCert, caKey := testCertCreate(t, "Test CA", nil, nil, 365*24*time.Hour)
+	leafCert, _ := testCertCreate(t, "localhost", caCert, caKey, 20*24*time.Hour)
+
+	caFile := filepath.Join(tmpDir, "ca.pem")
+	testCertWrite(t, caFile, caCert)
+	leafFile := filepath.Join(tmpDir, "server.crt")
+	testCertWrite(t, leafFile, leafCert)
+	derFile := filepath.Join(tmpDir, "server.der")
+	require.NoErrorf(t, os.WriteFile(derFile, leafCert.Raw, 0o600), "der written")
+
+	snc := StartTestAgent(t, "")
+
+	res := snc.RunCheck("check_cert", []string{"file=" + leafFile})
+	assert.Equalf(t, CheckExitWarning, res.State, "state warning")
+	assert.Regexpf(t, `^WARNING - localhost expires in 1\d days \|'.*server.crt days_left'=1\d;30:;7:$`,
+		string(res.BuildPluginOutput()), "output matches")
+
+	res = snc.RunCheck("check_cert", []string{"file=" + leafFile, "ca-file=" + caFile, "warn=days_left < 10", "crit=chain_valid = false"})
+	assert.Equalf(t, CheckExitOK, res.State, "chain valid with ca file")
+
+	res = snc.RunCheck("check_cert", []string{"file=" + leafFile, "warn=days_left < 10", "crit=chain_valid = false"})
+	assert.Equalf(t, CheckExitCritical, res.State, "chain invalid without ca file")
+
+	res = snc.RunCheck("check_cert", []string{"file=" + filepath.Join(tmpDir, "*"), "warn=days_left < 10", "crit=days_left < 5",
+		"detail-syntax=${cn}:${is_ca}:${key_type}:${key_size}:${sans}", "show-all"})
+	assert.Equalf(t, CheckExitOK, res.State, "state ok")
+	assert.Equalf(t, "OK - Test CA:true:ECDSA:256:, localhost:false:ECDSA:256:localhost, 127.0.0.1, localhost:false:ECDSA:256:localhost, 127.0.0.1",
+		res.Output, "glob and der files")
+
+	res = snc.RunCheck("check_cert", []string{"file=" + filepath.Join(tmpDir, "missing.pem")})
+	assert.Equalf(t, CheckExitUnknown, res.State, "missing file")
+
+	StopTestAgent(t, snc)
+}
+
+func TestCheckCertHost(t *testing.T) {
+	tmpDir := t.TempDir()
+	caCert, caKey := testCertCreate(t, "Test CA", nil, nil, 365*24*time.Hour)
+	leafCert, leafKey := testCertCreate(t, "localhost", caCert, caKey, 100*24*time.Hour)
+	caFile := filepath.Join(tmpDir, "ca.pem")
+	testCertWrite(t, caFile, caCert)
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leafCert.Raw, caCert.Raw},
+			PrivateKey:  leafKey,
+		}},
+	}
+
+	// plain tls server
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoErrorf(t, err, "tls listener started")
+	defer listener.Close()
+	go testCertServe(listener, func(conn net.Conn) {
+		_ = conn.(*tls.Conn).Handshake()
+	})
+
+	// smtp server with starttls
+	smtpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoErrorf(t, err, "smtp listener started")
+	defer smtpListener.Close()
+	go testCertServe(smtpListener, func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte("220 mail ESMTP\r\n"))
+		_, _ = reader.ReadString('\n')
+		_, _ = conn.Write([]byte("250-mail\r\n250 STARTTLS\r\n"))
+		_, _ = reader.ReadString('\n')
+		_, _ = conn.Write([]byte("220 ready\r\n"))
+		_ = tls.Server(conn, tlsConfig).Handshake()
+	})
+
+	snc := StartTestAgent(t, "")
+
+	res := snc.RunCheck("check_cert", []string{"host=" + listener.Addr().String(), "servername=localhost", "ca-file=" + caFile,
+		"crit=chain_valid = false", "detail-syntax=${index}:${cn}:${chain_valid}", "show-all"})
+	assert.Equalf(t, CheckExitOK, res.State, "state ok")
+	assert.Equalf(t, "OK - 0:localhost:true, 1:Test CA:true", res.Output, "chain from server")
+
+	res = snc.RunCheck("check_cert", []string{"host=" + listener.Addr().String(), "servername=wrong.name", "ca-file=" + caFile,
+		"crit=chain_valid = false", "detail-syntax=${chain_error}"})
+	assert.Equalf(t, CheckExitCritical, res.State, "host name mismatch")
+	assert.Containsf(t, res.Output, "wrong.name", "verification error")
+
+	res = snc.RunCheck("check_cert", []string{"host=" + smtpListener.Addr().String(), "starttls=smtp", "servername=localhost", "ca-file=" + caFile,
+		"crit=chain_valid = false", "detail-syntax=${index}:${cn}:${chain_valid}", "show-all"})
+	assert.Equalf(t, CheckExitOK, res.State, "state ok")
+	assert.Equalf(t, "OK - 0:localhost:true, 1:Test CA:true", res.Output, "chain from smtp server")
+
+	StopTestAgent(t, snc)
+}
+
+func testCertServe(listener net.Listener, handler func(conn net.Conn)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		handler(conn)
+		conn.Close()
+	}
+}
+
+func testCertCreate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, valid time.Duration) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoErrorf(t, err, "key generated")
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(valid),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer := key
+	if parent == nil {
+		parent = template
+	} else {
+		template.DNSNames = []string{name}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		signer = parentKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	require.NoErrorf(t, err, "certificate created")
+	cert, err := x509.ParseCertificate(der)
+	require.NoErrorf(t, err, "certificate parsed")
+
+	return cert, key
+}
+
+func testCertWrite(t *testing.T, file string, cert *x509.Certificate) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	require.NoErrorf(t, os.WriteFile(file, data, 0o600), "certificate written")
+}
@@ -0,0 +1,246 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/convert"
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+func init() {
+	AvailableChecks["check_disk_io"] = CheckEntry{"check_disk_io", NewCheckDiskIO}
+}
+
+type CheckDiskIO struct {
+	snc      *Agent
+	names    []string
+	excludes []string
+	timeStr  string
+}
+
+func NewCheckDiskIO() CheckHandler {
+	return &CheckDiskIO{
+		timeStr: "1m",
+	}
+}
+
+func (l *CheckDiskIO) Build() *CheckData {
+	return &CheckData{
+		name: "check_disk_io",
+		description: `Checks the disk i/o rates, latency and utilization of block devices.
+
+The metrics are calculated from /proc/diskstats which is collected by the CheckSystemUnix module
+every metrics interval. Devices matching the 'disk device filter' from the /settings/system/default
+section are not collected.`,
+		implemented:  Linux,
+		hasInventory: ListInventory,
+		result: &CheckResult{
+			State: CheckExitOK,
+		},
+		args: map[string]CheckArgument{
+			"dev":     {value: &l.names, description: "Alias for device"},
+			"device":  {value: &l.names, description: "The device to check. Default is all"},
+			"name":    {value: &l.names, description: "Alias for device"},
+			"exclude": {value: &l.excludes, description: "Exclude device by name"},
+			"time":    {value: &l.timeStr, description: "Time frame used to calculate the rates, default: 1m"},
+		},
+		defaultWarning:  "util_pct > 80",
+		defaultCritical: "util_pct > 95",
+		okSyntax:        "%(status) - %(list)",
+		detailSyntax:    "%(name) >%(write_bytes_rate:h)B/s <%(read_bytes_rate:h)B/s %(util_pct)%",
+		topSyntax:       "%(status) - %(list)",
+		emptySyntax:     "%(status) - No devices found",
+		emptyState:      CheckExitUnknown,
+		attributes: []CheckAttribute{
+			{name: "name", description: "Name of the device"},
+			{name: "time", description: "Time frame used to calculate the rates"},
+			{name: "read_bytes_rate", description: "Bytes read per second", unit: UByte},
+			{name: "write_bytes_rate", description: "Bytes written per second", unit: UByte},
+			{name: "read_iops", description: "Read operations per second"},
+			{name: "write_iops", description: "Write operations per second"},
+			{name: "iops", description: "Read and write operations per second"},
+			{name: "await", description: "Average time in milliseconds for i/o requests to be served"},
+			{name: "util_pct", description: "Percentage of time the device was busy", unit: UPercent},
+			{name: "total_read", description: "Total bytes read", unit: UByte},
+			{name: "total_written", description: "Total bytes written", unit: UByte},
+		},
+		exampleDefault: `
+    check_disk_io device=sda
+    OK - sda >1.2 MB/s <350 kB/s 3.4% |'sda read_bytes_rate'=350000B;;;0 'sda write_bytes_rate'=1200000B;;;0 'sda iops'=42.5;;;0 'sda await'=0.8ms;;;0 'sda util_pct'=3.4%;80;95;0;100
+
+Alert on slow devices over the last 5 minutes:
+
+    check_disk_io time=5m warn='await > 20' crit='await > 50'
+    OK - sda >1.1 MB/s <310 kB/s 3.1%, nvme0n1 >5.1 MB/s <1.4 MB/s 1.2% |...
+	`,
+		exampleArgs: `'warn=util_pct > 80' 'crit=util_pct > 95'`,
+	}
+}
+
+func (l *CheckDiskIO) Check(_ context.Context, snc *Agent, check *CheckData, _ []Argument) (*CheckResult, error) {
+	l.snc = snc
+
+	dur, err := utils.ExpandDuration(l.timeStr)
+	if err != nil {
+		return nil, fmt.Errorf("time: %s", err.Error())
+	}
+	if dur <= 0 {
+		return nil, fmt.Errorf("time must be a positive duration")
+	}
+	lookback := time.Duration(dur * float64(time.Second))
+
+	devices := []string{}
+	for _, key := range snc.Counter.Keys("disk") {
+		if dev, ok := strings.CutSuffix(key, "_read_bytes"); ok {
+			devices = append(devices, dev)
+		}
+	}
+	slices.Sort(devices)
+
+	found := map[string]bool{}
+	for _, dev := range devices {
+		if slices.Contains(l.excludes, dev) {
+			log.Tracef("device %s excluded by 'exclude' argument", dev)
+
+			continue
+		}
+		if len(l.names) > 0 && !slices.Contains(l.names, dev) {
+			log.Tracef("device %s excluded by 'device' argument", dev)
+
+			continue
+		}
+		found[dev] = true
+
+		entry := l.buildEntry(dev, lookback)
+		if !check.MatchMapCondition(check.filter, entry, true) {
+			log.Tracef("device %s excluded by filter", dev)
+
+			continue
+		}
+
+		check.listData = append(check.listData, entry)
+		l.addMetrics(check, entry)
+	}
+
+	// warn about all devices explicitly requested but not found
+	for _, dev := range l.names {
+		if _, ok := found[dev]; !ok {
+			entry := l.buildEntry(dev, lookback)
+			entry["_error"] = fmt.Sprintf("no device named %s found", dev)
+			check.listData = append(check.listData, entry)
+		}
+	}
+
+	return check.Finalize()
+}
+
+// buildEntry calculates the rates of a device over the lookback time frame.
+func (l *CheckDiskIO) buildEntry(dev string, lookback time.Duration) map[string]string {
+	readBytes := l.getRate(dev, "read_bytes", lookback)
+	writeBytes := l.getRate(dev, "write_bytes", lookback)
+	reads := l.getRate(dev, "reads", lookback)
+	writes := l.getRate(dev, "writes", lookback)
+	iops := reads + writes
+
+	await := float64(0)
+	if iops > 0 {
+		await = (l.getRate(dev, "read_ms", lookback) + l.getRate(dev, "write_ms", lookback)) / iops
+	}
+
+	// io_ms is increased by one millisecond for every millisecond the device was busy
+	util := math.Min(l.getRate(dev, "io_ms", lookback)/10, 100)
+
+	return map[string]string{
+		"name":             dev,
+		"time":             l.timeStr,
+		"read_bytes_rate":  fmt.Sprintf("%.0f", readBytes),
+		"write_bytes_rate": fmt.Sprintf("%.0f", writeBytes),
+		"read_iops":        fmt.Sprintf("%.2f", reads),
+		"write_iops":       fmt.Sprintf("%.2f", writes),
+		"iops":             fmt.Sprintf("%.2f", iops),
+		"await":            fmt.Sprintf("%.2f", await),
+		"util_pct":         fmt.Sprintf("%.1f", util),
+		"total_read":       fmt.Sprintf("%.0f", l.getLast(dev, "read_bytes")),
+		"total_written":    fmt.Sprintf("%.0f", l.getLast(dev, "write_bytes")),
+	}
+}
+
+func (l *CheckDiskIO) addMetrics(check *CheckData, entry map[string]string) {
+	name := entry["name"]
+	check.result.Metrics = append(check.result.Metrics,
+		&CheckMetric{
+			ThresholdName: "read_bytes_rate",
+			Name:          name + " read_bytes_rate",
+			Value:         convert.Float64(entry["read_bytes_rate"]),
+			Unit:          "B",
+			Warning:       check.warnThreshold,
+			Critical:      check.critThreshold,
+			Min:           &Zero,
+		},
+		&CheckMetric{
+			ThresholdName: "write_bytes_rate",
+			Name:          name + " write_bytes_rate",
+			Value:         convert.Float64(entry["write_bytes_rate"]),
+			Unit:          "B",
+			Warning:       check.warnThreshold,
+			Critical:      check.critThreshold,
+			Min:           &Zero,
+		},
+		&CheckMetric{
+			ThresholdName: "iops",
+			Name:          name + " iops",
+			Value:         convert.Float64(entry["iops"]),
+			Warning:       check.warnThreshold,
+			Critical:      check.critThreshold,
+			Min:           &Zero,
+		},
+		&CheckMetric{
+			ThresholdName: "await",
+			Name:          name + " await",
+			Value:         convert.Float64(entry["await"]),
+			Unit:          "ms",
+			Warning:       check.warnThreshold,
+			Critical:      check.critThreshold,
+			Min:           &Zero,
+		},
+		&CheckMetric{
+			ThresholdName: "util_pct",
+			Name:          name + " util_pct",
+			Value:         convert.Float64(entry["util_pct"]),
+			Unit:          "%",
+			Warning:       check.warnThreshold,
+			Critical:      check.critThreshold,
+			Min:           &Zero,
+			Max:           &Hundred,
+		},
+	)
+}
+
+func (l *CheckDiskIO) getRate(dev, column string, lookback time.Duration) float64 {
+	rate, _ := l.snc.Counter.GetRate("disk", dev+"_"+column, lookback)
+
+	// counter wrapped or device has been reset
+	if rate < 0 {
+		return 0
+	}
+
+	return rate
+}
+
+func (l *CheckDiskIO) getLast(dev, column string) float64 {
+	counter := l.snc.Counter.Get("disk", dev+"_"+column)
+	if counter == nil {
+		return 0
+	}
+	last := counter.GetLast()
+	if last == nil {
+		return 0
+	}
+
+	return last.Float64()
+}
@@ -0,0 +1,60 @@
+package snclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDiskIO(t *testing.T) {
+	snc := StartTestAgent(t, "")
+
+	res := snc.RunCheck("check_disk_io", []string{})
+	assert.Containsf(t, []int64{CheckExitOK, CheckExitWarning, CheckExitCritical}, res.State, "state from real devices")
+
+	// fake device with full utilization
+	for _, col := range []string{"reads", "read_bytes", "read_ms", "writes", "write_bytes", "write_ms", "io_ms"} {
+		snc.Counter.Create("disk", "testdisk_"+col, time.Minute, time.Millisecond)
+		snc.Counter.Set("disk", "testdisk_"+col, float64(0))
+	}
+	time.Sleep(100 * time.Millisecond)
+	snc.Counter.Set("disk", "testdisk_reads", float64(10))
+	snc.Counter.Set("disk", "testdisk_read_bytes", float64(1024*1024))
+	snc.Counter.Set("disk", "testdisk_read_ms", float64(50))
+	snc.Counter.Set("disk", "testdisk_writes", float64(10))
+	snc.Counter.Set("disk", "testdisk_write_bytes", float64(0))
+	snc.Counter.Set("disk", "testdisk_write_ms", float64(50))
+	snc.Counter.Set("disk", "testdisk_io_ms", float64(1000))
+
+	res = snc.RunCheck("check_disk_io", []string{"device=testdisk", "detail-syntax=${name} ${util_pct}% ${await}ms ${total_read}"})
+	assert.Equalf(t, CheckExitCritical, res.State, "state critical")
+	assert.Regexpf(t, `^CRITICAL - testdisk 100.0% [\d.]+ms 1048576$`, res.Output, "output matches")
+
+	res = snc.RunCheck("check_disk_io", []string{"device=testdisk", "warn=await > 10", "crit=await > 20"})
+	assert.Equalf(t, CheckExitOK, res.State, "await thresholds")
+
+	res = snc.RunCheck("check_disk_io", []string{"device=nonexisting"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "state unknown")
+	assert.Equalf(t, "UNKNOWN - no device named nonexisting found", res.Output, "output matches")
+
+	StopTestAgent(t, snc)
+}
+
+func TestCheckDiskIOParseDiskStats(t *testing.T) {
+	content := `   7       0 loop0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+ 259       0 nvme0n1 224370 72571 14539102 48396 1373446 640811 62035936 1282016 0 537084 1398117 0 0 0 0 96372 67703
+ 259       1 nvme0n1p1 325 1078 12926 71 2 0 2 0 0 112 71 0 0 0 0 0 0
+`
+	devices := parseLinuxDiskStats(content)
+	assert.Lenf(t, devices, 3, "parsed devices")
+	assert.Equalf(t, map[string]float64{
+		"reads":       224370,
+		"read_bytes":  14539102 * 512,
+		"read_ms":     48396,
+		"writes":      1373446,
+		"write_bytes": 62035936 * 512,
+		"write_ms":    1282016,
+		"io_ms":       537084,
+	}, devices["nvme0n1"], "parsed nvme0n1")
+}
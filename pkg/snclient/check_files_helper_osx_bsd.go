@@ -25,3 +25,11 @@ func getCheckFileTimes(fileInfo fs.FileInfo) (*FileInfoUnified, error) {
 func getFileVersion(path string) (string, error) {
 	return "0.0.0.0", fmt.Errorf("file version not supported: %s", path)
 }
+
+func getFileInode(fileInfo fs.FileInfo) uint64 {
+	if fileInfoSys, ok := fileInfo.Sys().(*syscall.Stat_t); ok {
+		return fileInfoSys.Ino
+	}
+
+	return 0
+}
@@ -30,3 +30,8 @@ func getFileVersion(path string) (string, error) {
 
 	return f.FixedInfo().FileVersion.String(), nil
 }
+
+// getFileInode returns 0 on windows, rotation is detected by file size only
+func getFileInode(_ fs.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,78 @@
+package snclient
+
+func init() {
+	AvailableChecks["check_journald"] = CheckEntry{"check_journald", NewCheckJournald}
+}
+
+const DefaultJournaldUniqueIndex = "${unit}-${syslog_identifier}-${priority}-${message}"
+
+type CheckJournald struct {
+	units           []string
+	scanRange       string
+	truncateMessage int
+	uniqueIndex     string
+}
+
+func NewCheckJournald() CheckHandler {
+	return &CheckJournald{
+		scanRange:   "-24h",
+		uniqueIndex: "1",
+	}
+}
+
+func (l *CheckJournald) Build() *CheckData {
+	return &CheckData{
+		name: "check_journald",
+		description: `Checks the systemd journal entries.
+
+The journal is read by 'journalctl -o json' for the given scan-range.
+Priorities follow the syslog levels: 0 emerg, 1 alert, 2 crit, 3 err, 4 warning, 5 notice, 6 info and 7 debug.
+`,
+		implemented: Linux,
+		result: &CheckResult{
+			State: CheckExitOK,
+		},
+		hasInventory: NoCallInventory,
+		args: map[string]CheckArgument{
+			"unit":             {value: &l.units, description: "Only read entries of this systemd unit (can be specified multiple times)"},
+			"timezone":         {description: "Sets the timezone for time metrics (default is local time)"},
+			"scan-range":       {value: &l.scanRange, description: "Sets time range to scan for message (default is 24h)"},
+			"truncate-message": {value: &l.truncateMessage, description: "Maximum length of message for each journal entry"},
+			"unique-index":     {value: &l.uniqueIndex, description: "Combination of fields that identifies unique entries, set to 0 to disable. Default is \"" + DefaultJournaldUniqueIndex + "\""},
+			"unique":           {value: &l.uniqueIndex, description: "Alias for unique-index"},
+		},
+		defaultFilter:   "priority <= 4",
+		defaultWarning:  "priority = 4",
+		defaultCritical: "priority <= 3",
+		detailSyntax:    "%(syslog_identifier): %(message)",
+		okSyntax:        "%(status) - Journal seems fine",
+		topSyntax:       "%(status) - %(count) message(s) %(problem_list)",
+		emptySyntax:     "%(status) - No entries found",
+		emptyState:      0,
+		attributes: []CheckAttribute{
+			{name: "unit", description: "The systemd unit which generated the message (_SYSTEMD_UNIT)"},
+			{name: "priority", description: "Syslog priority from 0 (emerg) to 7 (debug) (PRIORITY)"},
+			{name: "level", description: "Textual priority: emerg, alert, crit, err, warning, notice, info or debug"},
+			{name: "message", description: "The message as a string (MESSAGE)"},
+			{name: "pid", description: "Process id of the logging process (_PID)"},
+			{name: "syslog_identifier", description: "The syslog identifier (SYSLOG_IDENTIFIER)"},
+			{name: "hostname", description: "Hostname of the originating host (_HOSTNAME)"},
+			{name: "time", description: "Time of the message being written (__REALTIME_TIMESTAMP)", unit: UDate},
+		},
+		exampleDefault: `
+    check_journald
+    OK - Journal seems fine
+
+Check a specific unit for errors within the last hour:
+
+    check_journald unit=nginx.service scan-range=1h "filter=priority <= 3" "crit=count > 0"
+    CRITICAL - 1 message(s) nginx: connect() failed (111: Connection refused)
+
+Return all entries, by default only unique entries will be returned:
+
+    check_journald "unique-index=0"
+    WARNING - 3 message(s) kernel: usb 1-1: device descriptor read/64, error -71, ...
+	`,
+		exampleArgs: `filter="unit = 'nginx.service' and priority <= 3"`,
+	}
+}
@@ -0,0 +1,159 @@
+package snclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/convert"
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+// journaldLevels maps syslog priorities to their textual names
+var journaldLevels = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+func (l *CheckJournald) Check(ctx context.Context, snc *Agent, check *CheckData, _ []Argument) (*CheckResult, error) {
+	lookBack, err := utils.ExpandDuration(l.scanRange)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse scan-range: %s", err.Error())
+	}
+	if lookBack < 0 {
+		lookBack *= -1
+	}
+	scanLookBack := time.Now().Add(-time.Second * time.Duration(lookBack))
+
+	cmdArgs := []string{"-o", "json", "--no-pager", "-q", fmt.Sprintf("--since=@%d", scanLookBack.Unix())}
+	for _, unit := range l.units {
+		cmdArgs = append(cmdArgs, "-u", unit)
+	}
+	cmd := exec.CommandContext(ctx, "journalctl", cmdArgs...)
+	output, stderr, exitCode, _, err := snc.runExternalCommand(ctx, cmd, DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("journalctl failed: %s\n%s", err.Error(), stderr)
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("journalctl failed: %s\n%s", output, stderr)
+	}
+
+	if err := l.parseJournal(check, output); err != nil {
+		return nil, err
+	}
+
+	return check.Finalize()
+}
+
+// parseJournal parses the output of journalctl -o json and adds all entries to the check list data.
+func (l *CheckJournald) parseJournal(check *CheckData, output string) error {
+	uniqueIndexList := map[string]map[string]string{}
+	filterUnique := false
+
+	switch l.uniqueIndex {
+	case "", "0", "false", "no":
+		l.uniqueIndex = ""
+	case "1":
+		filterUnique = true
+		l.uniqueIndex = DefaultJournaldUniqueIndex
+	default:
+		filterUnique = true
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return fmt.Errorf("failed to parse journal entry: %s: %s", err.Error(), line)
+		}
+
+		unit := journaldField(fields, "_SYSTEMD_UNIT")
+		if unit == "" {
+			unit = journaldField(fields, "_SYSTEMD_USER_UNIT")
+		}
+		priority := journaldField(fields, "PRIORITY")
+		level := ""
+		if prio, err := convert.Int64E(priority); err == nil && prio >= 0 && prio < int64(len(journaldLevels)) {
+			level = journaldLevels[prio]
+		}
+		message := journaldField(fields, "MESSAGE")
+		if l.truncateMessage > 0 && len(message) > l.truncateMessage {
+			message = message[:l.truncateMessage]
+		}
+		timestamp := convert.Int64(journaldField(fields, "__REALTIME_TIMESTAMP")) / 1e6
+
+		listData := map[string]string{
+			"unit":              unit,
+			"priority":          priority,
+			"level":             level,
+			"message":           message,
+			"pid":               journaldField(fields, "_PID"),
+			"syslog_identifier": journaldField(fields, "SYSLOG_IDENTIFIER"),
+			"hostname":          journaldField(fields, "_HOSTNAME"),
+			"time":              fmt.Sprintf("%d", timestamp),
+		}
+		if !check.MatchMapCondition(check.filter, listData, true) {
+			continue
+		}
+
+		if !filterUnique {
+			check.listData = append(check.listData, listData)
+
+			continue
+		}
+
+		// filter out duplicate entries based on the unique-index argument
+		uniqueID := ReplaceMacros(l.uniqueIndex, check.timezone, listData)
+		log.Tracef("expanded unique filter: %s", uniqueID)
+		if prevEntry, ok := uniqueIndexList[uniqueID]; ok {
+			count := convert.Int64(prevEntry["_count"])
+			prevEntry["_count"] = fmt.Sprintf("%d", count+1)
+		} else {
+			check.listData = append(check.listData, listData)
+			listData["_count"] = "1"
+			uniqueIndexList[uniqueID] = listData
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read journal: %s", err.Error())
+	}
+
+	return nil
+}
+
+// journaldField returns given field as string.
+// Fields can be plain strings, byte arrays for binary data or lists of strings if a field is set multiple times.
+func journaldField(fields map[string]json.RawMessage, name string) string {
+	raw, ok := fields[name]
+	if !ok {
+		return ""
+	}
+
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+
+	var bytes []byte
+	var numbers []int
+	if err := json.Unmarshal(raw, &numbers); err == nil {
+		for _, num := range numbers {
+			bytes = append(bytes, byte(num))
+		}
+
+		return strings.ToValidUTF8(string(bytes), "?")
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, ", ")
+	}
+
+	return ""
+}
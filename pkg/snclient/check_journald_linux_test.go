@@ -0,0 +1,59 @@
+package snclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckJournaldParse(t *testing.T) {
+	output := `{"__REALTIME_TIMESTAMP":"1700000000123456","_SYSTEMD_UNIT":"nginx.service","PRIORITY":"3","MESSAGE":"connect() failed","_PID":"1234","SYSLOG_IDENTIFIER":"nginx","_HOSTNAME":"web1"}
+{"__REALTIME_TIMESTAMP":"1700000001123456","_SYSTEMD_UNIT":"nginx.service","PRIORITY":"3","MESSAGE":"connect() failed","_PID":"1234","SYSLOG_IDENTIFIER":"nginx","_HOSTNAME":"web1"}
+{"__REALTIME_TIMESTAMP":"1700000002123456","PRIORITY":"4","MESSAGE":[98,105,110,255],"SYSLOG_IDENTIFIER":"kernel","_HOSTNAME":"web1"}
+{"__REALTIME_TIMESTAMP":"1700000003123456","_SYSTEMD_UNIT":"cron.service","PRIORITY":"6","MESSAGE":"job started","_PID":"42","SYSLOG_IDENTIFIER":"CRON"}
+`
+	check := &CheckData{}
+	journald := &CheckJournald{uniqueIndex: "1"}
+	err := journald.parseJournal(check, output)
+	require.NoErrorf(t, err, "parse ok")
+	require.Lenf(t, check.listData, 3, "unique entries")
+
+	assert.Equal(t, map[string]string{
+		"unit":              "nginx.service",
+		"priority":          "3",
+		"level":             "err",
+		"message":           "connect() failed",
+		"pid":               "1234",
+		"syslog_identifier": "nginx",
+		"hostname":          "web1",
+		"time":              "1700000000",
+		"_count":            "2",
+	}, check.listData[0])
+	assert.Equalf(t, "bin?", check.listData[1]["message"], "binary message")
+	assert.Equalf(t, "warning", check.listData[1]["level"], "level")
+	assert.Equalf(t, "", check.listData[1]["unit"], "kernel has no unit")
+
+	check = &CheckData{}
+	journald = &CheckJournald{uniqueIndex: "0", truncateMessage: 3}
+	err = journald.parseJournal(check, output)
+	require.NoErrorf(t, err, "parse ok")
+	require.Lenf(t, check.listData, 4, "all entries")
+	assert.Equalf(t, "job", check.listData[3]["message"], "truncated message")
+}
+
+func TestCheckJournaldFilter(t *testing.T) {
+	output := `{"__REALTIME_TIMESTAMP":"1700000000123456","_SYSTEMD_UNIT":"nginx.service","PRIORITY":"3","MESSAGE":"connect() failed","SYSLOG_IDENTIFIER":"nginx"}
+{"__REALTIME_TIMESTAMP":"1700000001123456","_SYSTEMD_UNIT":"cron.service","PRIORITY":"2","MESSAGE":"crashed","SYSLOG_IDENTIFIER":"CRON"}
+{"__REALTIME_TIMESTAMP":"1700000002123456","_SYSTEMD_UNIT":"nginx.service","PRIORITY":"6","MESSAGE":"reloaded","SYSLOG_IDENTIFIER":"nginx"}
+`
+	cond, err := NewCondition("unit = 'nginx.service' and priority <= 3", nil)
+	require.NoErrorf(t, err, "condition ok")
+
+	check := &CheckData{filter: ConditionList{cond}}
+	journald := &CheckJournald{uniqueIndex: "1"}
+	err = journald.parseJournal(check, output)
+	require.NoErrorf(t, err, "parse ok")
+	require.Lenf(t, check.listData, 1, "filtered entries")
+	assert.Equalf(t, "connect() failed", check.listData[0]["message"], "message")
+}
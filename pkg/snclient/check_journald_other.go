@@ -0,0 +1,13 @@
+//go:build !linux
+
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+func (l *CheckJournald) Check(_ context.Context, _ *Agent, _ *CheckData, _ []Argument) (*CheckResult, error) {
+	return nil, fmt.Errorf("not implemented on platform %s", runtime.GOOS)
+}
@@ -0,0 +1,323 @@
+package snclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+func init() {
+	AvailableChecks["check_logfile"] = CheckEntry{"check_logfile", NewCheckLogFile}
+}
+
+// logFileStateLock serializes reading and updating offset files
+var logFileStateLock sync.Mutex
+
+// logFileOffset stores the position of the last fully read line of a logfile
+type logFileOffset struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+	LineNo int64  `json:"lineno"`
+}
+
+type CheckLogFile struct {
+	paths         []string
+	pathList      CommaStringList
+	offsetFile    string
+	readFromStart bool
+}
+
+func NewCheckLogFile() CheckHandler {
+	return &CheckLogFile{
+		pathList: CommaStringList{},
+	}
+}
+
+func (l *CheckLogFile) Build() *CheckData {
+	return &CheckData{
+		name: "check_logfile",
+		description: `Checks logfiles for new lines matching the filter.
+
+The read offset of each file is stored between runs, so each line will only be reported once.
+Rotated (changed inode) and truncated files will be read again from the beginning.
+
+Offsets are stored in the folder set by 'offset dir' in the /settings/logfile section
+(default: ${shared-path}/logfile).`,
+		implemented: ALL,
+		result: &CheckResult{
+			State: CheckExitOK,
+		},
+		args: map[string]CheckArgument{
+			"file":            {value: &l.paths, description: "Logfile to read, wildcards are supported (can be specified multiple times)", isFilter: true},
+			"path":            {value: &l.paths, description: "Alias for file", isFilter: true},
+			"files":           {value: &l.pathList, description: "A comma separated list of logfiles", isFilter: true},
+			"read-from-start": {value: &l.readFromStart, description: "Read new files from the beginning instead of starting at the current end of file (default: false)"},
+		},
+		detailSyntax: "%(filename): %(line)",
+		okSyntax:     "%(status) - All %(count) matching lines are ok (%(total) new lines read)",
+		topSyntax:    "%(status) - %(count) matching line(s): %(detail_list)",
+		emptySyntax:  "%(status) - No new lines found",
+		emptyState:   CheckExitOK,
+		attributes: []CheckAttribute{
+			{name: "line", description: "The content of the matched line"},
+			{name: "file", description: "Full path of the logfile"},
+			{name: "filename", description: "Name of the logfile without path"},
+			{name: "lineno", description: "Line number within the logfile (counted since the last rotation)"},
+			{name: "age", description: "Seconds since the logfile was last written", unit: UDuration},
+			{name: "total", description: "Total number of new lines read"},
+		},
+		exampleDefault: `
+Alert on new error lines in the syslog:
+
+    check_logfile file=/var/log/syslog "filter=line like 'error'" "crit=count > 0"
+    OK - No new lines found
+
+Match multiple files by wildcard:
+
+    check_logfile file="/var/log/nginx/*.log" "filter=line ~ 'HTTP/1.1\" 5\d\d'" "warn=count > 0" "crit=count > 10"
+    CRITICAL - 12 matching line(s): access.log: ...
+	`,
+		exampleArgs: `'file=/var/log/messages' 'filter=line like "error"' 'crit=count > 0'`,
+	}
+}
+
+func (l *CheckLogFile) Check(_ context.Context, snc *Agent, check *CheckData, _ []Argument) (*CheckResult, error) {
+	l.paths = append(l.paths, l.pathList...)
+	if len(l.paths) == 0 {
+		return nil, fmt.Errorf("no file specified")
+	}
+
+	files, err := l.expandPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	offsetDir, err := l.offsetDir(snc)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := utils.Sha256Sum(strings.Join(check.rawArgs, "\x00"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build offset file name: %s", err.Error())
+	}
+	l.offsetFile = filepath.Join(offsetDir, fmt.Sprintf("logfile-%s.json", sum[0:16]))
+
+	logFileStateLock.Lock()
+	defer logFileStateLock.Unlock()
+
+	// offsets of files not part of this run are kept
+	offsets := l.readOffsets()
+	total := int64(0)
+	for _, file := range files {
+		offset, num, err := l.readFile(check, file, offsets)
+		if err != nil {
+			return nil, err
+		}
+		offsets[file] = *offset
+		total += num
+	}
+
+	if err := l.writeOffsets(offsets); err != nil {
+		return nil, err
+	}
+
+	check.details = map[string]string{
+		"total": fmt.Sprintf("%d", total),
+	}
+
+	if check.HasThreshold("count") {
+		check.result.Metrics = append(check.result.Metrics,
+			&CheckMetric{
+				Name:     "count",
+				Value:    int64(len(check.listData)),
+				Warning:  check.warnThreshold,
+				Critical: check.critThreshold,
+				Min:      &Zero,
+			})
+	}
+
+	return check.Finalize()
+}
+
+// offsetDir returns the private folder used to store offset files, it will be created if required.
+func (l *CheckLogFile) offsetDir(snc *Agent) (string, error) {
+	offsetDir, ok := snc.config.Section("/settings/logfile").GetString("offset dir")
+	if !ok || offsetDir == "" {
+		sharedPath, _ := snc.config.Section("/paths").GetString("shared-path")
+		offsetDir = filepath.Join(sharedPath, "logfile")
+	}
+
+	if err := os.MkdirAll(offsetDir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create offset dir %s: %s", offsetDir, err.Error())
+	}
+
+	return offsetDir, nil
+}
+
+// expandPaths returns sorted list of all files matching the given paths and globs.
+func (l *CheckLogFile) expandPaths() ([]string, error) {
+	files := []string{}
+	for _, path := range l.paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !strings.ContainsAny(path, "*?[") {
+			files = append(files, path)
+
+			continue
+		}
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %s: %s", path, err.Error())
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// readFile reads all new lines from given file and returns the new offset along with the number of lines read.
+func (l *CheckLogFile) readFile(check *CheckData, file string, offsets map[string]logFileOffset) (offset *logFileOffset, lines int64, err error) {
+	fileHandle, err := os.Open(file)
+	if err != nil {
+		switch {
+		case os.IsNotExist(err):
+			return nil, 0, fmt.Errorf("%s: no such file or directory", file)
+		case os.IsPermission(err):
+			return nil, 0, fmt.Errorf("%s: file not readable", file)
+		default:
+			return nil, 0, fmt.Errorf("%s: %s", file, err.Error())
+		}
+	}
+	defer fileHandle.Close()
+
+	fileInfo, err := fileHandle.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("stat %s: %s", file, err.Error())
+	}
+	if fileInfo.IsDir() {
+		return nil, 0, fmt.Errorf("%s: is a directory", file)
+	}
+
+	offset = &logFileOffset{
+		Inode: getFileInode(fileInfo),
+	}
+	prev, ok := offsets[file]
+	skipLines := false
+	switch {
+	case !ok && !l.readFromStart:
+		log.Debugf("logfile %s seen first time, starting at end of file", file)
+		skipLines = true
+	case !ok:
+		log.Debugf("logfile %s seen first time, starting at beginning", file)
+	case prev.Inode != offset.Inode:
+		log.Debugf("logfile %s has been rotated (inode %d -> %d)", file, prev.Inode, offset.Inode)
+	case fileInfo.Size() < prev.Offset:
+		log.Debugf("logfile %s has been truncated (size %d < offset %d)", file, fileInfo.Size(), prev.Offset)
+	default:
+		offset.Offset = prev.Offset
+		offset.LineNo = prev.LineNo
+	}
+
+	if offset.Offset == fileInfo.Size() {
+		return offset, 0, nil
+	}
+
+	if _, err = fileHandle.Seek(offset.Offset, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("seek %s: %s", file, err.Error())
+	}
+
+	age := fmt.Sprintf("%d", time.Now().Unix()-fileInfo.ModTime().Unix())
+	filename := filepath.Base(file)
+	reader := bufio.NewReader(fileHandle)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// incomplete lines will be read next time
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, 0, fmt.Errorf("read %s: %s", file, err.Error())
+		}
+		offset.Offset += int64(len(line))
+		offset.LineNo++
+		if skipLines {
+			continue
+		}
+		lines++
+
+		entry := map[string]string{
+			"line":     strings.TrimRight(line, "\r\n"),
+			"file":     file,
+			"filename": filename,
+			"lineno":   fmt.Sprintf("%d", offset.LineNo),
+			"age":      age,
+		}
+		if check.MatchMapCondition(check.filter, entry, true) {
+			check.listData = append(check.listData, entry)
+		}
+	}
+
+	return offset, lines, nil
+}
+
+// readOffsets returns the stored offsets or an empty map if none found.
+func (l *CheckLogFile) readOffsets() map[string]logFileOffset {
+	offsets := make(map[string]logFileOffset)
+	data, err := os.ReadFile(l.offsetFile)
+	if err != nil {
+		log.Tracef("reading offset file %s failed: %s", l.offsetFile, err.Error())
+
+		return offsets
+	}
+
+	if err := json.Unmarshal(data, &offsets); err != nil {
+		log.Warnf("offset file %s is corrupt, starting over: %s", l.offsetFile, err.Error())
+
+		return make(map[string]logFileOffset)
+	}
+
+	return offsets
+}
+
+// writeOffsets stores offsets in the offset file.
+func (l *CheckLogFile) writeOffsets(offsets map[string]logFileOffset) error {
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return fmt.Errorf("json error: %s", err.Error())
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(l.offsetFile), filepath.Base(l.offsetFile)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create offset file: %s", err.Error())
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if err2 := tmpFile.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write offset file %s: %s", tmpFile.Name(), err.Error())
+	}
+
+	if err := os.Rename(tmpFile.Name(), l.offsetFile); err != nil {
+		return fmt.Errorf("failed to write offset file %s: %s", l.offsetFile, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package snclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLogFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	snc := StartTestAgent(t, `
+[/settings/logfile]
+offset dir = `+filepath.Join(tmpDir, "offsets")+`
+`)
+
+	logFile := filepath.Join(tmpDir, "test.log")
+	err := os.WriteFile(logFile, []byte("line 1 ok\nline 2 error\n"), 0o600)
+	require.NoErrorf(t, err, "log file written")
+
+	args := []string{"file=" + filepath.Join(tmpDir, "*.log"), "filter=line like 'error'", "crit=count > 0", "detail-syntax=%(lineno): %(line)"}
+
+	res := snc.RunCheck("check_logfile", []string{})
+	assert.Equalf(t, CheckExitUnknown, res.State, "state Unknown")
+	assert.Contains(t, string(res.BuildPluginOutput()), "UNKNOWN - no file specified")
+
+	// first run starts at end of file
+	res = snc.RunCheck("check_logfile", args)
+	assert.Equalf(t, CheckExitOK, res.State, "state OK")
+	assert.Contains(t, string(res.BuildPluginOutput()), "OK - No new lines found")
+
+	appendLogFile(t, logFile, "line 3 ok\nline 4 error\nincomplete error")
+	res = snc.RunCheck("check_logfile", args)
+	assert.Equalf(t, CheckExitCritical, res.State, "state Critical")
+	assert.Contains(t, string(res.BuildPluginOutput()), "CRITICAL - 1 matching line(s): 4: line 4 error |'count'=1;;0;0")
+
+	// only new lines are reported
+	appendLogFile(t, logFile, " line\nline 6 ok\n")
+	res = snc.RunCheck("check_logfile", args)
+	assert.Equalf(t, CheckExitCritical, res.State, "state Critical")
+	assert.Contains(t, string(res.BuildPluginOutput()), "CRITICAL - 1 matching line(s): 5: incomplete error line")
+
+	res = snc.RunCheck("check_logfile", args)
+	assert.Equalf(t, CheckExitOK, res.State, "state OK")
+	assert.Contains(t, string(res.BuildPluginOutput()), "OK - No new lines found")
+
+	// truncated file starts from the beginning
+	err = os.WriteFile(logFile, []byte("error after truncate\n"), 0o600)
+	require.NoErrorf(t, err, "log file truncated")
+	res = snc.RunCheck("check_logfile", args)
+	assert.Equalf(t, CheckExitCritical, res.State, "state Critical")
+	assert.Contains(t, string(res.BuildPluginOutput()), "CRITICAL - 1 matching line(s): 1: error after truncate")
+
+	// rotated file starts from the beginning
+	require.NoErrorf(t, os.Rename(logFile, logFile+".1"), "log file rotated")
+	err = os.WriteFile(logFile, []byte("first ok\nerror after rotate\n"), 0o600)
+	require.NoErrorf(t, err, "log file rotated")
+	res = snc.RunCheck("check_logfile", args)
+	assert.Equalf(t, CheckExitCritical, res.State, "state Critical")
+	assert.Contains(t, string(res.BuildPluginOutput()), "CRITICAL - 1 matching line(s): 2: error after rotate")
+
+	res = snc.RunCheck("check_logfile", []string{"file=" + logFile + ".1", "read-from-start"})
+	assert.Equalf(t, CheckExitOK, res.State, "state OK")
+	assert.Contains(t, string(res.BuildPluginOutput()), "OK - All 1 matching lines are ok (1 new lines read)")
+
+	res = snc.RunCheck("check_logfile", []string{"file=" + filepath.Join(tmpDir, "none.log")})
+	assert.Equalf(t, CheckExitUnknown, res.State, "state Unknown")
+	assert.Contains(t, string(res.BuildPluginOutput()), "none.log: no such file or directory")
+
+	// offsets of files temporarily not matching the pattern are kept
+	otherDir := filepath.Join(tmpDir, "other")
+	require.NoErrorf(t, os.MkdirAll(otherDir, 0o700), "other dir created")
+	otherFile := filepath.Join(otherDir, "a.log")
+	require.NoErrorf(t, os.WriteFile(otherFile, []byte("error a\n"), 0o600), "log file written")
+	otherArgs := []string{"file=" + filepath.Join(otherDir, "*.log"), "read-from-start", "filter=line like 'error'", "crit=count > 0"}
+	res = snc.RunCheck("check_logfile", otherArgs)
+	assert.Equalf(t, CheckExitCritical, res.State, "state Critical")
+
+	require.NoErrorf(t, os.Rename(otherFile, otherFile+".bak"), "log file moved")
+	require.NoErrorf(t, os.WriteFile(filepath.Join(otherDir, "b.log"), []byte("ok b\n"), 0o600), "log file written")
+	res = snc.RunCheck("check_logfile", otherArgs)
+	assert.Equalf(t, CheckExitOK, res.State, "state OK")
+
+	require.NoErrorf(t, os.Rename(otherFile+".bak", otherFile), "log file moved back")
+	res = snc.RunCheck("check_logfile", otherArgs)
+	assert.Equalf(t, CheckExitOK, res.State, "state OK")
+	assert.Contains(t, string(res.BuildPluginOutput()), "OK - No new lines found")
+
+	StopTestAgent(t, snc)
+}
+
+func appendLogFile(t *testing.T, file, data string) {
+	t.Helper()
+	fileHandle, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoErrorf(t, err, "log file opened")
+	_, err = fileHandle.WriteString(data)
+	require.NoErrorf(t, err, "log file written")
+	require.NoErrorf(t, fileHandle.Close(), "log file closed")
+}
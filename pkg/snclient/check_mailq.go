@@ -1,21 +1,56 @@
 package snclient
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/consol-monitoring/snclient/pkg/convert"
+	"github.com/consol-monitoring/snclient/pkg/humanize"
+	"github.com/consol-monitoring/snclient/pkg/utils"
 )
 
 func init() {
 	AvailableChecks["check_mailq"] = CheckEntry{"check_mailq", NewCheckMailq}
 }
 
+const (
+	mailqDetailSyntax  = "${mta}: active ${active} / deferred ${deferred}"
+	mailqTopSyntax     = "%(status) - ${list}"
+	mailqEmptySyntax   = "%(status) - could not get any mailq data"
+	mailqMessageSyntax = "${id} to ${recipient_domain} (${queue}, ${age | duration})"
+)
+
+var (
+	// sendmail: u5ENhEXa003517*     583 Tue Jun 14 23:43 <root@test.local>
+	reMailqSendmailMessage = regexp.MustCompile(`^(\w+?)([*X-]?)\s+(\d+)\s+(\w{3}\s+\w{3}\s+\d+\s+\d+:\d+)\s+(\S+)`)
+
+	// exim: 25m  2.9K 1hBqXm-0003Yb-Vl <sender@example.com> *** frozen ***
+	reMailqEximMessage = regexp.MustCompile(`^\s*(\d+[smhdw])\s+([\d.]+[KMG]?)\s+(\S+)\s+<([^>]*)>(.*)$`)
+)
+
 type CheckMailq struct {
-	snc *Agent
-	mta string
+	snc      *Agent
+	mta      string
+	messages bool
+}
+
+// mailqMessage is a single message from the mail queue.
+type mailqMessage struct {
+	queue      string
+	id         string
+	sender     string
+	recipients []string
+	arrival    time.Time
+	size       int64
+	reason     string
 }
 
 func NewCheckMailq() CheckHandler {
@@ -26,23 +61,35 @@ func NewCheckMailq() CheckHandler {
 
 func (l *CheckMailq) Build() *CheckData {
 	return &CheckData{
-		name:         "check_mailq",
-		description:  "Checks the mailq.",
+		name: "check_mailq",
+		description: `Checks the mailq.
+
+Supports postfix, exim, sendmail and opensmtpd. Exim does not list the retry state of messages, queued exim
+messages are counted as active unless they are frozen or have been delivered to some recipients already, those
+are counted as deferred.
+Use the messages argument to check each queued message instead of the queue summary.`,
 		implemented:  Linux | FreeBSD | Darwin,
 		hasInventory: ListInventory,
 		result: &CheckResult{
 			State: CheckExitOK,
 		},
 		args: map[string]CheckArgument{
-			"mta": {value: &l.mta, isFilter: true, description: "Set source mta for checking mailq instead of auto detect. Can be postfix or auto"},
+			"mta": {value: &l.mta, isFilter: true, description: "Set source mta for checking mailq instead of auto detect. Can be postfix, exim, sendmail, opensmtpd or auto"},
+			"messages": {
+				value:           &l.messages,
+				isFilter:        true,
+				description:     "List each queued message instead of the queue summary",
+				defaultWarning:  "age > 1h",
+				defaultCritical: "age > 4h",
+			},
 		},
 		defaultFilter:   "none",
 		defaultWarning:  "active > 5 || active_size > 10MB || deferred > 0 || deferred_size > 10MB",
 		defaultCritical: "active > 10 || active_size > 20MB || deferred > 10 || deferred_size > 20MB",
-		detailSyntax:    "${mta}: active ${active} / deferred ${deferred}",
-		topSyntax:       "%(status) - ${list}",
+		detailSyntax:    mailqDetailSyntax,
+		topSyntax:       mailqTopSyntax,
 		emptyState:      CheckExitUnknown,
-		emptySyntax:     "%(status) - could not get any mailq data",
+		emptySyntax:     mailqEmptySyntax,
 		attributes: []CheckAttribute{
 			{name: "mta", description: "name of the mta"},
 			{name: "folder", description: "checked spool folder"},
@@ -50,10 +97,31 @@ func (l *CheckMailq) Build() *CheckData {
 			{name: "active_size", description: "size of active mails in bytes", unit: UByte},
 			{name: "deferred", description: "number of deferred mails"},
 			{name: "deferred_size", description: "size of deferred mails in bytes", unit: UByte},
+			{name: "queue", description: "queue of the message, ex.: active, deferred, hold (messages only)"},
+			{name: "id", description: "queue id of the message (messages only)"},
+			{name: "sender", description: "sender address (messages only)"},
+			{name: "recipients", description: "comma separated list of recipients of this domain (messages only)"},
+			{name: "recipient_domain", description: "domain of the recipients (messages only)"},
+			{name: "arrival", description: "date when the message was queued (messages only)", unit: UDate},
+			{name: "age", description: "seconds since the message was queued (messages only)", unit: UDuration},
+			{name: "size", description: "size of the message in bytes (messages only)", unit: UByte},
+			{name: "reason", description: "reason why the message was deferred (messages only)"},
 		},
 		exampleDefault: `
     check_mailq
     OK - postfix: active 0 / deferred 0 |...
+
+Alert on deferred mails to gmail.com which are older than one hour:
+
+    check_mailq messages "filter=queue = deferred and recipient_domain = gmail.com" "crit=age > 1h"
+    CRITICAL - 1/1 messages: critical(4E2A1C3B2F to gmail.com (deferred, 01:12h))
+
+Show number of queued mails per recipient domain:
+
+    check_mailq messages group-by=recipient_domain "detail-syntax=${recipient_domain}: ${group_count}" show-all
+    OK - example.com: 3, gmail.com: 1
+
+Messages with recipients in multiple domains are listed once for each domain.
 	`,
 		exampleArgs: `warn='active > 5 || deferred > 0' crit='active > 10 || deferred > 10'`,
 	}
@@ -62,35 +130,105 @@ func (l *CheckMailq) Build() *CheckData {
 func (l *CheckMailq) Check(ctx context.Context, snc *Agent, check *CheckData, _ []Argument) (*CheckResult, error) {
 	l.snc = snc
 
+	if l.messages {
+		l.setMessageDefaults(check)
+	}
+
 	err := l.addQueues(ctx, check)
 	if err != nil {
 		return nil, err
 	}
 
+	if l.messages && check.HasThreshold("count") {
+		check.result.Metrics = append(check.result.Metrics,
+			&CheckMetric{
+				Name:     "count",
+				Value:    int64(len(check.listData)),
+				Warning:  check.warnThreshold,
+				Critical: check.critThreshold,
+				Min:      &Zero,
+			})
+	}
+
 	return check.Finalize()
 }
 
-func (l *CheckMailq) addQueues(ctx context.Context, check *CheckData) (err error) {
-	if l.mta == "auto" || l.mta == "postfix" {
-		err = l.addPostfix(ctx, check)
-		if err != nil {
-			log.Debugf("failed: postfix: %s", err.Error())
-			if l.mta != "auto" {
-				return err
-			}
-		} else {
+// setMessageDefaults replaces the summary syntax defaults unless they have been changed by arguments.
+func (l *CheckMailq) setMessageDefaults(check *CheckData) {
+	if check.detailSyntax == mailqDetailSyntax {
+		check.detailSyntax = mailqMessageSyntax
+	}
+	if check.topSyntax == mailqTopSyntax {
+		check.topSyntax = "%(status) - %(problem_count)/%(count) messages: %(problem_list)"
+	}
+	if check.okSyntax == "" {
+		check.okSyntax = "%(status) - %(count) messages in queue"
+	}
+	if check.emptySyntax == mailqEmptySyntax {
+		check.emptySyntax = "%(status) - no messages in queue"
+	}
+	if !check.emptyStateSet {
+		check.emptyState = CheckExitOK
+	}
+}
+
+func (l *CheckMailq) addQueues(ctx context.Context, check *CheckData) error {
+	sources := []struct {
+		mta string
+		fn  func(context.Context, *CheckData) error
+	}{
+		{"postfix", l.addPostfix},
+		{"exim", l.addExim},
+		{"opensmtpd", l.addOpenSMTPD},
+		{"sendmail", l.addSendmail},
+	}
+
+	errs := []string{}
+	for _, source := range sources {
+		if l.mta != "auto" && l.mta != source.mta {
+			continue
+		}
+
+		err := source.fn(ctx, check)
+		if err == nil {
 			return nil
 		}
+		log.Debugf("failed: %s: %s", source.mta, err.Error())
+		if l.mta != "auto" {
+			return fmt.Errorf("%s: %s", source.mta, err.Error())
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", source.mta, err.Error()))
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("unknown mta %s, must be one of: postfix, exim, sendmail, opensmtpd or auto", l.mta)
 	}
 
-	return err
+	return fmt.Errorf("no supported mta found:\n%s", strings.Join(errs, "\n"))
 }
 
 // get queue from postfix
 func (l *CheckMailq) addPostfix(ctx context.Context, check *CheckData) error {
+	if l.messages {
+		output, stderr, rc, err := l.snc.execCommand(ctx, "postqueue -j", DefaultCmdTimeout)
+		if err != nil {
+			return fmt.Errorf("postqueue failed: %s\n%s", err.Error(), stderr)
+		}
+		if rc != 0 {
+			return fmt.Errorf("postqueue failed: %s\n%s", output, stderr)
+		}
+		messages, err := parseMailqPostfix(output)
+		if err != nil {
+			return err
+		}
+		l.addMessages(check, "postfix", messages)
+
+		return nil
+	}
+
 	queueFolder, stderr, rc, err := l.snc.execCommand(ctx, "postconf -h queue_directory", DefaultCmdTimeout)
 	if err != nil {
-		return fmt.Errorf("postfix: postconf failed: %s\n%s", err.Error(), stderr)
+		return fmt.Errorf("postconf failed: %s\n%s", err.Error(), stderr)
 	}
 	if rc != 0 {
 		return fmt.Errorf("postconf failed: %s\n%s", queueFolder, stderr)
@@ -112,6 +250,325 @@ func (l *CheckMailq) addPostfix(ctx context.Context, check *CheckData) error {
 	return nil
 }
 
+// get queue from exim
+func (l *CheckMailq) addExim(ctx context.Context, check *CheckData) error {
+	var lastErr error
+	for _, binary := range []string{"exim", "exim4"} {
+		output, stderr, rc, err := l.snc.execCommand(ctx, binary+" -bp", DefaultCmdTimeout)
+		switch {
+		case err != nil:
+			lastErr = fmt.Errorf("%s -bp failed: %s\n%s", binary, err.Error(), stderr)
+		case rc != 0:
+			lastErr = fmt.Errorf("%s -bp failed: %s\n%s", binary, output, stderr)
+		default:
+			l.addMessages(check, "exim", parseMailqExim(output, time.Now()))
+
+			return nil
+		}
+	}
+
+	return lastErr
+}
+
+// get queue from opensmtpd
+func (l *CheckMailq) addOpenSMTPD(ctx context.Context, check *CheckData) error {
+	output, stderr, rc, err := l.snc.execCommand(ctx, "smtpctl show queue", DefaultCmdTimeout)
+	if err != nil {
+		return fmt.Errorf("smtpctl failed: %s\n%s", err.Error(), stderr)
+	}
+	if rc != 0 {
+		return fmt.Errorf("smtpctl failed: %s\n%s", output, stderr)
+	}
+
+	l.addMessages(check, "opensmtpd", parseMailqOpenSMTPD(output))
+
+	return nil
+}
+
+// get queue from sendmail
+func (l *CheckMailq) addSendmail(ctx context.Context, check *CheckData) error {
+	output, stderr, rc, err := l.snc.execCommand(ctx, "sendmail -bp", DefaultCmdTimeout)
+	if err != nil {
+		return fmt.Errorf("sendmail -bp failed: %s\n%s", err.Error(), stderr)
+	}
+	if rc != 0 {
+		return fmt.Errorf("sendmail -bp failed: %s\n%s", output, stderr)
+	}
+
+	l.addMessages(check, "sendmail", parseMailqSendmail(output, time.Now()))
+
+	return nil
+}
+
+// addMessages adds either the summary entry or one entry for each message and recipient domain.
+func (l *CheckMailq) addMessages(check *CheckData, mta string, messages []mailqMessage) {
+	if !l.messages {
+		entry := l.defaultEntry(mta)
+		for _, queue := range []string{"active", "deferred"} {
+			count := int64(0)
+			size := int64(0)
+			for i := range messages {
+				if mailqSummaryQueue(messages[i].queue) == queue {
+					count++
+					size += messages[i].size
+				}
+			}
+			entry[queue] = fmt.Sprintf("%d", count)
+			entry[queue+"_size"] = fmt.Sprintf("%d", size)
+		}
+		check.listData = append(check.listData, entry)
+		l.addMetrics(check, entry)
+
+		return
+	}
+
+	now := time.Now()
+	for i := range messages {
+		msg := &messages[i]
+		domains := map[string][]string{}
+		for _, rcpt := range msg.recipients {
+			domain := mailqDomain(rcpt)
+			domains[domain] = append(domains[domain], rcpt)
+		}
+		if len(domains) == 0 {
+			domains[""] = []string{}
+		}
+
+		for _, domain := range utils.SortedKeys(domains) {
+			check.listData = append(check.listData, map[string]string{
+				"mta":              mta,
+				"queue":            msg.queue,
+				"id":               msg.id,
+				"sender":           msg.sender,
+				"recipients":       strings.Join(domains[domain], ", "),
+				"recipient_domain": domain,
+				"arrival":          fmt.Sprintf("%d", msg.arrival.Unix()),
+				"age":              fmt.Sprintf("%d", int64(now.Sub(msg.arrival).Seconds())),
+				"size":             fmt.Sprintf("%d", msg.size),
+				"reason":           msg.reason,
+			})
+		}
+	}
+}
+
+// mailqSummaryQueue maps the mta specific queue names to active / deferred.
+func mailqSummaryQueue(queue string) string {
+	switch queue {
+	case "active":
+		return "active"
+	case "deferred", "frozen":
+		return "deferred"
+	}
+
+	return ""
+}
+
+// mailqDomain returns the lower case domain of a mail address.
+func mailqDomain(address string) string {
+	address = strings.Trim(address, "<>")
+	idx := strings.LastIndex(address, "@")
+	if idx == -1 {
+		return ""
+	}
+
+	return strings.ToLower(address[idx+1:])
+}
+
+// parseMailqPostfix parses the json lines output of postqueue -j
+func parseMailqPostfix(output string) ([]mailqMessage, error) {
+	messages := []mailqMessage{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		data := struct {
+			QueueName   string `json:"queue_name"`
+			QueueID     string `json:"queue_id"`
+			ArrivalTime int64  `json:"arrival_time"`
+			MessageSize int64  `json:"message_size"`
+			Sender      string `json:"sender"`
+			Recipients  []struct {
+				Address     string `json:"address"`
+				DelayReason string `json:"delay_reason"`
+			} `json:"recipients"`
+		}{}
+		if err := json.Unmarshal([]byte(line), &data); err != nil {
+			return nil, fmt.Errorf("postqueue: cannot parse json: %s", err.Error())
+		}
+
+		msg := mailqMessage{
+			queue:   data.QueueName,
+			id:      data.QueueID,
+			sender:  data.Sender,
+			arrival: time.Unix(data.ArrivalTime, 0),
+			size:    data.MessageSize,
+		}
+		for _, rcpt := range data.Recipients {
+			msg.recipients = append(msg.recipients, rcpt.Address)
+			if msg.reason == "" {
+				msg.reason = rcpt.DelayReason
+			}
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// parseMailqExim parses the output of exim -bp
+func parseMailqExim(output string, now time.Time) []mailqMessage {
+	messages := []mailqMessage{}
+	var current *mailqMessage
+	for _, line := range strings.Split(output, "\n") {
+		if matches := reMailqEximMessage.FindStringSubmatch(line); matches != nil {
+			age, _ := utils.ExpandDuration(matches[1])
+			size := matches[2]
+			if strings.HasSuffix(size, "K") || strings.HasSuffix(size, "M") || strings.HasSuffix(size, "G") {
+				size += "i"
+			}
+			bytes, _ := humanize.ParseBytes(size)
+
+			queue := "active"
+			if strings.Contains(matches[5], "*** frozen ***") {
+				queue = "frozen"
+			}
+			messages = append(messages, mailqMessage{
+				queue:   queue,
+				id:      matches[3],
+				sender:  matches[4],
+				arrival: now.Add(-time.Duration(age) * time.Second),
+				size:    int64(bytes), //nolint:gosec // message sizes are small
+			})
+			current = &messages[len(messages)-1]
+
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if current == nil || len(fields) == 0 || line[0] != ' ' {
+			current = nil
+
+			continue
+		}
+
+		// already delivered recipients are prefixed with D, the remaining recipients are retried
+		if len(fields) > 1 && (fields[0] == "D" || fields[0] == "+D") {
+			if current.queue == "active" {
+				current.queue = "deferred"
+			}
+
+			continue
+		}
+		current.recipients = append(current.recipients, fields[len(fields)-1])
+	}
+
+	return messages
+}
+
+// parseMailqSendmail parses the output of sendmail -bp
+func parseMailqSendmail(output string, now time.Time) []mailqMessage {
+	messages := []mailqMessage{}
+	var current *mailqMessage
+	for _, line := range strings.Split(output, "\n") {
+		if matches := reMailqSendmailMessage.FindStringSubmatch(line); matches != nil {
+			queue := "deferred"
+			if matches[2] == "*" {
+				queue = "active"
+			}
+			messages = append(messages, mailqMessage{
+				queue:   queue,
+				id:      matches[1],
+				sender:  strings.Trim(matches[5], "<>"),
+				arrival: parseMailqSendmailDate(matches[4], now),
+				size:    convert.Int64(matches[3]),
+			})
+			current = &messages[len(messages)-1]
+
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if current == nil || trimmed == "" || (line[0] != ' ' && line[0] != '\t') || strings.HasPrefix(trimmed, "Total requests:") {
+			current = nil
+
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "(") {
+			current.reason = strings.Trim(trimmed, "()")
+
+			continue
+		}
+		current.recipients = append(current.recipients, strings.Trim(trimmed, "<>"))
+	}
+
+	return messages
+}
+
+// parseMailqSendmailDate parses dates like "Tue Jun 14 23:43" which do not contain a year.
+func parseMailqSendmailDate(date string, now time.Time) time.Time {
+	date = strings.Join(strings.Fields(date), " ")
+	parsed, err := time.ParseInLocation("Mon Jan 2 15:04", date, now.Location())
+	if err != nil {
+		log.Debugf("sendmail: cannot parse date %s: %s", date, err.Error())
+
+		return now
+	}
+
+	parsed = parsed.AddDate(now.Year(), 0, 0)
+	if parsed.After(now.Add(24 * time.Hour)) {
+		parsed = parsed.AddDate(-1, 0, 0)
+	}
+
+	return parsed
+}
+
+// parseMailqOpenSMTPD parses the output of smtpctl show queue, it contains one line per envelope (recipient):
+// id|src|agent|flags|sender|rcpt|dest|creation|expire|lasttry|retry|runstate|runtime|errorline
+func parseMailqOpenSMTPD(output string) []mailqMessage {
+	messages := []mailqMessage{}
+	index := map[string]int{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) < 14 || len(fields[0]) != 16 {
+			continue
+		}
+
+		// the first 8 characters of the envelope id are the message id
+		msgID := fields[0][:8]
+		idx, ok := index[msgID]
+		if !ok {
+			queue := "deferred"
+			switch {
+			case slices.Contains(strings.Split(fields[3], ","), "hold"):
+				queue = "hold"
+			case fields[11] == "inflight":
+				queue = "active"
+			}
+			messages = append(messages, mailqMessage{
+				queue:   queue,
+				id:      msgID,
+				sender:  fields[4],
+				arrival: time.Unix(convert.Int64(fields[7]), 0),
+			})
+			idx = len(messages) - 1
+			index[msgID] = idx
+		}
+
+		msg := &messages[idx]
+		msg.recipients = append(msg.recipients, fields[5])
+		if msg.reason == "" {
+			msg.reason = strings.TrimSpace(strings.Join(fields[13:], "|"))
+		}
+	}
+
+	return messages
+}
+
 func (l *CheckMailq) defaultEntry(source string) map[string]string {
 	return map[string]string{
 		"mta":           source,
@@ -0,0 +1,170 @@
+package snclient
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mailqEximOutput = `
+25m  2.9K 1hBqXm-0003Yb-Vl <alice@example.com>
+          bob@Example.com
+        D carol@example.com
+          dave@gmail.com
+
+ 2h   512 1hBqXn-0003Yc-AB <> *** frozen ***
+          postmaster@gmail.com
+
+ 3m  1.0K 1hBqXo-0003Yd-CD <bob@example.com>
+          eve@example.org
+
+`
+
+func TestCheckMailqExim(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("check_mailq is not available on windows")
+	}
+
+	binDir := t.TempDir()
+	script := "#!/bin/sh\ncat <<'EOF'\n" + mailqEximOutput + "EOF\n"
+	require.NoErrorf(t, os.WriteFile(filepath.Join(binDir, "exim"), []byte(script), 0o700), "fake exim written") //nolint:gosec // test script must be executable
+	t.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+
+	snc := StartTestAgent(t, "")
+
+	res := snc.RunCheck("check_mailq", []string{"mta=exim"})
+	assert.Equalf(t, CheckExitWarning, res.State, "deferred mails")
+	assert.Equalf(t, "WARNING - exim: active 1 / deferred 2 |'active'=1;5;10;0 'active_size'=1024B;10000000;20000000;0 "+
+		"'deferred'=2;0;10;0 'deferred_size'=3481B;10000000;20000000;0", string(res.BuildPluginOutput()), "summary output")
+
+	res = snc.RunCheck("check_mailq", []string{"messages", "filter=recipient_domain = gmail.com", "crit=age > 1h"})
+	assert.Equalf(t, CheckExitCritical, res.State, "old message to gmail")
+	assert.Equalf(t, "CRITICAL - 1/2 messages: critical(1hBqXn-0003Yc-AB to gmail.com (frozen, 02:00h))", res.Output, "messages output")
+
+	res = snc.RunCheck("check_mailq", []string{"messages", "group-by=recipient_domain", "detail-syntax=${recipient_domain}: ${group_count}", "show-all"})
+	assert.Equalf(t, CheckExitWarning, res.State, "default thresholds")
+	assert.Equalf(t, "WARNING - example.com: 1, gmail.com: 2, example.org: 1", res.Output, "grouped by domain")
+
+	res = snc.RunCheck("check_mailq", []string{"messages", "filter=recipient_domain = none.com"})
+	assert.Equalf(t, CheckExitOK, res.State, "no matching messages")
+	assert.Equalf(t, "OK - no messages in queue", res.Output, "empty output")
+
+	// auto detection reports the errors of all mta
+	t.Setenv("PATH", t.TempDir())
+	res = snc.RunCheck("check_mailq", []string{})
+	assert.Equalf(t, CheckExitUnknown, res.State, "no mta")
+	assert.Containsf(t, res.Output, "postfix:", "postfix error")
+	assert.Containsf(t, res.Output, "exim:", "exim error")
+	assert.Containsf(t, res.Output, "sendmail", "sendmail error")
+
+	StopTestAgent(t, snc)
+}
+
+func TestCheckMailqParsePostfix(t *testing.T) {
+	output := `{"queue_name": "deferred", "queue_id": "4E2A1C3B2F", "arrival_time": 1700000000, "message_size": 2345, "forced_expire": false, "sender": "alice@example.com", "recipients": [{"address": "bob@gmail.com", "delay_reason": "connect to gmail.com: Connection timed out"}]}
+{"queue_name": "active", "queue_id": "5F3B2D4C3A", "arrival_time": 1700000100, "message_size": 512, "forced_expire": false, "sender": "", "recipients": [{"address": "carol@example.com"}]}
+`
+	messages, err := parseMailqPostfix(output)
+	require.NoErrorf(t, err, "parsed")
+	assert.Equalf(t, []mailqMessage{{
+		queue:      "deferred",
+		id:         "4E2A1C3B2F",
+		sender:     "alice@example.com",
+		recipients: []string{"bob@gmail.com"},
+		arrival:    time.Unix(1700000000, 0),
+		size:       2345,
+		reason:     "connect to gmail.com: Connection timed out",
+	}, {
+		queue:      "active",
+		id:         "5F3B2D4C3A",
+		sender:     "",
+		recipients: []string{"carol@example.com"},
+		arrival:    time.Unix(1700000100, 0),
+		size:       512,
+	}}, messages, "postfix messages")
+}
+
+func TestCheckMailqParseExim(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	messages := parseMailqExim(mailqEximOutput, now)
+	assert.Equalf(t, []mailqMessage{{
+		queue:      "deferred",
+		id:         "1hBqXm-0003Yb-Vl",
+		sender:     "alice@example.com",
+		recipients: []string{"bob@Example.com", "dave@gmail.com"},
+		arrival:    now.Add(-25 * time.Minute),
+		size:       2969,
+	}, {
+		queue:      "frozen",
+		id:         "1hBqXn-0003Yc-AB",
+		sender:     "",
+		recipients: []string{"postmaster@gmail.com"},
+		arrival:    now.Add(-2 * time.Hour),
+		size:       512,
+	}, {
+		queue:      "active",
+		id:         "1hBqXo-0003Yd-CD",
+		sender:     "bob@example.com",
+		recipients: []string{"eve@example.org"},
+		arrival:    now.Add(-3 * time.Minute),
+		size:       1024,
+	}}, messages, "exim messages")
+}
+
+func TestCheckMailqParseSendmail(t *testing.T) {
+	output := `		/var/spool/mqueue (2 requests)
+-----Q-ID----- --Size-- -----Q-Time----- ------------Sender/Recipient-----------
+u5ENhEXa003517*     583 Tue Jun 14 23:43 <root@test.local>
+					 <user@gmail.com>
+u5ENh9X6003493     1024 Mon Dec 30 08:15 <root@test.local>
+                 (host map: lookup (example.com): deferred)
+					 <user@example.com>
+					 <other@example.com>
+		Total requests: 2
+`
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.Local)
+	messages := parseMailqSendmail(output, now)
+	assert.Equalf(t, []mailqMessage{{
+		queue:      "active",
+		id:         "u5ENhEXa003517",
+		sender:     "root@test.local",
+		recipients: []string{"user@gmail.com"},
+		arrival:    time.Date(2024, 6, 14, 23, 43, 0, 0, time.Local),
+		size:       583,
+	}, {
+		queue:      "deferred",
+		id:         "u5ENh9X6003493",
+		sender:     "root@test.local",
+		recipients: []string{"user@example.com", "other@example.com"},
+		arrival:    time.Date(2024, 12, 30, 8, 15, 0, 0, time.Local),
+		size:       1024,
+		reason:     "host map: lookup (example.com): deferred",
+	}}, messages, "sendmail messages")
+}
+
+func TestCheckMailqParseOpenSMTPD(t *testing.T) {
+	output := `a1b2c3d4e5f60718|inet4|mta|auth|alice@example.com|bob@gmail.com|bob@gmail.com|1700000000|1700345600|1700000600|3|pending|900|421 Temporary failure
+a1b2c3d4f0f0f0f0|inet4|mta|auth|alice@example.com|carol@gmail.com|carol@gmail.com|1700000000|1700345600|1700000600|3|pending|900|421 Temporary failure
+b1b2c3d4e5f60718|local|mda|hold|root@host|dave@host|dave@host|1700000100|1700345700|0|0|offline||
+`
+	messages := parseMailqOpenSMTPD(output)
+	assert.Equalf(t, []mailqMessage{{
+		queue:      "deferred",
+		id:         "a1b2c3d4",
+		sender:     "alice@example.com",
+		recipients: []string{"bob@gmail.com", "carol@gmail.com"},
+		arrival:    time.Unix(1700000000, 0),
+		reason:     "421 Temporary failure",
+	}, {
+		queue:      "hold",
+		id:         "b1b2c3d4",
+		sender:     "root@host",
+		recipients: []string{"dave@host"},
+		arrival:    time.Unix(1700000100, 0),
+	}}, messages, "opensmtpd messages")
+}
@@ -2,6 +2,7 @@ package snclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"runtime"
@@ -20,18 +21,24 @@ type CheckPing struct {
 	packets  int64
 	ipv4     bool
 	ipv6     bool
+	mode     string
 }
 
 func NewCheckPing() CheckHandler {
 	return &CheckPing{
 		packets: 5,
+		mode:    "auto",
 	}
 }
 
 func (l *CheckPing) Build() *CheckData {
 	return &CheckData{
-		name:         "check_ping",
-		description:  "Checks the icmp ping connection.",
+		name: "check_ping",
+		description: `Checks the icmp ping connection.
+
+Uses native icmp sockets and falls back to the system ping command if icmp sockets are not permitted.
+On Linux unprivileged icmp sockets require the group of the agent to be in net.ipv4.ping_group_range,
+otherwise raw sockets are used which require root or the CAP_NET_RAW capability.`,
 		implemented:  ALL,
 		hasInventory: NoInventory,
 		result: &CheckResult{
@@ -41,7 +48,8 @@ func (l *CheckPing) Build() *CheckData {
 			"host":    {value: &l.hostname, description: "host name or ip address to ping"},
 			"packets": {value: &l.packets, description: "number of ICMP ECHO packets to send (default: 5)"},
 			"-4":      {value: &l.ipv4, description: "Force using IPv4."},
-			"-6":      {value: &l.ipv4, description: "Force using IPv6."},
+			"-6":      {value: &l.ipv6, description: "Force using IPv6."},
+			"mode":    {value: &l.mode, description: "ping implementation: auto, native or command (default: auto)"},
 		},
 		defaultFilter:   "none",
 		defaultWarning:  "rta > 1000 || pl > 30",
@@ -56,6 +64,9 @@ func (l *CheckPing) Build() *CheckData {
 			{name: "sent", description: "number of packets sent."},
 			{name: "received", description: "number of packets received."},
 			{name: "rta", description: "average round trip time in milliseconds."},
+			{name: "min", description: "minimum round trip time in milliseconds."},
+			{name: "max", description: "maximum round trip time in milliseconds."},
+			{name: "jitter", description: "average difference between consecutive round trip times in milliseconds (native mode only)."},
 			{name: "pl", description: "packet loss in percent.", unit: UPercent},
 		},
 		exampleDefault: `
@@ -85,11 +96,19 @@ func (l *CheckPing) Check(ctx context.Context, snc *Agent, check *CheckData, _ [
 }
 
 func (l *CheckPing) addSources(ctx context.Context, check *CheckData) (err error) {
-	switch runtime.GOOS {
-	case "linux", "darwin", "freebsd":
-		err = l.addPingLinux(ctx, check)
-	case "windows":
-		err = l.addPingWindows(ctx, check)
+	switch l.mode {
+	case "native":
+		err = l.addPingNative(ctx, check)
+	case "command":
+		err = l.addPingCommand(ctx, check)
+	case "auto":
+		err = l.addPingNative(ctx, check)
+		if errors.Is(err, errPingNativeUnavailable) {
+			log.Debugf("ping: %s, falling back to ping command", err.Error())
+			err = l.addPingCommand(ctx, check)
+		}
+	default:
+		return fmt.Errorf("unknown mode %s, must be one of: auto, native or command", l.mode)
 	}
 	if err != nil {
 		log.Debugf("failed: ping: %s", err.Error())
@@ -100,6 +119,16 @@ func (l *CheckPing) addSources(ctx context.Context, check *CheckData) (err error
 	return nil
 }
 
+// run system ping command
+func (l *CheckPing) addPingCommand(ctx context.Context, check *CheckData) error {
+	switch runtime.GOOS {
+	case "windows":
+		return l.addPingWindows(ctx, check)
+	default:
+		return l.addPingLinux(ctx, check)
+	}
+}
+
 // run linux ping command
 func (l *CheckPing) addPingLinux(ctx context.Context, check *CheckData) error {
 	cmd := fmt.Sprintf("ping -c %d '%s'", l.packets, l.hostname)
@@ -197,8 +226,10 @@ func (l *CheckPing) parsePingRTA(entry map[string]string, output string) {
 	// rtt min/avg/max/mdev = 0.019/0.019/0.021/0.000 ms
 	reRTA := regexp.MustCompile(`rtt min/avg/max/mdev = ([\d.]+)/([\d.]+)/([\d.]+)/([\d.]+) ms`)
 	rtaList := reRTA.FindStringSubmatch(output)
-	if len(rtaList) >= 3 {
+	if len(rtaList) >= 4 {
+		entry["min"] = rtaList[1]
 		entry["rta"] = rtaList[2]
+		entry["max"] = rtaList[3]
 
 		return
 	}
@@ -207,8 +238,10 @@ func (l *CheckPing) parsePingRTA(entry map[string]string, output string) {
 	// round-trip min/avg/max = 0.066/0.103/0.145 ms
 	reRTA = regexp.MustCompile(`round-trip min/avg/max = ([\d.]+)/([\d.]+)/([\d.]+) ms`)
 	rtaList = reRTA.FindStringSubmatch(output)
-	if len(rtaList) >= 3 {
+	if len(rtaList) >= 4 {
+		entry["min"] = rtaList[1]
 		entry["rta"] = rtaList[2]
+		entry["max"] = rtaList[3]
 
 		return
 	}
@@ -217,8 +250,10 @@ func (l *CheckPing) parsePingRTA(entry map[string]string, output string) {
 	// round-trip min/avg/max/stddev = 0.040/0.066/0.095/0.021 ms
 	reRTA = regexp.MustCompile(`round-trip min/avg/max/stddev = ([\d.]+)/([\d.]+)/([\d.]+)/([\d.]+) ms`)
 	rtaList = reRTA.FindStringSubmatch(output)
-	if len(rtaList) >= 3 {
+	if len(rtaList) >= 4 {
+		entry["min"] = rtaList[1]
 		entry["rta"] = rtaList[2]
+		entry["max"] = rtaList[3]
 
 		return
 	}
@@ -228,7 +263,9 @@ func (l *CheckPing) parsePingRTA(entry map[string]string, output string) {
 	// Minimum = 5ms, Maximum = 11ms, Average = 7ms
 	reRTA = regexp.MustCompile(` = ([\d.]+)ms,.*? = ([\d.]+)ms,.*? = ([\d.]+)ms`)
 	rtaList = reRTA.FindStringSubmatch(output)
-	if len(rtaList) >= 3 {
+	if len(rtaList) >= 4 {
+		entry["min"] = rtaList[1]
+		entry["max"] = rtaList[2]
 		entry["rta"] = rtaList[3]
 
 		return
@@ -304,6 +341,9 @@ func (l *CheckPing) defaultEntry() map[string]string {
 		"sent":      "",
 		"received":  "",
 		"rta":       "",
+		"min":       "",
+		"max":       "",
+		"jitter":    "",
 		"pl":        "",
 	}
 }
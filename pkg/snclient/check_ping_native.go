@@ -0,0 +1,319 @@
+package snclient
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// interval between two echo requests
+	pingNativeInterval = 1 * time.Second
+
+	// time to wait for outstanding replies after the last echo request
+	pingNativeReplyTimeout = 2 * time.Second
+
+	// size of the echo request payload, same as the default of the ping command
+	pingNativePayloadSize = 56
+
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+var errPingNativeUnavailable = errors.New("native icmp not available")
+
+// pingNativeConn is an icmp socket used to send echo requests.
+type pingNativeConn struct {
+	conn  *icmp.PacketConn
+	proto int
+	udp   bool // unprivileged datagram socket
+}
+
+// pingNativeStats contains the result of a native ping run.
+type pingNativeStats struct {
+	sent     int64
+	received int64
+	ttl      int
+	rtts     []float64 // round trip times in milliseconds
+}
+
+// addPingNative sends icmp echo requests without using the system ping command.
+// Returns errPingNativeUnavailable if no icmp socket could be opened.
+func (l *CheckPing) addPingNative(ctx context.Context, check *CheckData) error {
+	addr, err := l.resolvePingAddress(ctx)
+	if err != nil {
+		log.Debugf("ping: resolving %s failed: %s", l.hostname, err.Error())
+		entry := l.defaultEntry()
+		entry["_error"] = "failed to resolve hostname"
+		entry["pl"] = "100"
+		check.listData = append(check.listData, entry)
+
+		return nil
+	}
+
+	pconn, err := listenPingNative(addr.To4() == nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errPingNativeUnavailable, err.Error())
+	}
+	defer pconn.conn.Close()
+
+	deadline := time.Now().Add(time.Duration((check.timeout - 1) * float64(time.Second)))
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	stats, err := pconn.run(ctx, addr, l.packets, deadline)
+	if err != nil {
+		return fmt.Errorf("ping failed: %s", err.Error())
+	}
+
+	entry := l.defaultEntry()
+	stats.fillEntry(entry)
+	check.listData = append(check.listData, entry)
+	l.addMetrics(check, entry)
+
+	return nil
+}
+
+// resolvePingAddress returns the first ip address of the host respecting the -4/-6 flags.
+func (l *CheckPing) resolvePingAddress(ctx context.Context) (net.IP, error) {
+	network := "ip"
+	switch {
+	case l.ipv4:
+		network = "ip4"
+	case l.ipv6:
+		network = "ip6"
+	}
+
+	addrs, err := net.DefaultResolver.LookupIP(ctx, network, l.hostname)
+	if err != nil {
+		return nil, fmt.Errorf("lookup %s: %s", l.hostname, err.Error())
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("lookup %s: no address found", l.hostname)
+	}
+
+	return addrs[0], nil
+}
+
+// listenPingNative opens an unprivileged icmp datagram socket and falls back to a raw socket.
+func listenPingNative(useIPv6 bool) (*pingNativeConn, error) {
+	type socketType struct {
+		network string
+		address string
+		udp     bool
+	}
+	sockets := []socketType{
+		{"udp4", "0.0.0.0", true},
+		{"ip4:icmp", "0.0.0.0", false},
+	}
+	proto := protocolICMP
+	if useIPv6 {
+		sockets = []socketType{
+			{"udp6", "::", true},
+			{"ip6:ipv6-icmp", "::", false},
+		}
+		proto = protocolICMPv6
+	}
+
+	// datagram sockets are not supported on windows
+	if runtime.GOOS == "windows" {
+		sockets = sockets[1:]
+	}
+
+	var lastErr error
+	for _, socket := range sockets {
+		conn, err := icmp.ListenPacket(socket.network, socket.address)
+		if err != nil {
+			log.Debugf("ping: cannot open %s icmp socket: %s", socket.network, err.Error())
+			lastErr = err
+
+			continue
+		}
+
+		pconn := &pingNativeConn{conn: conn, proto: proto, udp: socket.udp}
+		pconn.enableTTL()
+
+		return pconn, nil
+	}
+
+	return nil, lastErr
+}
+
+// enableTTL requests the ttl / hop limit of received packets, not all platforms support this.
+func (p *pingNativeConn) enableTTL() {
+	var err error
+	if p.proto == protocolICMPv6 {
+		err = p.conn.IPv6PacketConn().SetControlMessage(ipv6.FlagHopLimit, true)
+	} else {
+		err = p.conn.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true)
+	}
+	if err != nil {
+		log.Tracef("ping: cannot enable ttl control messages: %s", err.Error())
+	}
+}
+
+// run sends the echo requests and collects the replies.
+func (p *pingNativeConn) run(ctx context.Context, addr net.IP, packets int64, deadline time.Time) (*pingNativeStats, error) {
+	var dst net.Addr = &net.IPAddr{IP: addr}
+	if p.udp {
+		dst = &net.UDPAddr{IP: addr}
+	}
+
+	// random payload to identify our replies, the echo id is replaced by the kernel for datagram sockets
+	payload := make([]byte, pingNativePayloadSize)
+	if _, err := rand.Read(payload); err != nil {
+		return nil, fmt.Errorf("random payload: %s", err.Error())
+	}
+
+	var msgType icmp.Type = ipv4.ICMPTypeEcho
+	if p.proto == protocolICMPv6 {
+		msgType = ipv6.ICMPTypeEchoRequest
+	}
+
+	stats := &pingNativeStats{}
+	sendTimes := map[int]time.Time{}
+	for seq := range int(packets) {
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			break
+		}
+
+		msg := icmp.Message{
+			Type: msgType,
+			Body: &icmp.Echo{
+				ID:   os.Getpid() & 0xffff,
+				Seq:  seq,
+				Data: payload,
+			},
+		}
+		data, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, fmt.Errorf("icmp marshal: %s", err.Error())
+		}
+
+		sendTimes[seq] = time.Now()
+		if _, err := p.conn.WriteTo(data, dst); err != nil {
+			log.Debugf("ping: sending echo request to %s failed: %s", addr.String(), err.Error())
+		}
+		stats.sent++
+
+		waitUntil := time.Now().Add(pingNativeInterval)
+		if int64(seq) == packets-1 {
+			waitUntil = time.Now().Add(pingNativeReplyTimeout)
+		}
+		if waitUntil.After(deadline) {
+			waitUntil = deadline
+		}
+
+		p.readReplies(ctx, stats, sendTimes, payload, waitUntil, int64(seq) == packets-1)
+	}
+
+	return stats, nil
+}
+
+// readReplies reads echo replies until waitUntil. If last is set, it returns as soon as all replies have been received.
+func (p *pingNativeConn) readReplies(ctx context.Context, stats *pingNativeStats, sendTimes map[int]time.Time, payload []byte, waitUntil time.Time, last bool) {
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		if last && stats.received == stats.sent {
+			return
+		}
+		if err := p.conn.SetReadDeadline(waitUntil); err != nil {
+			log.Debugf("ping: set read deadline: %s", err.Error())
+
+			return
+		}
+
+		size, ttl, err := p.readFrom(buf)
+		if err != nil {
+			// deadline reached
+			return
+		}
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(p.proto, buf[:size])
+		if err != nil {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || (msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply) {
+			continue
+		}
+		sendTime, ok := sendTimes[echo.Seq]
+		if !ok || !slices.Equal(echo.Data, payload) {
+			continue
+		}
+
+		// ignore duplicates
+		delete(sendTimes, echo.Seq)
+		stats.received++
+		stats.rtts = append(stats.rtts, float64(received.Sub(sendTime).Microseconds())/1000)
+		if ttl > 0 {
+			stats.ttl = ttl
+		}
+	}
+}
+
+// readFrom reads the next packet and returns the ttl if available.
+func (p *pingNativeConn) readFrom(buf []byte) (size, ttl int, err error) {
+	if p.proto == protocolICMPv6 {
+		size, cm, _, err := p.conn.IPv6PacketConn().ReadFrom(buf)
+		if cm != nil {
+			ttl = cm.HopLimit
+		}
+
+		return size, ttl, err //nolint:wrapcheck // caller only checks for errors
+	}
+
+	size, cm, _, err := p.conn.IPv4PacketConn().ReadFrom(buf)
+	if cm != nil {
+		ttl = cm.TTL
+	}
+
+	return size, ttl, err //nolint:wrapcheck // caller only checks for errors
+}
+
+// fillEntry sets the check attributes from the ping statistics.
+func (s *pingNativeStats) fillEntry(entry map[string]string) {
+	entry["sent"] = fmt.Sprintf("%d", s.sent)
+	entry["received"] = fmt.Sprintf("%d", s.received)
+	entry["pl"] = "100"
+	if s.sent > 0 {
+		entry["pl"] = fmt.Sprintf("%d", 100*(s.sent-s.received)/s.sent)
+	}
+	if s.ttl > 0 {
+		entry["ttl"] = fmt.Sprintf("%d", s.ttl)
+	}
+
+	if len(s.rtts) == 0 {
+		return
+	}
+
+	sum := 0.0
+	jitter := 0.0
+	for i, rtt := range s.rtts {
+		sum += rtt
+		if i > 0 {
+			jitter += math.Abs(rtt - s.rtts[i-1])
+		}
+	}
+	if len(s.rtts) > 1 {
+		jitter /= float64(len(s.rtts) - 1)
+	}
+
+	entry["rta"] = fmt.Sprintf("%.3f", sum/float64(len(s.rtts)))
+	entry["min"] = fmt.Sprintf("%.3f", slices.Min(s.rtts))
+	entry["max"] = fmt.Sprintf("%.3f", slices.Max(s.rtts))
+	entry["jitter"] = fmt.Sprintf("%.3f", jitter)
+}
@@ -35,6 +35,9 @@ func TestPingParserLinuxOK(t *testing.T) {
 		"sent":      "2",
 		"received":  "2",
 		"rta":       "0.376",
+		"min":       "0.359",
+		"max":       "0.393",
+		"jitter":    "",
 		"pl":        "0",
 		"ttl":       "64",
 	}
@@ -59,6 +62,9 @@ func TestPingParserLinuxBad(t *testing.T) {
 		"sent":      "2",
 		"received":  "0",
 		"rta":       "",
+		"min":       "",
+		"max":       "",
+		"jitter":    "",
 		"pl":        "100",
 		"ttl":       "",
 	}
@@ -83,6 +89,9 @@ func TestPingParserLinuxAlpineOK(t *testing.T) {
 		"sent":      "2",
 		"received":  "2",
 		"rta":       "0.083",
+		"min":       "0.067",
+		"max":       "0.100",
+		"jitter":    "",
 		"pl":        "0",
 		"ttl":       "64",
 	}
@@ -107,6 +116,9 @@ func TestPingParserLinuxAlpineBad(t *testing.T) {
 		"sent":      "2",
 		"received":  "0",
 		"rta":       "",
+		"min":       "",
+		"max":       "",
+		"jitter":    "",
 		"pl":        "100",
 		"ttl":       "",
 	}
@@ -129,6 +141,9 @@ func TestPingParserLinuxAlpineBad2(t *testing.T) {
 		"sent":      "",
 		"received":  "",
 		"rta":       "",
+		"min":       "",
+		"max":       "",
+		"jitter":    "",
 		"pl":        "100",
 		"ttl":       "",
 	}
@@ -148,6 +163,9 @@ func TestPingParserWindowsOK(t *testing.T) {
 		"sent":      "3",
 		"received":  "3",
 		"rta":       "7",
+		"min":       "5",
+		"max":       "11",
+		"jitter":    "",
 		"pl":        "0",
 		"ttl":       "127",
 	}
@@ -175,6 +193,9 @@ func TestPingParserWindowsBad(t *testing.T) {
 		"sent":      "4",
 		"received":  "4",
 		"rta":       "",
+		"min":       "",
+		"max":       "",
+		"jitter":    "",
 		"pl":        "100",
 		"ttl":       "",
 	}
@@ -201,6 +222,9 @@ func TestPingParserWindowsBad2(t *testing.T) {
 		"sent":      "3",
 		"received":  "0",
 		"rta":       "",
+		"min":       "",
+		"max":       "",
+		"jitter":    "",
 		"pl":        "100",
 		"ttl":       "",
 	}
@@ -226,6 +250,9 @@ func TestPingParserWindowsOKDE(t *testing.T) {
 		"sent":      "5",
 		"received":  "5",
 		"rta":       "6",
+		"min":       "3",
+		"max":       "9",
+		"jitter":    "",
 		"pl":        "0",
 		"ttl":       "",
 	}
@@ -256,6 +283,9 @@ func TestPingParserOSXOK(t *testing.T) {
 		"sent":      "5",
 		"received":  "5",
 		"rta":       "0.066",
+		"min":       "0.040",
+		"max":       "0.095",
+		"jitter":    "",
 		"pl":        "0.0",
 		"ttl":       "64",
 	}
@@ -284,6 +314,9 @@ func TestPingParserOSXBad(t *testing.T) {
 		"sent":      "5",
 		"received":  "0",
 		"rta":       "",
+		"min":       "",
+		"max":       "",
+		"jitter":    "",
 		"pl":        "100.0",
 		"ttl":       "",
 	}
@@ -303,3 +336,22 @@ Request timeout for icmp_seq 3
 	delete(entry, "_error")
 	assert.Equalf(t, exp, entry, "parsed ping ok output")
 }
+
+func TestPingNativeStats(t *testing.T) {
+	exp := map[string]string{
+		"host_name": "",
+		"sent":      "4",
+		"received":  "3",
+		"rta":       "2.000",
+		"min":       "1.000",
+		"max":       "3.000",
+		"jitter":    "1.500",
+		"pl":        "25",
+		"ttl":       "64",
+	}
+	stats := &pingNativeStats{sent: 4, received: 3, ttl: 64, rtts: []float64{1, 3, 2}}
+	chk := &CheckPing{}
+	entry := chk.defaultEntry()
+	stats.fillEntry(entry)
+	assert.Equalf(t, exp, entry, "native ping stats")
+}
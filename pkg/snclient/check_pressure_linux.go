@@ -0,0 +1,244 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/convert"
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+func init() {
+	AvailableChecks["check_pressure"] = CheckEntry{"check_pressure", NewCheckPressure}
+}
+
+// cgroupCounterExpire sets the time after which counters of cgroups which are no longer checked are removed.
+const cgroupCounterExpire = time.Hour
+
+// cgroupRootPath contains the cgroup v2 hierarchy, the cgroup argument must point below this folder.
+var cgroupRootPath = "/sys/fs/cgroup"
+
+type CheckPressure struct {
+	snc     *Agent
+	types   []string
+	cgroup  string
+	timeStr string
+}
+
+func NewCheckPressure() CheckHandler {
+	return &CheckPressure{
+		timeStr: "1m",
+	}
+}
+
+func (l *CheckPressure) Build() *CheckData {
+	return &CheckData{
+		name: "check_pressure",
+		description: `Checks the linux pressure stall information (PSI) of cpu, memory and io.
+
+The some values contain the percentage of time at least one task was stalled, full values contain
+the percentage of time all non-idle tasks were stalled at the same time.
+
+System wide pressure is read from /proc/pressure and requires a kernel with CONFIG_PSI enabled.
+Pressure of a cgroup (v2 only) is read from the *.pressure files of the given cgroup path.`,
+		implemented:  Linux,
+		hasInventory: ListInventory,
+		result: &CheckResult{
+			State: CheckExitOK,
+		},
+		args: map[string]CheckArgument{
+			"type":   {value: &l.types, description: "Select resource to check, can be: cpu, memory or io. Default is all"},
+			"cgroup": {value: &l.cgroup, description: "Check pressure of this cgroup instead of the whole system, path relative to /sys/fs/cgroup or absolute path below it"},
+			"time":   {value: &l.timeStr, description: "Time frame used to calculate the total stall time rate, cgroups use the time since the last check instead. Default: 1m"},
+		},
+		defaultWarning:  "some_avg60 > 20 || full_avg60 > 10",
+		defaultCritical: "some_avg60 > 40 || full_avg60 > 20",
+		okSyntax:        "%(status) - %(list)",
+		detailSyntax:    "%(name) some %(some_avg60)% full %(full_avg60)%",
+		topSyntax:       "%(status) - %(list)",
+		emptySyntax:     "%(status) - No pressure information found",
+		emptyState:      CheckExitUnknown,
+		attributes: []CheckAttribute{
+			{name: "name", description: "Name of the resource: cpu, memory or io"},
+			{name: "cgroup", description: "Path of the cgroup, empty for system wide pressure"},
+			{name: "some_avg10", description: "Percentage of time some tasks were stalled (10 second average)", unit: UPercent},
+			{name: "some_avg60", description: "Percentage of time some tasks were stalled (60 second average)", unit: UPercent},
+			{name: "some_avg300", description: "Percentage of time some tasks were stalled (300 second average)", unit: UPercent},
+			{name: "some_total", description: "Percentage of time some tasks were stalled within the time frame", unit: UPercent},
+			{name: "full_avg10", description: "Percentage of time all tasks were stalled (10 second average)", unit: UPercent},
+			{name: "full_avg60", description: "Percentage of time all tasks were stalled (60 second average)", unit: UPercent},
+			{name: "full_avg300", description: "Percentage of time all tasks were stalled (300 second average)", unit: UPercent},
+			{name: "full_total", description: "Percentage of time all tasks were stalled within the time frame", unit: UPercent},
+		},
+		exampleDefault: `
+    check_pressure
+    OK - cpu some 3.26% full 0.00%, memory some 0.05% full 0.01%, io some 0.00% full 0.00% |'cpu some_avg10'=0.82%;;;0;100 ...
+
+Check memory pressure of a container:
+
+    check_pressure cgroup=system.slice/docker-4f2d...scope type=memory warn='full_avg10 > 5' crit='full_avg10 > 10'
+    OK - memory some 1.20% full 0.40% |...
+	`,
+		exampleArgs: `'warn=some_avg60 > 20' 'crit=some_avg60 > 40'`,
+	}
+}
+
+func (l *CheckPressure) Check(_ context.Context, snc *Agent, check *CheckData, _ []Argument) (*CheckResult, error) {
+	l.snc = snc
+
+	dur, err := utils.ExpandDuration(l.timeStr)
+	if err != nil {
+		return nil, fmt.Errorf("time: %s", err.Error())
+	}
+	if dur <= 0 {
+		return nil, fmt.Errorf("time must be a positive duration")
+	}
+	lookback := time.Duration(dur * float64(time.Second))
+
+	folder := "/proc/pressure"
+	if l.cgroup != "" {
+		folder, err = cgroupFolder(l.cgroup)
+		if err != nil {
+			return nil, err
+		}
+		if err := utils.IsFolder(folder); err != nil {
+			return nil, fmt.Errorf("cgroup %s not found: %s", l.cgroup, err.Error())
+		}
+		l.deleteStaleCounters()
+	}
+
+	for _, res := range linuxPressureResources {
+		if len(l.types) > 0 && !slices.Contains(l.types, res) {
+			continue
+		}
+
+		file := filepath.Join(folder, res)
+		if l.cgroup != "" {
+			file = filepath.Join(folder, res+".pressure")
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("pressure stall information not available: %s", err.Error())
+		}
+
+		entry := l.buildEntry(res, parseLinuxPressure(string(content)), folder, lookback)
+		if !check.MatchMapCondition(check.filter, entry, true) {
+			continue
+		}
+
+		check.listData = append(check.listData, entry)
+		l.addMetrics(check, entry)
+	}
+
+	return check.Finalize()
+}
+
+func (l *CheckPressure) buildEntry(res string, lines map[string]map[string]float64, folder string, lookback time.Duration) map[string]string {
+	entry := map[string]string{
+		"name":   res,
+		"cgroup": l.cgroup,
+	}
+	for _, line := range []string{"some", "full"} {
+		// system wide full cpu pressure is not reported by older kernels, missing values default to 0
+		values := lines[line]
+		for _, avg := range []string{"avg10", "avg60", "avg300"} {
+			entry[line+"_"+avg] = fmt.Sprintf("%.2f", values[avg])
+		}
+
+		// total stall time is in microseconds and cannot exceed the wall clock time
+		rate := l.getTotalRate(folder, res+"_"+line, values["total"], lookback)
+		entry[line+"_total"] = fmt.Sprintf("%.2f", math.Min(rate/1e4, 100))
+	}
+
+	return entry
+}
+
+// getTotalRate returns the increase of the total stall time per second.
+func (l *CheckPressure) getTotalRate(folder, key string, total float64, lookback time.Duration) float64 {
+	var rate float64
+	if l.cgroup == "" {
+		// system wide values are collected by the CheckSystem task
+		rate, _ = l.snc.Counter.GetRate("pressure", key, lookback)
+	} else {
+		// cgroups are not collected in the background, use the value from the last check run
+		key = folder + ":" + key
+		counter := l.snc.Counter.Get("pressure", key)
+		if counter == nil {
+			l.snc.counterCreate("pressure", key, time.Second, time.Second)
+			counter = l.snc.Counter.Get("pressure", key)
+		}
+		if last := counter.GetLast(); last != nil {
+			elapsed := time.Since(time.UnixMilli(last.UnixMilli)).Seconds()
+			if elapsed > 0 {
+				rate = (total - last.Float64()) / elapsed
+			}
+		}
+		counter.Set(total)
+	}
+
+	if rate < 0 {
+		return 0
+	}
+
+	return rate
+}
+
+// cgroupFolder returns the folder of given cgroup which must be below the cgroup root folder.
+func cgroupFolder(cgroup string) (string, error) {
+	folder := cgroup
+	if !filepath.IsAbs(folder) {
+		folder = filepath.Join(cgroupRootPath, folder)
+	}
+	folder = filepath.Clean(folder)
+
+	rel, err := filepath.Rel(cgroupRootPath, folder)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("cgroup %s is not below %s", cgroup, cgroupRootPath)
+	}
+
+	return folder, nil
+}
+
+// deleteStaleCounters removes the counters of cgroups which have not been checked recently.
+func (l *CheckPressure) deleteStaleCounters() {
+	for _, key := range l.snc.Counter.Keys("pressure") {
+		// system wide counters do not contain the cgroup folder
+		if !strings.Contains(key, ":") {
+			continue
+		}
+		counter := l.snc.Counter.Get("pressure", key)
+		if counter == nil {
+			continue
+		}
+		last := counter.GetLast()
+		if last == nil || time.Since(time.UnixMilli(last.UnixMilli)) > cgroupCounterExpire {
+			log.Tracef("removing stale pressure counter %s", key)
+			l.snc.Counter.Delete("pressure", key)
+		}
+	}
+}
+
+func (l *CheckPressure) addMetrics(check *CheckData, entry map[string]string) {
+	for _, line := range []string{"some", "full"} {
+		for _, attr := range []string{"avg10", "avg60", "avg300", "total"} {
+			name := line + "_" + attr
+			check.result.Metrics = append(check.result.Metrics, &CheckMetric{
+				ThresholdName: name,
+				Name:          entry["name"] + " " + name,
+				Value:         convert.Float64(entry[name]),
+				Unit:          "%",
+				Warning:       check.warnThreshold,
+				Critical:      check.critThreshold,
+				Min:           &Zero,
+				Max:           &Hundred,
+			})
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package snclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPressure(t *testing.T) {
+	snc := StartTestAgent(t, "")
+
+	if utils.IsFolder("/proc/pressure") == nil {
+		res := snc.RunCheck("check_pressure", []string{"warn=none", "crit=none"})
+		assert.Equalf(t, CheckExitOK, res.State, "state ok")
+		assert.Regexpf(t, `^OK - cpu some [\d.]+% full [\d.]+%, memory some`, string(res.BuildPluginOutput()), "output matches")
+	}
+
+	root := cgroupRootPath
+	cgroupRootPath = t.TempDir()
+	defer func() { cgroupRootPath = root }()
+
+	cgroup := filepath.Join(cgroupRootPath, "system.slice")
+	require.NoErrorf(t, os.Mkdir(cgroup, 0o700), "cgroup created")
+	writePressure := func(memory string) {
+		require.NoErrorf(t, os.WriteFile(filepath.Join(cgroup, "cpu.pressure"), []byte("some avg10=1.50 avg60=2.50 avg300=3.50 total=1000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"), 0o600), "cpu written")
+		require.NoErrorf(t, os.WriteFile(filepath.Join(cgroup, "memory.pressure"), []byte(memory), 0o600), "memory written")
+		require.NoErrorf(t, os.WriteFile(filepath.Join(cgroup, "io.pressure"), []byte("some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"), 0o600), "io written")
+	}
+	writePressure("some avg10=45.00 avg60=30.00 avg300=10.00 total=5000000\nfull avg10=12.00 avg60=8.00 avg300=2.00 total=1000000\n")
+
+	res := snc.RunCheck("check_pressure", []string{"cgroup=" + cgroup})
+	assert.Equalf(t, CheckExitWarning, res.State, "state warning")
+	assert.Equalf(t, "WARNING - cpu some 2.50% full 0.00%, memory some 30.00% full 8.00%, io some 0.00% full 0.00%", res.Output, "output matches")
+
+	res = snc.RunCheck("check_pressure", []string{"cgroup=" + cgroup, "type=memory", "crit=full_avg10 > 10"})
+	assert.Equalf(t, CheckExitCritical, res.State, "state critical")
+	assert.Containsf(t, string(res.BuildPluginOutput()), "'memory full_avg10'=12%;;10;0;100", "metric matches")
+
+	// total stall time increased by 200 seconds since the last check
+	writePressure("some avg10=45.00 avg60=30.00 avg300=10.00 total=205000000\nfull avg10=12.00 avg60=8.00 avg300=2.00 total=1000000\n")
+	res = snc.RunCheck("check_pressure", []string{"cgroup=" + cgroup, "type=memory", "warn=some_total > 50", "crit=none", "detail-syntax=${some_total} ${full_total}"})
+	assert.Equalf(t, CheckExitWarning, res.State, "stall time rate")
+	assert.Regexpf(t, `^WARNING - 100.00 0.00$`, res.Output, "rate is capped by elapsed time")
+
+	res = snc.RunCheck("check_pressure", []string{"cgroup=system.slice", "type=cpu", "detail-syntax=${name} ${some_avg60}"})
+	assert.Equalf(t, "OK - cpu 2.50", res.Output, "relative cgroup path")
+
+	res = snc.RunCheck("check_pressure", []string{"cgroup=" + filepath.Join(cgroup, "missing")})
+	assert.Equalf(t, CheckExitUnknown, res.State, "missing cgroup")
+
+	for _, path := range []string{"../", "system.slice/../../etc", t.TempDir(), "/proc/pressure"} {
+		res = snc.RunCheck("check_pressure", []string{"cgroup=" + path})
+		assert.Equalf(t, CheckExitUnknown, res.State, "cgroup outside of root: %s", path)
+		assert.Containsf(t, res.Output, "is not below", "cgroup outside of root: %s", path)
+	}
+
+	res = snc.RunCheck("check_pressure", []string{"time=0"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "invalid time")
+
+	// counters of cgroups which are not checked anymore are removed
+	snc.Counter.Create("pressure", "/sys/fs/cgroup/removed:memory_some", time.Second, time.Second)
+	snc.RunCheck("check_pressure", []string{"cgroup=system.slice"})
+	assert.Nilf(t, snc.Counter.Get("pressure", "/sys/fs/cgroup/removed:memory_some"), "stale counter removed")
+	assert.NotNilf(t, snc.Counter.Get("pressure", cgroup+":memory_some"), "counter in use kept")
+
+	StopTestAgent(t, snc)
+}
+
+func TestCheckPressureParse(t *testing.T) {
+	lines := parseLinuxPressure("some avg10=0.82 avg60=3.26 avg300=2.82 total=334112033\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n")
+	assert.Equalf(t, map[string]map[string]float64{
+		"some": {"avg10": 0.82, "avg60": 3.26, "avg300": 2.82, "total": 334112033},
+		"full": {"avg10": 0, "avg60": 0, "avg300": 0, "total": 0},
+	}, lines, "parsed pressure")
+}
@@ -0,0 +1,343 @@
+package snclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/nrpe"
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+// DefaultRemoteConfig contains the defaults for remote targets
+var DefaultRemoteConfig = ConfigData{
+	"protocol": "http",
+	"use ssl":  "true",
+}
+
+func init() {
+	AvailableChecks["check_remote"] = CheckEntry{"check_remote", NewCheckRemote}
+}
+
+type CheckRemote struct {
+	snc      *Agent
+	target   string
+	host     string
+	port     string
+	protocol string
+	command  string
+	args     []string
+	password string
+	insecure string
+	useSSL   string
+}
+
+func NewCheckRemote() CheckHandler {
+	return &CheckRemote{}
+}
+
+func (l *CheckRemote) Build() *CheckData {
+	return &CheckData{
+		name: "check_remote",
+		description: `Forwards a query to another agent by the REST API or NRPE and returns its result unchanged.
+
+Connection and http client settings (ex.: client certificates, insecure, tls min version) are
+taken from the /settings/remote section or from /settings/remote/targets/<target> if target is set.
+Arguments override the config settings. Host, port, ssl and insecure cannot be changed for targets
+and configured credentials are never sent to hosts given by argument.`,
+		implemented: ALL,
+		args: map[string]CheckArgument{
+			"target":   {value: &l.target, description: "Use settings from config section /settings/remote/targets/<target>"},
+			"host":     {value: &l.host, description: "Hostname or address of the remote agent, can be a full url for http"},
+			"port":     {value: &l.port, description: "Port of the remote agent (default: 8443 for http and 5666 for nrpe)"},
+			"protocol": {value: &l.protocol, description: "Protocol used to query the remote agent, can be http or nrpe (default: http)"},
+			"command":  {value: &l.command, description: "Command to run on the remote agent"},
+			"arg":      {value: &l.args, description: "Argument passed to the remote command, can be used multiple times"},
+			"password": {value: &l.password, description: "Password for the remote REST API"},
+			"insecure": {value: &l.insecure, description: "Skip certificate verification (default: false)"},
+			"ssl":      {value: &l.useSSL, description: "Use ssl/tls to connect to the remote agent (default: true)"},
+		},
+		result: &CheckResult{
+			State: CheckExitOK,
+		},
+		exampleDefault: `
+    check_remote host=10.0.1.5 password=secret command=check_load
+    OK - total load average: 0.12, 0.18, 0.20 |...
+
+Run check_drivesize on an agent behind this one by NRPE with remote arguments:
+
+    check_remote host=10.0.1.5 protocol=nrpe command=check_drivesize arg=drive=/ "arg=warn=used_pct > 90"
+    OK - All 1 drive(s) are ok |...
+
+Use a target from the config:
+
+    check_remote target=dmz-web command=check_cpu
+    OK - CPU load is ok. |...
+	`,
+		exampleArgs: `host=10.0.1.5 command=check_load`,
+	}
+}
+
+func (l *CheckRemote) Check(ctx context.Context, snc *Agent, _ *CheckData, _ []Argument) (*CheckResult, error) {
+	enabled, _, _ := snc.config.Section("/modules").GetBool("CheckRemote")
+	if !enabled {
+		return nil, fmt.Errorf("module CheckRemote is not enabled in /modules section")
+	}
+	l.snc = snc
+
+	conf, err := l.remoteConfig()
+	if err != nil {
+		return nil, err
+	}
+	if l.command == "" {
+		return nil, fmt.Errorf("missing command argument")
+	}
+
+	host, _ := conf.GetString("host")
+	if host == "" {
+		return nil, fmt.Errorf("missing host, set host argument or target config")
+	}
+
+	httpOptions, err := snc.buildClientHTTPOptions(conf)
+	if err != nil {
+		return nil, err
+	}
+	useSSL, _, err := conf.GetBool("use ssl")
+	if err != nil {
+		return nil, fmt.Errorf("use ssl: %s", err.Error())
+	}
+
+	protocol, _ := conf.GetString("protocol")
+	switch strings.ToLower(protocol) {
+	case "http", "https", "rest":
+		return l.queryHTTP(ctx, conf, host, useSSL, httpOptions)
+	case "nrpe":
+		return l.queryNRPE(ctx, conf, host, useSSL, httpOptions)
+	default:
+		return nil, fmt.Errorf("unknown protocol: %s (supported are: http, nrpe)", protocol)
+	}
+}
+
+// remoteConfig returns the config section with all arguments applied.
+func (l *CheckRemote) remoteConfig() (*ConfigSection, error) {
+	name := "/settings/remote"
+	if l.target != "" {
+		name = "/settings/remote/targets/" + l.target
+		if !slices.Contains(l.snc.config.SectionNames(), name) {
+			return nil, fmt.Errorf("no such target: %s", l.target)
+		}
+	}
+	conf := l.snc.config.Section(name).Clone()
+
+	if l.target != "" {
+		// do not send target credentials to other hosts or over weaker connections
+		if l.host != "" || l.port != "" || l.useSSL != "" || l.insecure != "" {
+			return nil, fmt.Errorf("host, port, ssl and insecure cannot be used together with target")
+		}
+	} else if l.host != "" {
+		// configured credentials are only sent to configured hosts
+		for _, key := range []string{"user", "username", "password"} {
+			conf.Remove(key)
+		}
+	}
+
+	// set defaults unless inherited from parent sections
+	for _, defaults := range []ConfigData{DefaultRemoteConfig, DefaultHTTPClientConfig} {
+		for key, val := range defaults {
+			if _, ok := conf.GetString(key); !ok {
+				conf.Set(key, val)
+			}
+		}
+	}
+
+	overrides := map[string]string{
+		"host":     l.host,
+		"port":     l.port,
+		"protocol": l.protocol,
+		"password": l.password,
+		"insecure": l.insecure,
+		"use ssl":  l.useSSL,
+	}
+	for key, val := range overrides {
+		if val != "" {
+			conf.Set(key, val)
+		}
+	}
+
+	return conf, nil
+}
+
+// queryHTTP runs the command by the REST API
+func (l *CheckRemote) queryHTTP(ctx context.Context, conf *ConfigSection, host string, useSSL bool, httpOptions *HTTPClientOptions) (*CheckResult, error) {
+	baseURL := host
+	if !strings.Contains(host, "://") {
+		scheme := "https"
+		if !useSSL {
+			scheme = "http"
+		}
+		port, _ := conf.GetString("port")
+		if port == "" {
+			port = "8443"
+		}
+		baseURL = fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, port))
+	}
+
+	query := make([]string, 0, len(l.args))
+	for _, arg := range l.args {
+		query = append(query, url.QueryEscape(arg))
+	}
+	reqURL := fmt.Sprintf("%s/api/v1/queries/%s/commands/execute", strings.TrimSuffix(baseURL, "/"), url.PathEscape(l.command))
+	if len(query) > 0 {
+		reqURL += "?" + strings.Join(query, "&")
+	}
+
+	header := map[string]string{}
+	if httpOptions.user == "" && httpOptions.password != "" {
+		header["Password"] = httpOptions.password
+	}
+
+	resp, err := l.snc.httpDoBody(ctx, httpOptions, http.MethodGet, reqURL, header, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response failed: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote agent returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return parseRemoteResultV1(body)
+}
+
+// queryNRPE runs the command by nrpe
+func (l *CheckRemote) queryNRPE(ctx context.Context, conf *ConfigSection, host string, useSSL bool, httpOptions *HTTPClientOptions) (*CheckResult, error) {
+	port, _ := conf.GetString("port")
+	if port == "" {
+		port = nrpe.DefaultPort
+	}
+
+	client := nrpe.NewClient(net.JoinHostPort(host, port))
+	client.UseSSL = useSSL
+	client.TLSConfig = httpOptions.tlsConfig
+	client.ADH = nrpe.ADHDisabled
+	client.Timeout = time.Duration(httpOptions.reqTimeout) * time.Second
+	if deadline, ok := ctx.Deadline(); ok && (client.Timeout <= 0 || time.Until(deadline) < client.Timeout) {
+		client.Timeout = time.Until(deadline)
+	}
+
+	res, err := client.Query(ctx, l.command, l.args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CheckResult{
+		State:  int64(res.State),
+		Output: res.Output,
+	}
+	result.ParsePerformanceDataFromOutputRaw()
+
+	return result, nil
+}
+
+// parseRemoteResultV1 converts the json response from /api/v1/queries/.../commands/execute into a check result.
+func parseRemoteResultV1(data []byte) (*CheckResult, error) {
+	response := struct {
+		Result json.Number `json:"result"`
+		Lines  []struct {
+			Message string                            `json:"message"`
+			Perf    map[string]map[string]interface{} `json:"perf"`
+		} `json:"lines"`
+	}{}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse json response: %s", err.Error())
+	}
+
+	state, err := response.Result.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse result state: %s", err.Error())
+	}
+
+	result := &CheckResult{
+		State:   state,
+		Metrics: []*CheckMetric{},
+	}
+	messages := []string{}
+	for _, line := range response.Lines {
+		if line.Message != "" {
+			messages = append(messages, line.Message)
+		}
+		for _, name := range utils.SortedKeys(line.Perf) {
+			result.Metrics = append(result.Metrics, remotePerfV1ToMetric(name, line.Perf[name]))
+		}
+	}
+	result.Output = strings.Join(messages, "\n")
+
+	return result, nil
+}
+
+func remotePerfV1ToMetric(name string, perf map[string]interface{}) *CheckMetric {
+	metric := &CheckMetric{
+		Name:  name,
+		Value: remoteJSONString(perf["value"]),
+	}
+	if unit, ok := perf["unit"].(string); ok {
+		metric.Unit = unit
+	}
+	if warn, ok := perf["warning"]; ok {
+		warnStr := remoteJSONString(warn)
+		metric.WarningStr = &warnStr
+	}
+	if crit, ok := perf["critical"]; ok {
+		critStr := remoteJSONString(crit)
+		metric.CriticalStr = &critStr
+	}
+	if minV, err := strconv.ParseFloat(remoteJSONString(perf["minimum"]), 64); err == nil {
+		metric.Min = &minV
+	}
+	if maxV, err := strconv.ParseFloat(remoteJSONString(perf["maximum"]), 64); err == nil {
+		metric.Max = &maxV
+	}
+
+	// keep the perf data exactly as sent by the remote agent
+	raw := fmt.Sprintf("'%s'=%s%s;%s;%s;%s;%s",
+		name,
+		remoteJSONString(perf["value"]),
+		metric.Unit,
+		remoteJSONString(perf["warning"]),
+		remoteJSONString(perf["critical"]),
+		remoteJSONString(perf["minimum"]),
+		remoteJSONString(perf["maximum"]),
+	)
+	metric.Raw = strings.TrimRight(raw, ";")
+
+	return metric
+}
+
+// remoteJSONString returns json values as string without changing number formats
+func remoteJSONString(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
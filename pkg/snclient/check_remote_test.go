@@ -0,0 +1,106 @@
+package snclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRemote(t *testing.T) {
+	config := `
+[/modules]
+CheckRemote = enabled
+WEBServer = enabled
+NRPEServer = enabled
+
+[/settings/WEB/server]
+port = 45666
+use ssl = false
+password = test
+
+[/settings/NRPE/server]
+port = 45667
+use ssl = false
+allow arguments = true
+allow nasty characters = true
+
+[/settings/remote]
+use ssl = false
+
+[/settings/remote/targets/web]
+host = 127.0.0.1
+port = 45666
+password = test
+`
+	snc := StartTestAgent(t, config)
+
+	res := snc.RunCheck("check_remote", []string{"host=127.0.0.1", "port=45666", "password=test", "command=check_dummy", "arg=1", "arg=remote text"})
+	assert.Equalf(t, CheckExitWarning, res.State, "state from remote")
+	assert.Equalf(t, "remote text", string(res.BuildPluginOutput()), "output from remote")
+
+	res = snc.RunCheck("check_remote", []string{"target=web", "command=check_dummy", "arg=2", "arg=target text"})
+	assert.Equalf(t, CheckExitCritical, res.State, "state from remote target")
+	assert.Equalf(t, "target text", string(res.BuildPluginOutput()), "output from remote target")
+
+	res = snc.RunCheck("check_remote", []string{"target=web", "password=wrong", "command=check_dummy"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "wrong password")
+	assert.Containsf(t, string(res.BuildPluginOutput()), "403 Forbidden", "output contains http status")
+
+	res = snc.RunCheck("check_remote", []string{"target=web", "host=127.0.0.2", "command=check_dummy"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "host override for target")
+	assert.Containsf(t, string(res.BuildPluginOutput()), "cannot be used together with target", "output contains error")
+
+	res = snc.RunCheck("check_remote", []string{"target=web", "insecure=true", "command=check_dummy"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "insecure override for target")
+
+	res = snc.RunCheck("check_remote", []string{"target=unknown", "command=check_dummy"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "unknown target")
+	assert.Containsf(t, string(res.BuildPluginOutput()), "no such target: unknown", "output contains error")
+
+	res = snc.RunCheck("check_remote", []string{"host=127.0.0.1", "port=45667", "protocol=nrpe", "command=check_dummy", "arg=1", "arg=nrpe text|val=5;10;20"})
+	assert.Equalf(t, CheckExitWarning, res.State, "state from remote nrpe")
+	assert.Equalf(t, "nrpe text |val=5;10;20", string(res.BuildPluginOutput()), "output and perfdata from remote nrpe")
+
+	StopTestAgent(t, snc)
+}
+
+func TestCheckRemoteDisabled(t *testing.T) {
+	snc := StartTestAgent(t, "")
+
+	res := snc.RunCheck("check_remote", []string{"host=127.0.0.1", "command=check_dummy"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "state unknown")
+	assert.Containsf(t, string(res.BuildPluginOutput()), "module CheckRemote is not enabled", "output contains error")
+
+	StopTestAgent(t, snc)
+}
+
+func TestCheckRemotePerfData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/queries/check_test/commands/execute" || r.URL.RawQuery != "warn%3Dload+%3E+5" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"command":"check_test","result":1,"lines":[`+
+			`{"message":"WARNING - load is high","perf":{"load1":{"value":5.50,"unit":"","warning":"5","critical":"10","minimum":0}}},`+
+			`{"message":"","perf":{"used":{"value":"U","unit":"B","maximum":1024}}}]}`)
+	}))
+	defer server.Close()
+
+	config := `
+[/modules]
+CheckRemote = enabled
+`
+	snc := StartTestAgent(t, config)
+
+	res := snc.RunCheck("check_remote", []string{"host=" + server.URL, "command=check_test", "arg=warn=load > 5"})
+	require.Equalf(t, CheckExitWarning, res.State, "state from remote: %s", res.Output)
+	assert.Equalf(t, "WARNING - load is high |'load1'=5.50;5;10;0 'used'=UB;;;;1024", string(res.BuildPluginOutput()), "output and perfdata unchanged")
+
+	StopTestAgent(t, snc)
+}
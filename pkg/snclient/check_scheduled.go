@@ -0,0 +1,31 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+)
+
+// CheckScheduled returns the cached result of a scheduled check.
+type CheckScheduled struct {
+	noCopy    noCopy
+	name      string
+	scheduler *SchedulerHandler
+}
+
+func (c *CheckScheduled) Build() *CheckData {
+	return &CheckData{
+		name:        c.name,
+		description: fmt.Sprintf("Returns the cached result of the scheduled check %s.", c.name),
+	}
+}
+
+func (c *CheckScheduled) Check(_ context.Context, _ *Agent, check *CheckData, _ []Argument) (*CheckResult, error) {
+	res, timezone, ok := c.scheduler.GetResult(c.name)
+	if !ok {
+		return nil, fmt.Errorf("no such scheduled check: %s", c.name)
+	}
+	// finalize with the timezone of the scheduled check
+	check.timezone = timezone
+
+	return res, nil
+}
@@ -3,9 +3,16 @@ package snclient
 import (
 	"context"
 	"fmt"
+	"maps"
+	"os"
+	"os/exec"
 	"regexp"
 	"slices"
 	"strings"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/convert"
+	"github.com/consol-monitoring/snclient/pkg/utils"
 )
 
 func init() {
@@ -13,7 +20,7 @@ func init() {
 }
 
 var (
-	reSvcDetails   = regexp.MustCompile(`(?s)^.*?\.service(?:\s\-\s(.*?)|)\n.*Active:\s*([A-Za-z() :-]+)(?:\ssince|\n)`)
+	reSvcDetails   = regexp.MustCompile(`(?s)^.*?\.(?:service|timer)(?:\s\-\s(.*?)|)\n.*Active:\s*([A-Za-z() :-]+)(?:\ssince|\n)`)
 	reSvcMainPid   = regexp.MustCompile(`Main\sPID:\s(\d+)`)
 	reSvcPidMaster = regexp.MustCompile(`─(\d+).+\(master\)`)
 	reSvcPidCgroup = regexp.MustCompile(`[├─└]─\s*(\d+)\s+`)
@@ -21,31 +28,63 @@ var (
 	reSvcTasks     = regexp.MustCompile(`Tasks:\s*(\d+)`)
 	reSvcStatic    = regexp.MustCompile(`;\sstatic\)`)
 	reSvcActive    = regexp.MustCompile(`\s*Active:\s+(\S+)`)
-	reSvcFirstLine = regexp.MustCompile(`^.\s(\S+)\.service\s+`)
-	reSvcNameLine  = regexp.MustCompile(`^\s*(\S+)\.service\s+`)
+	reSvcFirstLine = regexp.MustCompile(`^.\s(\S+)\.(?:service|timer)\s+`)
+	reSvcNameLine  = regexp.MustCompile(`^\s*(\S+)\.(?:service|timer)\s+`)
 )
 
 const (
 	systemctlStatusCmd = "systemctl status --lines=0 --no-pager --quiet"
 	systemctlNames     = "systemctl list-units --lines=0 --no-pager --quiet --no-legend"
+	systemctlShowCmd   = "systemctl show --no-pager"
 )
 
+// unit properties fetched by systemctl show
+var systemdShowProperties = []string{
+	"Id", "NRestarts", "ExecMainStatus", "ActiveEnterTimestamp", "Result",
+	"LastTriggerUSec", "NextElapseUSecRealtime", "Unit",
+}
+
+// attributes only set by some backends or unit types
+var serviceOptionalAttributes = []string{
+	"restarts", "exec_main_status", "active_enter", "result",
+	"last_trigger", "next_elapse", "unit", "last_result",
+}
+
 type CheckService struct {
 	snc      *Agent
 	services []string
 	excludes []string
+	backend  string
+	unitType string
+}
+
+// serviceBackend lists and checks services of a linux init system.
+type serviceBackend interface {
+	// list returns all services
+	list(ctx context.Context) ([]map[string]string, error)
+
+	// status returns a single service or an error if the service does not exist
+	status(ctx context.Context, name string) (map[string]string, error)
 }
 
 func NewCheckService() CheckHandler {
-	return &CheckService{}
+	return &CheckService{
+		backend:  "auto",
+		unitType: "service",
+	}
 }
 
 func (l *CheckService) Build() *CheckData {
-	stateCondition := "( state not in ('running', 'oneshot', 'static') || active = 'failed' ) "
+	stateCondition := "( state not in ('running', 'oneshot', 'static', 'waiting', 'elapsed') || active = 'failed' || ( last_result != '' && last_result != 'success' ) ) "
 
 	return &CheckData{
 		name: "check_service",
-		description: `Checks the state of one or multiple linux (systemctl) services.
+		description: `Checks the state of one or multiple linux services.
+
+Supports systemd, OpenRC, SysV-init and runit. The init system is detected automatically
+unless set by the backend argument.
+
+Systemd timers can be checked with type=timer.
 
 There is a specific [check_service for windows](../check_service_windows) as well.`,
 		implemented:  Linux,
@@ -67,6 +106,8 @@ There is a specific [check_service for windows](../check_service_windows) as wel
 				defaultCritical: stateCondition,
 			},
 			"exclude": {value: &l.excludes, description: "List of services to exclude from the check (mainly used when service is set to *) (case insensitive)"},
+			"backend": {value: &l.backend, description: "Init system to use: auto, systemd, openrc, sysv or runit (default: auto)"},
+			"type":    {value: &l.unitType, description: "Unit type to check: service or timer, timers are only supported by systemd (default: service)"},
 		},
 		defaultFilter:   "active != inactive",
 		defaultCritical: stateCondition + " && preset != 'disabled'",
@@ -80,7 +121,7 @@ There is a specific [check_service for windows](../check_service_windows) as wel
 			{name: "service", description: "Alias for name"},
 			{name: "desc", description: "Description of the service"},
 			{name: "active", description: "The active attribute of a service, one of: active, inactive or failed"},
-			{name: "state", description: "The state of the service, one of: stopped, starting, oneshot, running, static or unknown"},
+			{name: "state", description: "The state of the service, one of: stopped, starting, oneshot, running, static, waiting, elapsed or unknown"},
 			{name: "pid", description: "The pid of the service"},
 			{name: "created", description: "Date when service was started", unit: UDate},
 			{name: "age", description: "Seconds since service was started", unit: UDuration},
@@ -89,6 +130,15 @@ There is a specific [check_service for windows](../check_service_windows) as wel
 			{name: "cpu", description: "CPU usage in percent (main process)", unit: UPercent},
 			{name: "preset", description: "The preset attribute of the service, one of: enabled or disabled"},
 			{name: "tasks", description: "Number of tasks for this service"},
+			{name: "backend", description: "The init system of the service, one of: systemd, openrc, sysv or runit"},
+			{name: "restarts", description: "Number of automatic restarts since the unit was loaded (systemd only)"},
+			{name: "exec_main_status", description: "Exit code of the main process (systemd only)"},
+			{name: "active_enter", description: "Date when the unit entered the active state (systemd only)", unit: UDate},
+			{name: "result", description: "Result of the last run, ex.: success, exit-code, signal, timeout or core-dump (systemd only)"},
+			{name: "last_trigger", description: "Date when the timer triggered last (timer only)", unit: UDate},
+			{name: "next_elapse", description: "Date when the timer triggers next (timer only)", unit: UDate},
+			{name: "unit", description: "Name of the unit triggered by the timer (timer only)"},
+			{name: "last_result", description: "Result of the last run of the triggered unit (timer only)"},
 		},
 		exampleDefault: `
 Checking all services except some excluded ones:
@@ -105,6 +155,16 @@ Check memory usage of specific service:
 
     check_service service=docker warn='rss > 1GB' warn='rss > 2GB'
     OK - All 1 service(s) are ok. |'docker'=4 'docker rss'=59691008B;;;0 'docker vms'=3166244864B;;;0 'docker cpu'=0.7%;;;0 'docker tasks'=20;;;0
+
+Alert on crash looping services which are currently running:
+
+    check_service warn='restarts > 0' crit='restarts > 5 || result != success'
+    OK - All 74 service(s) are ok.
+
+Check systemd timers and the result of their last run:
+
+    check_service type=timer
+    OK - All 12 service(s) are ok.
 	`,
 		exampleArgs: "service=docker",
 	}
@@ -118,16 +178,50 @@ func (l *CheckService) Check(ctx context.Context, snc *Agent, check *CheckData,
 		l.excludes[i] = strings.ToLower(l.excludes[i])
 	}
 
+	backendName, err := l.detectBackend()
+	if err != nil {
+		return nil, err
+	}
+	log.Tracef("using service backend: %s", backendName)
+
+	switch {
+	case l.unitType != "service" && l.unitType != "timer":
+		return nil, fmt.Errorf("unknown type %s, must be one of: service or timer", l.unitType)
+	case l.unitType == "timer" && backendName != "systemd":
+		return nil, fmt.Errorf("type=timer is only supported by the systemd backend")
+	}
+
+	var backend serviceBackend
+	switch backendName {
+	case "openrc":
+		backend = &serviceBackendOpenRC{snc: snc}
+	case "sysv":
+		backend = &serviceBackendSysV{snc: snc}
+	case "runit":
+		backend = &serviceBackendRunit{snc: snc}
+	default:
+		backend = &serviceBackendSystemd{snc: snc, check: l, unitType: l.unitType}
+	}
+
 	if len(l.services) == 0 || slices.Contains(l.services, "*") {
-		// fetch status of all services at once instead of calling systemctl over and over
-		output, stderr, _, err := snc.execCommand(ctx, fmt.Sprintf("%s --type=service --all", systemctlStatusCmd), DefaultCmdTimeout)
+		// fetch status of all services at once instead of checking them one by one
+		entries, err := backend.list(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch service list: %s%s", err.Error(), stderr)
+			return nil, fmt.Errorf("failed to fetch service list: %s", err.Error())
 		}
 
-		err = l.parseAllServices(ctx, check, output)
-		if err != nil {
-			return nil, err
+		for _, listEntry := range entries {
+			if slices.Contains(l.excludes, strings.ToLower(listEntry["name"])) {
+				log.Tracef("service %s excluded by 'exclude' argument", listEntry["name"])
+
+				continue
+			}
+
+			setServiceOptionalAttributes(listEntry, backendName)
+			err = l.addService(ctx, check, listEntry["name"], listEntry, l.services, l.excludes)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -152,7 +246,13 @@ func (l *CheckService) Check(ctx context.Context, snc *Agent, check *CheckData,
 			return nil, fmt.Errorf("service name must not contain nasty characters")
 		}
 
-		err := l.addServiceByName(ctx, check, service, l.services, l.excludes)
+		listEntry, err := backend.status(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+
+		setServiceOptionalAttributes(listEntry, backendName)
+		err = l.addService(ctx, check, listEntry["name"], listEntry, l.services, l.excludes)
 		if err != nil {
 			return nil, err
 		}
@@ -166,33 +266,207 @@ func (l *CheckService) Check(ctx context.Context, snc *Agent, check *CheckData,
 	return check.Finalize()
 }
 
-func (l *CheckService) addServiceByName(ctx context.Context, check *CheckData, service string, services, excludes []string) error {
-	err := l.addServiceByExactName(ctx, check, service, services, excludes)
+// setServiceOptionalAttributes sets the backend and all attributes not supported by every backend.
+func setServiceOptionalAttributes(listEntry map[string]string, backendName string) {
+	listEntry["backend"] = backendName
+	for _, attr := range serviceOptionalAttributes {
+		if _, ok := listEntry[attr]; !ok {
+			listEntry[attr] = ""
+		}
+	}
+}
+
+// detectBackend returns the init system used to check services.
+func (l *CheckService) detectBackend() (string, error) {
+	switch l.backend {
+	case "systemd", "openrc", "sysv", "runit":
+		return l.backend, nil
+	case "auto":
+	default:
+		return "", fmt.Errorf("unknown backend %s, must be one of: auto, systemd, openrc, sysv or runit", l.backend)
+	}
+
+	// same check as sd_booted()
+	if utils.IsFolder("/run/systemd/system") == nil {
+		return "systemd", nil
+	}
+	if utils.IsFolder("/run/openrc") == nil {
+		return "openrc", nil
+	}
+	if _, err := exec.LookPath("sv"); err == nil && runitServiceDir() != "" {
+		return "runit", nil
+	}
+	if utils.IsFolder("/etc/init.d") == nil {
+		return "sysv", nil
+	}
+
+	return "systemd", nil
+}
+
+// serviceBackendSystemd uses systemctl to check services.
+type serviceBackendSystemd struct {
+	snc      *Agent
+	check    *CheckService
+	unitType string
+}
+
+func (b *serviceBackendSystemd) list(ctx context.Context) ([]map[string]string, error) {
+	output, stderr, _, err := b.snc.execCommand(ctx, fmt.Sprintf("%s --type=%s --all", systemctlStatusCmd, b.unitType), DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%s%s", err.Error(), stderr)
+	}
+
+	entries := b.check.parseAllServices(output)
+	b.addProperties(ctx, entries, fmt.Sprintf("'*.%s'", b.unitType))
+
+	return entries, nil
+}
+
+func (b *serviceBackendSystemd) status(ctx context.Context, service string) (map[string]string, error) {
+	listEntry, err := b.statusExactName(ctx, service)
 	if err == nil {
-		return nil
+		return listEntry, nil
 	}
 
-	realService := l.findServiceByName(ctx, service)
+	realService := b.findServiceByName(ctx, service)
 	if realService == "" {
-		return err
+		return nil, err
 	}
 
-	return l.addServiceByExactName(ctx, check, realService, services, excludes)
+	return b.statusExactName(ctx, realService)
 }
 
-func (l *CheckService) addServiceByExactName(ctx context.Context, check *CheckData, service string, services, excludes []string) error {
-	output, stderr, _, err := l.snc.execCommand(ctx, fmt.Sprintf("%s %s.service ", systemctlStatusCmd, service), DefaultCmdTimeout)
+func (b *serviceBackendSystemd) statusExactName(ctx context.Context, service string) (map[string]string, error) {
+	output, stderr, _, err := b.snc.execCommand(ctx, fmt.Sprintf("%s %s.%s ", systemctlStatusCmd, service, b.unitType), DefaultCmdTimeout)
 	if err != nil {
-		return fmt.Errorf("systemctl failed: %s\n%s", err.Error(), stderr)
+		return nil, fmt.Errorf("systemctl failed: %s\n%s", err.Error(), stderr)
 	}
 
 	if match, _ := regexp.MatchString(`Unit .* could not be found`, stderr); match || len(output) < 1 {
-		return fmt.Errorf("could not find service: %s", service)
+		return nil, fmt.Errorf("could not find service: %s", service)
+	}
+
+	listEntry := b.check.parseSystemCtlStatus(service, output)
+	b.addProperties(ctx, []map[string]string{listEntry}, fmt.Sprintf("%s.%s", service, b.unitType))
+
+	return listEntry, nil
+}
+
+// addProperties adds restart counters, results and timer details from systemctl show.
+func (b *serviceBackendSystemd) addProperties(ctx context.Context, entries []map[string]string, unitPattern string) {
+	units, err := b.show(ctx, unitPattern)
+	if err != nil {
+		log.Debugf("%s", err.Error())
+
+		return
 	}
 
-	listEntry := l.parseSystemCtlStatus(service, output)
+	// fetch result of the units triggered by timers
+	triggered := map[string]map[string]string{}
+	if b.unitType == "timer" {
+		names := []string{}
+		for _, props := range units {
+			if props["Unit"] != "" && !strings.ContainsAny(props["Unit"], SystemCmdNastyCharacters) {
+				names = append(names, props["Unit"])
+			}
+		}
+		if len(names) > 0 {
+			triggered, err = b.show(ctx, strings.Join(names, " "))
+			if err != nil {
+				log.Debugf("%s", err.Error())
+			}
+		}
+	}
+
+	for _, listEntry := range entries {
+		props, ok := systemdEntryProperties(listEntry["name"]+"."+b.unitType, units, len(entries) == 1)
+		if !ok {
+			continue
+		}
+		setSystemdProperties(listEntry, props, triggered[props["Unit"]])
+	}
+}
+
+// systemdEntryProperties returns the properties of given unit. Units queried by an alias are shown with
+// the id of the unit they point to (ex.: sshd.service -> ssh.service), so single units use the only block.
+func systemdEntryProperties(unit string, units map[string]map[string]string, single bool) (map[string]string, bool) {
+	if single && len(units) == 1 {
+		return slices.Collect(maps.Values(units))[0], true
+	}
+
+	props, ok := units[unit]
+
+	return props, ok
+}
+
+// show returns the unit properties from systemctl show by unit name.
+func (b *serviceBackendSystemd) show(ctx context.Context, units string) (map[string]map[string]string, error) {
+	cmd := fmt.Sprintf("%s --property=%s %s", systemctlShowCmd, strings.Join(systemdShowProperties, ","), units)
+	output, stderr, _, err := b.snc.execCommand(ctx, cmd, DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show failed: %s\n%s", err.Error(), stderr)
+	}
+
+	return parseSystemCtlShow(output), nil
+}
+
+// parseSystemCtlShow parses the key=value blocks from systemctl show.
+func parseSystemCtlShow(output string) map[string]map[string]string {
+	units := map[string]map[string]string{}
+	for _, block := range strings.Split(output, "\n\n") {
+		props := map[string]string{}
+		for _, line := range strings.Split(block, "\n") {
+			key, val, ok := strings.Cut(line, "=")
+			if ok {
+				props[strings.TrimSpace(key)] = strings.TrimSpace(val)
+			}
+		}
+		if props["Id"] != "" {
+			units[props["Id"]] = props
+		}
+	}
+
+	return units
+}
+
+// setSystemdProperties sets the attributes from the unit properties and the properties of the triggered unit (timers only).
+func setSystemdProperties(listEntry, props, triggered map[string]string) {
+	listEntry["restarts"] = props["NRestarts"]
+	listEntry["exec_main_status"] = props["ExecMainStatus"]
+	listEntry["active_enter"] = parseSystemdTimestamp(props["ActiveEnterTimestamp"])
+	listEntry["result"] = props["Result"]
 
-	return l.addService(ctx, check, service, listEntry, services, excludes)
+	if _, ok := props["LastTriggerUSec"]; !ok {
+		return
+	}
+	listEntry["last_trigger"] = parseSystemdTimestamp(props["LastTriggerUSec"])
+	listEntry["next_elapse"] = parseSystemdTimestamp(props["NextElapseUSecRealtime"])
+	listEntry["unit"] = props["Unit"]
+	if triggered != nil {
+		listEntry["last_result"] = triggered["Result"]
+		listEntry["exec_main_status"] = triggered["ExecMainStatus"]
+	}
+}
+
+// parseSystemdTimestamp converts systemd timestamps like "Mon 2024-06-03 10:00:00 CEST" or "@1717401600" into unix timestamps.
+func parseSystemdTimestamp(val string) string {
+	if strings.HasPrefix(val, "@") {
+		return strings.TrimPrefix(val, "@")
+	}
+
+	// systemctl prints local time, the timezone abbreviation is ambiguous
+	fields := strings.Fields(val)
+	if len(fields) < 3 {
+		return ""
+	}
+	date, err := time.ParseInLocation("2006-01-02 15:04:05", fields[1]+" "+fields[2], time.Local)
+	if err != nil {
+		log.Tracef("cannot parse systemd timestamp %s: %s", val, err.Error())
+
+		return ""
+	}
+
+	return fmt.Sprintf("%d", date.Unix())
 }
 
 func (l *CheckService) addService(ctx context.Context, check *CheckData, service string, listEntry map[string]string, services, excludes []string) error {
@@ -217,6 +491,19 @@ func (l *CheckService) addService(ctx context.Context, check *CheckData, service
 
 	l.addServiceMetrics(service, l.svcStateFloat(listEntry["state"]), check, listEntry)
 
+	if listEntry["restarts"] != "" {
+		check.result.Metrics = append(check.result.Metrics,
+			&CheckMetric{
+				ThresholdName: "restarts",
+				Name:          fmt.Sprintf("%s restarts", service),
+				Value:         convert.Int64(listEntry["restarts"]),
+				Warning:       check.warnThreshold,
+				Critical:      check.critThreshold,
+				Min:           &Zero,
+			},
+		)
+	}
+
 	return nil
 }
 
@@ -230,6 +517,10 @@ func (l *CheckService) svcState(serviceState string) string {
 		return "oneshot"
 	case "active (running)":
 		return "running"
+	case "active (waiting)":
+		return "waiting"
+	case "active (elapsed)":
+		return "elapsed"
 	}
 	if strings.HasPrefix(serviceState, "failed") {
 		return "stopped"
@@ -250,13 +541,18 @@ func (l *CheckService) svcStateFloat(serviceState string) float64 {
 		return float64(4)
 	case "static":
 		return float64(5)
+	case "waiting":
+		return float64(6)
+	case "elapsed":
+		return float64(7)
 	}
 
 	return float64(0)
 }
 
-func (l *CheckService) parseSystemCtlStatus(name, output string) (listEntry map[string]string) {
-	listEntry = map[string]string{
+// newServiceEntry returns a list entry with all default attributes.
+func newServiceEntry(name string) map[string]string {
+	return map[string]string{
 		"name":    name,
 		"service": name,
 		"active":  "",
@@ -271,6 +567,26 @@ func (l *CheckService) parseSystemCtlStatus(name, output string) (listEntry map[
 		"cpu":     "",
 		"tasks":   "",
 	}
+}
+
+// readServicePidFile returns the pid from the usual pid file locations or an empty string.
+func readServicePidFile(name string) string {
+	for _, file := range []string{"/run/" + name + ".pid", "/var/run/" + name + ".pid", "/run/" + name + "/" + name + ".pid"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		pid := strings.TrimSpace(string(data))
+		if utils.IsDigitsOnly(pid) && pid != "" {
+			return pid
+		}
+	}
+
+	return ""
+}
+
+func (l *CheckService) parseSystemCtlStatus(name, output string) (listEntry map[string]string) {
+	listEntry = newServiceEntry(name)
 
 	match := reSvcDetails.FindStringSubmatch(output)
 	if len(match) > 2 {
@@ -320,7 +636,7 @@ func (l *CheckService) parseSystemCtlStatus(name, output string) (listEntry map[
 	return listEntry
 }
 
-func (l *CheckService) parseAllServices(ctx context.Context, check *CheckData, output string) (err error) {
+func (l *CheckService) parseAllServices(output string) (entries []map[string]string) {
 	// services are separated by two empty lines
 	services := strings.Split(output, "\n\n")
 	for _, svc := range services {
@@ -332,24 +648,14 @@ func (l *CheckService) parseAllServices(ctx context.Context, check *CheckData, o
 		}
 		service := serviceMatches[1]
 
-		if slices.Contains(l.excludes, strings.ToLower(service)) {
-			log.Tracef("service %s excluded by 'exclude' argument", service)
-
-			continue
-		}
-
-		listEntry := l.parseSystemCtlStatus(service, svc)
-		err = l.addService(ctx, check, service, listEntry, l.services, l.excludes)
-		if err != nil {
-			return err
-		}
+		entries = append(entries, l.parseSystemCtlStatus(service, svc))
 	}
 
-	return nil
+	return entries
 }
 
-func (l *CheckService) findServiceByName(ctx context.Context, service string) (name string) {
-	output, _, _, err := l.snc.execCommand(ctx, systemctlNames, DefaultCmdTimeout)
+func (b *serviceBackendSystemd) findServiceByName(ctx context.Context, service string) (name string) {
+	output, _, _, err := b.snc.execCommand(ctx, systemctlNames, DefaultCmdTimeout)
 	if err != nil {
 		log.Tracef("systemctl failed: %s", err.Error())
 
@@ -27,6 +27,10 @@ func TestCheckServiceLinux(t *testing.T) {
 	res = snc.RunCheck("check_service", []string{"service=nonexistingservice"})
 	assert.Equalf(t, CheckExitUnknown, res.State, "state Unknown")
 	assert.Containsf(t, string(res.BuildPluginOutput()), "UNKNOWN - could not find service: nonexistingservice", "output matches")
+
+	res = snc.RunCheck("check_service", []string{"backend=upstart"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "state Unknown")
+	assert.Containsf(t, string(res.BuildPluginOutput()), "unknown backend upstart", "output matches")
 }
 
 func TestCheckServiceLinuxSystemCtlOutput_1(t *testing.T) {
@@ -228,3 +232,154 @@ TriggeredBy: ● dnf-makecache.timer
 	}
 	assert.Equalf(t, expect, entry, "parsed systemctl output")
 }
+
+func TestCheckServiceLinuxOpenRCOutput(t *testing.T) {
+	output := `Runlevel: default
+ sshd                                                              [  started  ]
+ crond                                                             [  started 3 day(s) 02:10:05 (0) ]
+ nginx                                                             [  crashed  ]
+Dynamic Runlevel: hotplugged
+Dynamic Runlevel: needed/wanted
+ sysfs                                                             [  started  ]
+ sshd                                                              [  started  ]
+Dynamic Runlevel: manual
+ postfix                                                           [  stopped  ]
+`
+
+	entries := parseOpenRCStatus(output)
+	expect := []map[string]string{
+		newServiceEntry("sshd"),
+		newServiceEntry("crond"),
+		newServiceEntry("nginx"),
+		newServiceEntry("sysfs"),
+		newServiceEntry("postfix"),
+	}
+	for i, state := range [][]string{
+		{"running", "active", "enabled"},
+		{"running", "active", "enabled"},
+		{"stopped", "failed", "enabled"},
+		{"running", "active", "enabled"},
+		{"stopped", "inactive", "disabled"},
+	} {
+		expect[i]["state"], expect[i]["active"], expect[i]["preset"] = state[0], state[1], state[2]
+	}
+	assert.Equalf(t, expect, entries, "parsed rc-status output")
+}
+
+func TestCheckServiceLinuxSysVOutput(t *testing.T) {
+	output := ` [ + ]  cron
+ [ - ]  dbus
+ [ ? ]  hwclock.sh
+`
+
+	entries := parseSysVStatusAll(output)
+	expect := []map[string]string{
+		newServiceEntry("cron"),
+		newServiceEntry("dbus"),
+		newServiceEntry("hwclock.sh"),
+	}
+	expect[0]["state"], expect[0]["active"] = "running", "active"
+	expect[1]["state"], expect[1]["active"] = "stopped", "inactive"
+	expect[2]["state"], expect[2]["active"] = "unknown", "inactive"
+	assert.Equalf(t, expect, entries, "parsed service --status-all output")
+
+	for exitCode, expectState := range map[int64][]string{
+		0: {"running", "active"},
+		1: {"stopped", "failed"},
+		3: {"stopped", "inactive"},
+		4: {"unknown", "inactive"},
+	} {
+		state, active := sysvState(exitCode)
+		assert.Equalf(t, expectState, []string{state, active}, "lsb exit code %d", exitCode)
+	}
+}
+
+func TestCheckServiceLinuxRunitOutput(t *testing.T) {
+	output := `run: /etc/service/sshd: (pid 1234) 5678s; run: log: (pid 1233) 5678s
+down: /etc/service/cron: 12s, normally up
+down: /etc/service/backup: 300s
+run: /etc/service/getty-tty7: (pid 999) 20s, normally down
+fail: /etc/service/broken: unable to change to service directory: file does not exist
+`
+
+	entries := parseRunitStatus(output)
+	expect := []map[string]string{
+		newServiceEntry("sshd"),
+		newServiceEntry("cron"),
+		newServiceEntry("backup"),
+		newServiceEntry("getty-tty7"),
+		newServiceEntry("broken"),
+	}
+	for i, state := range [][]string{
+		{"running", "active", "enabled", "1234"},
+		{"stopped", "failed", "enabled", ""},
+		{"stopped", "inactive", "enabled", ""},
+		{"running", "active", "disabled", "999"},
+		{"unknown", "failed", "enabled", ""},
+	} {
+		expect[i]["state"], expect[i]["active"], expect[i]["preset"], expect[i]["pid"] = state[0], state[1], state[2], state[3]
+	}
+	expect[4]["desc"] = "unable to change to service directory: file does not exist"
+	assert.Equalf(t, expect, entries, "parsed sv status output")
+}
+
+func TestCheckServiceLinuxSystemCtlTimer(t *testing.T) {
+	output := `● logrotate.timer - Daily rotation of log files
+     Loaded: loaded (/usr/lib/systemd/system/logrotate.timer; enabled; preset: enabled)
+     Active: active (waiting) since Mon 2024-06-03 10:00:00 CEST; 2 days ago
+    Trigger: Wed 2024-06-05 00:00:00 CEST; 9h left
+   Triggers: ● logrotate.service`
+
+	cs := &CheckService{}
+	entries := cs.parseAllServices(output)
+	assert.Lenf(t, entries, 1, "parsed timer")
+	assert.Equalf(t, "logrotate", entries[0]["name"], "timer name")
+	assert.Equalf(t, "Daily rotation of log files", entries[0]["desc"], "timer description")
+	assert.Equalf(t, "waiting", entries[0]["state"], "timer state")
+	assert.Equalf(t, "enabled", entries[0]["preset"], "timer preset")
+
+	show := `Id=logrotate.timer
+Result=success
+Unit=logrotate.service
+ActiveEnterTimestamp=Mon 2024-06-03 10:00:00 CEST
+LastTriggerUSec=@1717452000
+NextElapseUSecRealtime=Wed 2024-06-05 00:00:00 CEST
+
+Id=logrotate.service
+NRestarts=0
+ExecMainStatus=1
+Result=exit-code
+ActiveEnterTimestamp=n/a
+`
+	units := parseSystemCtlShow(show)
+	assert.Lenf(t, units, 2, "parsed systemctl show")
+
+	setSystemdProperties(entries[0], units["logrotate.timer"], units[units["logrotate.timer"]["Unit"]])
+	assert.Equalf(t, "1717452000", entries[0]["last_trigger"], "last trigger")
+	assert.Equalf(t, parseSystemdTimestamp("Wed 2024-06-05 00:00:00 CEST"), entries[0]["next_elapse"], "next elapse")
+	assert.Equalf(t, "logrotate.service", entries[0]["unit"], "triggered unit")
+	assert.Equalf(t, "exit-code", entries[0]["last_result"], "last result")
+	assert.Equalf(t, "1", entries[0]["exec_main_status"], "exit code of triggered unit")
+	assert.Equalf(t, "success", entries[0]["result"], "timer result")
+
+	svc := newServiceEntry("logrotate")
+	setSystemdProperties(svc, units["logrotate.service"], nil)
+	assert.Equalf(t, "0", svc["restarts"], "restarts")
+	assert.Equalf(t, "", svc["active_enter"], "never active")
+	assert.NotContainsf(t, svc, "last_trigger", "no timer attributes")
+
+	// aliases are shown with the id of the real unit
+	alias := parseSystemCtlShow("Id=ssh.service\nNRestarts=3\nResult=success\n")
+	props, ok := systemdEntryProperties("sshd.service", alias, true)
+	assert.Truef(t, ok, "alias found")
+	assert.Equalf(t, "3", props["NRestarts"], "alias properties")
+	_, ok = systemdEntryProperties("sshd.service", alias, false)
+	assert.Falsef(t, ok, "lists use the unit id")
+	props, ok = systemdEntryProperties("logrotate.service", units, false)
+	assert.Truef(t, ok, "unit found by id")
+	assert.Equalf(t, "exit-code", props["Result"], "unit properties")
+
+	assert.Equalf(t, "", parseSystemdTimestamp(""), "empty timestamp")
+	assert.Equalf(t, "", parseSystemdTimestamp("n/a"), "empty timestamp")
+	assert.Equalf(t, "1717452000", parseSystemdTimestamp("@1717452000"), "unix timestamp")
+}
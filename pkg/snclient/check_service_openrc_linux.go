@@ -0,0 +1,107 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	reOpenRCRunlevel = regexp.MustCompile(`^(?:Dynamic\s+)?Runlevel:\s*(.+?)\s*$`)
+	reOpenRCService  = regexp.MustCompile(`^\s*(\S+)\s+\[\s*(\w+)[^\]]*\]\s*$`)
+	reOpenRCStatus   = regexp.MustCompile(`status:\s*(\w+)`)
+)
+
+// serviceBackendOpenRC uses rc-status and rc-service to check services.
+type serviceBackendOpenRC struct {
+	snc *Agent
+}
+
+func (b *serviceBackendOpenRC) list(ctx context.Context) ([]map[string]string, error) {
+	output, stderr, _, err := b.snc.execCommand(ctx, "rc-status --all --nocolor", DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("rc-status failed: %s%s", err.Error(), stderr)
+	}
+
+	entries := parseOpenRCStatus(output)
+	for _, entry := range entries {
+		entry["pid"] = readServicePidFile(entry["name"])
+	}
+
+	return entries, nil
+}
+
+func (b *serviceBackendOpenRC) status(ctx context.Context, service string) (map[string]string, error) {
+	output, stderr, _, err := b.snc.execCommand(ctx, fmt.Sprintf("rc-service --nocolor %s status", service), DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("rc-service failed: %s\n%s", err.Error(), stderr)
+	}
+
+	match := reOpenRCStatus.FindStringSubmatch(output + stderr)
+	if len(match) < 2 {
+		return nil, fmt.Errorf("could not find service: %s", service)
+	}
+
+	listEntry := newServiceEntry(service)
+	listEntry["state"], listEntry["active"] = openRCState(match[1])
+	listEntry["preset"] = "disabled"
+	if matches, _ := filepath.Glob(filepath.Join("/etc/runlevels", "*", service)); len(matches) > 0 {
+		listEntry["preset"] = "enabled"
+	}
+	listEntry["pid"] = readServicePidFile(service)
+
+	return listEntry, nil
+}
+
+// parseOpenRCStatus parses the output of rc-status --all.
+func parseOpenRCStatus(output string) (entries []map[string]string) {
+	runlevel := ""
+	seen := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		if match := reOpenRCRunlevel.FindStringSubmatch(line); len(match) > 1 {
+			runlevel = match[1]
+
+			continue
+		}
+
+		match := reOpenRCService.FindStringSubmatch(line)
+		if len(match) < 3 {
+			continue
+		}
+
+		// services may be part of multiple runlevels
+		name := match[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		listEntry := newServiceEntry(name)
+		listEntry["state"], listEntry["active"] = openRCState(match[2])
+		listEntry["preset"] = "enabled"
+		if runlevel == "manual" {
+			listEntry["preset"] = "disabled"
+		}
+		entries = append(entries, listEntry)
+	}
+
+	return entries
+}
+
+// openRCState maps the openrc status to the state and active attribute.
+func openRCState(status string) (state, active string) {
+	switch status {
+	case "started":
+		return "running", "active"
+	case "starting", "stopping":
+		return "starting", "activating"
+	case "stopped", "inactive":
+		return "stopped", "inactive"
+	case "crashed":
+		return "stopped", "failed"
+	}
+
+	return "unknown", ""
+}
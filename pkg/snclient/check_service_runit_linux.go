@@ -0,0 +1,108 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+var (
+	reRunitStatus = regexp.MustCompile(`^(run|down|finish|fail|warning):\s+([^:]+):\s*(.*)$`)
+	reRunitPid    = regexp.MustCompile(`\(pid\s+(\d+)\)`)
+)
+
+// serviceBackendRunit uses sv status to check services.
+type serviceBackendRunit struct {
+	snc *Agent
+}
+
+// runitServiceDir returns the folder containing the enabled runit services.
+func runitServiceDir() string {
+	for _, dir := range []string{os.Getenv("SVDIR"), "/etc/service", "/var/service", "/run/runit/service"} {
+		if dir != "" && utils.IsFolder(dir) == nil {
+			return dir
+		}
+	}
+
+	return ""
+}
+
+func (b *serviceBackendRunit) list(ctx context.Context) ([]map[string]string, error) {
+	svDir := runitServiceDir()
+	if svDir == "" {
+		return nil, fmt.Errorf("no runit service directory found")
+	}
+
+	output, stderr, _, err := b.snc.execCommand(ctx, fmt.Sprintf("sv status %s/*", svDir), DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("sv status failed: %s%s", err.Error(), stderr)
+	}
+
+	return parseRunitStatus(output), nil
+}
+
+func (b *serviceBackendRunit) status(ctx context.Context, service string) (map[string]string, error) {
+	svDir := runitServiceDir()
+	if svDir == "" || utils.IsFolder(filepath.Join(svDir, service)) != nil {
+		return nil, fmt.Errorf("could not find service: %s", service)
+	}
+
+	output, stderr, _, err := b.snc.execCommand(ctx, fmt.Sprintf("sv status %s", filepath.Join(svDir, service)), DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("sv status failed: %s\n%s", err.Error(), stderr)
+	}
+
+	entries := parseRunitStatus(output)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("could not find service: %s", service)
+	}
+
+	return entries[0], nil
+}
+
+// parseRunitStatus parses the output of sv status, ex.:
+//
+//	run: /etc/service/sshd: (pid 1234) 5678s; run: log: (pid 1233) 5678s
+//	down: /etc/service/cron: 12s, normally up
+func parseRunitStatus(output string) (entries []map[string]string) {
+	for _, line := range strings.Split(output, "\n") {
+		match := reRunitStatus.FindStringSubmatch(strings.TrimSpace(line))
+		if len(match) < 4 {
+			continue
+		}
+
+		// ignore status of the log service
+		details, _, _ := strings.Cut(match[3], ";")
+
+		listEntry := newServiceEntry(filepath.Base(match[2]))
+		listEntry["preset"] = "enabled"
+		if strings.Contains(details, "normally down") {
+			listEntry["preset"] = "disabled"
+		}
+
+		switch match[1] {
+		case "run":
+			listEntry["state"], listEntry["active"] = "running", "active"
+			if pid := reRunitPid.FindStringSubmatch(details); len(pid) > 1 {
+				listEntry["pid"] = pid[1]
+			}
+		case "down", "finish":
+			listEntry["state"], listEntry["active"] = "stopped", "inactive"
+			if strings.Contains(details, "normally up") {
+				listEntry["active"] = "failed"
+			}
+		default:
+			// supervise not running or service directory broken
+			listEntry["state"], listEntry["active"] = "unknown", "failed"
+			listEntry["desc"] = details
+		}
+		entries = append(entries, listEntry)
+	}
+
+	return entries
+}
@@ -0,0 +1,142 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+const sysvInitDir = "/etc/init.d"
+
+var (
+	reSysVStatusAll = regexp.MustCompile(`^\s*\[\s*([-+?])\s*\]\s+(\S+)\s*$`)
+
+	// files in /etc/init.d which are not services
+	sysvIgnoredScripts = []string{"README", "functions", "halt", "killall", "rc", "rcS", "reboot", "single", "skeleton"}
+)
+
+// serviceBackendSysV uses service --status-all and the init scripts to check services.
+type serviceBackendSysV struct {
+	snc *Agent
+}
+
+func (b *serviceBackendSysV) list(ctx context.Context) ([]map[string]string, error) {
+	var entries []map[string]string
+	if _, err := exec.LookPath("service"); err == nil {
+		output, _, _, err := b.snc.execCommand(ctx, "service --status-all", DefaultCmdTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("service --status-all failed: %s", err.Error())
+		}
+		entries = parseSysVStatusAll(output)
+	}
+
+	// not all distributions print the short status list, ask each init script instead
+	if len(entries) == 0 {
+		files, err := os.ReadDir(sysvInitDir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %s", sysvInitDir, err.Error())
+		}
+
+		for _, file := range files {
+			if file.IsDir() || slices.Contains(sysvIgnoredScripts, file.Name()) {
+				continue
+			}
+			listEntry, err := b.status(ctx, file.Name())
+			if err != nil {
+				log.Debugf("%s", err.Error())
+
+				continue
+			}
+			entries = append(entries, listEntry)
+		}
+
+		return entries, nil
+	}
+
+	for _, entry := range entries {
+		entry["preset"] = sysvPreset(entry["name"])
+		if entry["state"] == "running" {
+			entry["pid"] = readServicePidFile(entry["name"])
+		}
+	}
+
+	return entries, nil
+}
+
+func (b *serviceBackendSysV) status(ctx context.Context, service string) (map[string]string, error) {
+	script := filepath.Join(sysvInitDir, service)
+	if err := utils.IsFile(script); err != nil {
+		return nil, fmt.Errorf("could not find service: %s", service)
+	}
+
+	_, stderr, exitCode, err := b.snc.execCommand(ctx, script+" status", DefaultCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%s status failed: %s\n%s", script, err.Error(), stderr)
+	}
+
+	listEntry := newServiceEntry(service)
+	listEntry["state"], listEntry["active"] = sysvState(exitCode)
+	listEntry["preset"] = sysvPreset(service)
+	if listEntry["state"] == "running" {
+		listEntry["pid"] = readServicePidFile(service)
+	}
+
+	return listEntry, nil
+}
+
+// parseSysVStatusAll parses the output of service --status-all.
+func parseSysVStatusAll(output string) (entries []map[string]string) {
+	for _, line := range strings.Split(output, "\n") {
+		match := reSysVStatusAll.FindStringSubmatch(line)
+		if len(match) < 3 {
+			continue
+		}
+
+		listEntry := newServiceEntry(match[2])
+		switch match[1] {
+		case "+":
+			listEntry["state"], listEntry["active"] = "running", "active"
+		case "-":
+			listEntry["state"], listEntry["active"] = "stopped", "inactive"
+		default:
+			// init script does not support the status command
+			listEntry["state"], listEntry["active"] = "unknown", "inactive"
+		}
+		entries = append(entries, listEntry)
+	}
+
+	return entries
+}
+
+// sysvState maps the LSB exit code of the status command to the state and active attribute.
+func sysvState(exitCode int64) (state, active string) {
+	switch exitCode {
+	case 0:
+		return "running", "active"
+	case 1, 2:
+		// program is dead but pid file or lock file exists
+		return "stopped", "failed"
+	case 3:
+		return "stopped", "inactive"
+	}
+
+	return "unknown", "inactive"
+}
+
+// sysvPreset returns enabled if the service is started in any multi user runlevel.
+func sysvPreset(service string) string {
+	for _, pattern := range []string{"/etc/rc[2345].d/S??", "/etc/rc.d/rc[2345].d/S??"} {
+		if matches, _ := filepath.Glob(pattern + service); len(matches) > 0 {
+			return "enabled"
+		}
+	}
+
+	return "disabled"
+}
@@ -1,6 +1,7 @@
 package snclient
 
 import (
+	"cmp"
 	"fmt"
 	"math"
 	"slices"
@@ -76,6 +77,12 @@ const (
 	UPercent
 )
 
+// ListSort defines the sort order of list entries
+type ListSort struct {
+	attribute  string
+	descending bool
+}
+
 type CheckAttribute struct {
 	name        string
 	description string
@@ -118,6 +125,7 @@ type CheckData struct {
 	result                 *CheckResult
 	showHelp               ShowHelp
 	timeout                float64 // timeout in seconds
+	cacheTTL               float64 // result cache ttl in seconds set by cache-ttl, -1 if not set
 	perfConfig             []PerfConfig
 	perfSyntax             string
 	hasInventory           InventoryMode
@@ -127,6 +135,10 @@ type CheckData struct {
 	exampleDefault         string
 	exampleArgs            string
 	timezone               *time.Location // timezone used for date output set by --timezone
+	sortBy                 []ListSort     // sort list entries by these attributes
+	limit                  int64          // only show this number of list entries
+	groupBy                string         // group list entries by this attribute
+	groupSum               []string       // sum up these attributes when grouping list entries
 }
 
 func (cd *CheckData) Finalize() (*CheckResult, error) {
@@ -196,6 +208,8 @@ func (cd *CheckData) finalizeOutput() (*CheckResult, error) {
 	} else {
 		finalMacros = cd.buildListMacros()
 	}
+	cd.addAggregateMacros(finalMacros)
+	cd.applyListOptions(finalMacros)
 	err := cd.result.ApplyPerfConfig(cd.perfConfig)
 	if err != nil {
 		return nil, fmt.Errorf("%s", err.Error())
@@ -235,6 +249,14 @@ func (cd *CheckData) finalizeOutput() (*CheckResult, error) {
 }
 
 func (cd *CheckData) buildListMacros() map[string]string {
+	result, numCrit, numWarn := cd.listMacros(cd.listData)
+	cd.buildCountMetrics(len(cd.listData), numCrit, numWarn)
+
+	return result
+}
+
+// listMacros returns the count and list macros for given list entries along with the number of critical and warning entries
+func (cd *CheckData) listMacros(listData []map[string]string) (result map[string]string, numCrit, numWarn int) {
 	list := []string{}
 	okList := make([]string, 0)
 	warnList := make([]string, 0)
@@ -243,7 +265,7 @@ func (cd *CheckData) buildListMacros() map[string]string {
 	okCount := int64(0)
 	warnCount := int64(0)
 	critCount := int64(0)
-	for _, entry := range cd.listData {
+	for _, entry := range listData {
 		weight := int64(1)
 		if w, ok := entry["_count"]; ok {
 			weight = convert.Int64(w)
@@ -273,7 +295,7 @@ func (cd *CheckData) buildListMacros() map[string]string {
 	if cd.listCombine == "" {
 		cd.listCombine = ", "
 	}
-	result := map[string]string{
+	result = map[string]string{
 		"count":         fmt.Sprintf("%d", count),
 		"list":          strings.Join(list, cd.listCombine),
 		"ok_count":      fmt.Sprintf("%d", okCount),
@@ -309,9 +331,154 @@ func (cd *CheckData) buildListMacros() map[string]string {
 	result["problem_list"] = strings.Join(problemList, " ")
 	result["detail_list"] = strings.Join(detailList, " ")
 
-	cd.buildCountMetrics(len(list), len(critList), len(warnList))
+	return result, len(critList), len(warnList)
+}
 
-	return result
+// applyListOptions applies group-by, sort and limit to the list entries and replaces the list macros.
+// Counts and states are kept from the complete list, so limited entries still affect the result.
+func (cd *CheckData) applyListOptions(macros map[string]string) {
+	if cd.argsPassthrough {
+		return
+	}
+	if cd.groupBy == "" && len(cd.sortBy) == 0 && cd.limit == 0 {
+		return
+	}
+
+	listData := cd.listData
+	if cd.groupBy != "" {
+		listData = cd.groupListData(listData)
+	}
+	if len(cd.sortBy) > 0 {
+		listData = slices.Clone(listData)
+		sortListData(listData, cd.sortBy)
+	}
+	if cd.limit > 0 && int64(len(listData)) > cd.limit {
+		listData = listData[:cd.limit]
+	}
+
+	listMacros, _, _ := cd.listMacros(listData)
+	for _, key := range []string{"list", "ok_list", "warn_list", "crit_list", "problem_list", "detail_list"} {
+		macros[key] = listMacros[key]
+	}
+	macros["list_count"] = listMacros["count"]
+}
+
+// groupListData combines all entries with the same value of the group-by attribute.
+// Groups contain the group-by attribute, the number of entries as group_count, the worst state
+// and the sum of all attributes from group-sum. Other attributes are dropped.
+func (cd *CheckData) groupListData(listData []map[string]string) []map[string]string {
+	groups := []map[string]string{}
+	index := map[string]map[string]string{}
+	for _, entry := range listData {
+		name := entry[cd.groupBy]
+		weight := int64(1)
+		if w, ok := entry["_count"]; ok {
+			weight = convert.Int64(w)
+		}
+
+		group, ok := index[name]
+		if !ok {
+			group = map[string]string{
+				cd.groupBy:    name,
+				"group_count": "0",
+				"_state":      entry["_state"],
+			}
+			for _, key := range cd.groupSum {
+				group[key] = "0"
+			}
+			index[name] = group
+			groups = append(groups, group)
+		}
+
+		group["group_count"] = fmt.Sprintf("%d", convert.Int64(group["group_count"])+weight)
+		if convert.Int64(entry["_state"]) > convert.Int64(group["_state"]) {
+			group["_state"] = entry["_state"]
+		}
+		for _, key := range cd.groupSum {
+			num, ok := conditionAttributeNumber(entry, key)
+			if !ok {
+				continue
+			}
+			sum, _ := strconv.ParseFloat(group[key], 64)
+			group[key] = strconv.FormatFloat(sum+num, 'f', -1, 64)
+		}
+	}
+
+	return groups
+}
+
+// parseListOption parses the sort, limit, group-by and group-sum arguments
+func (cd *CheckData) parseListOption(keyword, argValue string) error {
+	switch keyword {
+	case "sort":
+		sortBy, err := ParseListSort(argValue)
+		if err != nil {
+			return err
+		}
+		cd.sortBy = sortBy
+	case "limit":
+		limit, err := convert.Int64E(argValue)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("limit must be a positive number: %s", argValue)
+		}
+		cd.limit = limit
+	case "group-by":
+		cd.groupBy = argValue
+	case "group-sum":
+		for _, key := range strings.Split(argValue, ",") {
+			key = strings.TrimSpace(key)
+			if key != "" {
+				cd.groupSum = append(cd.groupSum, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseListSort parses sort options, ex.: "rss desc, name"
+func ParseListSort(input string) (sortBy []ListSort, err error) {
+	for _, def := range strings.Split(input, ",") {
+		fields := strings.Fields(def)
+		switch {
+		case len(fields) == 1:
+			sortBy = append(sortBy, ListSort{attribute: fields[0]})
+		case len(fields) == 2 && strings.EqualFold(fields[1], "asc"):
+			sortBy = append(sortBy, ListSort{attribute: fields[0]})
+		case len(fields) == 2 && strings.EqualFold(fields[1], "desc"):
+			sortBy = append(sortBy, ListSort{attribute: fields[0], descending: true})
+		default:
+			return nil, fmt.Errorf("invalid sort option, expected: <attribute> [asc|desc]: %s", def)
+		}
+	}
+
+	return sortBy, nil
+}
+
+// sortListData sorts list entries by given attributes, numeric values are compared as numbers
+func sortListData(listData []map[string]string, sortBy []ListSort) {
+	sort.SliceStable(listData, func(i, j int) bool {
+		for _, def := range sortBy {
+			res := 0
+			num1, ok1 := conditionAttributeNumber(listData[i], def.attribute)
+			num2, ok2 := conditionAttributeNumber(listData[j], def.attribute)
+			if ok1 && ok2 {
+				res = cmp.Compare(num1, num2)
+			} else {
+				res = strings.Compare(listData[i][def.attribute], listData[j][def.attribute])
+			}
+			if res == 0 {
+				continue
+			}
+			if def.descending {
+				return res > 0
+			}
+
+			return res < 0
+		}
+
+		return false
+	})
 }
 
 func (cd *CheckData) buildListMacrosFromSingleEntry() map[string]string {
@@ -550,6 +717,7 @@ func (cd *CheckData) parseStateString(state string) int64 {
 func (cd *CheckData) parseArgs(args []string) (argList []Argument, err error) {
 	cd.rawArgs = args
 	cd.hasArgsSupplied = map[string]bool{}
+	cd.cacheTTL = -1
 	argList = make([]Argument, 0, len(args))
 	cd.expandArgDefinitions()
 	topSupplied := false
@@ -647,6 +815,21 @@ func (cd *CheckData) parseArgs(args []string) (argList []Argument, err error) {
 				return nil, fmt.Errorf("timeout parse error: %s", err2.Error())
 			}
 			cd.timeout = timeout
+		case "cache-ttl":
+			if cd.argsPassthrough {
+				// wrapped scripts and aliases use the configured cache ttl only
+				argList = append(argList, Argument{key: keyword, value: argValue})
+
+				continue
+			}
+			ttl, err2 := utils.ExpandDuration(argValue)
+			if err2 != nil {
+				return nil, fmt.Errorf("cache-ttl: %s", err2.Error())
+			}
+			if ttl < 0 {
+				return nil, fmt.Errorf("cache-ttl must not be negative")
+			}
+			cd.cacheTTL = ttl
 		case "perf-config":
 			perf, err2 := NewPerfConfig(argValue)
 			if err2 != nil {
@@ -657,6 +840,16 @@ func (cd *CheckData) parseArgs(args []string) (argList []Argument, err error) {
 			cd.perfSyntax = argValue
 		case "output":
 			cd.output = argValue
+		case "sort", "limit", "group-by", "group-sum":
+			if cd.argsPassthrough {
+				// wrapped scripts and aliases may use these arguments on their own
+				argList = append(argList, Argument{key: keyword, value: argValue})
+
+				continue
+			}
+			if err2 := cd.parseListOption(keyword, argValue); err2 != nil {
+				return nil, err2
+			}
 		default:
 			parsed, err2 := cd.parseAnyArg(argExpr, keyword, argValue)
 			switch {
@@ -689,6 +882,7 @@ func (cd *CheckData) parseArgs(args []string) (argList []Argument, err error) {
 	}
 
 	cd.applyConditionAlias()
+	cd.applyConditionTimezone()
 
 	return argList, nil
 }
@@ -892,6 +1086,15 @@ func (cd *CheckData) applyConditionAlias() {
 	cd.applyConditionAliasList(cd.okThreshold)
 }
 
+// applyConditionTimezone sets the check timezone for date/time functions in all conditions.
+func (cd *CheckData) applyConditionTimezone() {
+	for _, condList := range []ConditionList{cd.filter, cd.warnThreshold, cd.critThreshold, cd.okThreshold} {
+		for _, cond := range condList {
+			cond.setTimezone(cd.timezone)
+		}
+	}
+}
+
 // apply condition aliases to given conditions.
 func (cd *CheckData) applyConditionAliasList(condList ConditionList) {
 	for _, cond := range condList {
@@ -931,7 +1134,7 @@ func (cd *CheckData) hasThresholdCond(condList ConditionList, name string) bool
 			return cd.hasThresholdCond(cond.group, name)
 		}
 
-		if cond.keyword == name {
+		if cond.usesAttribute(name) {
 			return true
 		}
 	}
@@ -943,7 +1146,7 @@ func (cd *CheckData) hasThresholdCond(condList ConditionList, name string) bool
 // the name and not having a unit already
 func (cd *CheckData) SetDefaultThresholdUnit(defaultUnit string, names []string) {
 	setDefault := func(cond *Condition) bool {
-		if len(cond.group) == 0 && cond.unit == "" {
+		if len(cond.group) == 0 && cond.unit == "" && !cond.hasExpression() {
 			for _, name := range names {
 				if name == cond.keyword {
 					cond.unit = defaultUnit
@@ -959,6 +1162,27 @@ func (cd *CheckData) SetDefaultThresholdUnit(defaultUnit string, names []string)
 	cd.VisitAll(cd.filter, setDefault)
 }
 
+// addAggregateMacros calculates all aggregate functions used in thresholds, ex.: sum(rss) > 4GB, over the list data
+func (cd *CheckData) addAggregateMacros(macros map[string]string) {
+	addAggregates := func(cond *Condition) bool {
+		for _, expr := range []*ConditionExpression{cond.keywordExpr, cond.valueExpr} {
+			for _, aggr := range expr.aggregates() {
+				if _, ok := macros[aggr.aggregateKey]; ok {
+					continue
+				}
+				if val, ok := aggr.aggregateValue(cd.listData); ok {
+					macros[aggr.aggregateKey] = strconv.FormatFloat(val, 'f', -1, 64)
+				}
+			}
+		}
+
+		return true
+	}
+	cd.VisitAll(cd.warnThreshold, addAggregates)
+	cd.VisitAll(cd.critThreshold, addAggregates)
+	cd.VisitAll(cd.okThreshold, addAggregates)
+}
+
 // VisitAll calls callback recursively for each condition until callback returns false
 func (cd *CheckData) VisitAll(condList ConditionList, callback func(*Condition) bool) bool {
 	for _, cond := range condList {
@@ -998,25 +1222,40 @@ func (cd *CheckData) TransformThreshold(srcThreshold ConditionList, srcName, tar
 			return true
 		}
 
-		switch {
-		case srcUnit == "%":
-			pct := convert.Float64(cond.value)
-			val := pct / 100 * total
-			switch {
-			case strings.EqualFold(targetUnit, "b"):
-				cond.value = math.Round(val)
-			default:
-				cond.value = utils.ToPrecision(val, 3)
+		if srcUnit != "%" && targetUnit != "%" {
+			log.Errorf("unsupported src unit in threshold transition: %s", srcUnit)
+
+			return true
+		}
+
+		transform := func(value interface{}) interface{} {
+			if srcUnit == "%" {
+				pct := convert.Float64(value)
+				val := pct / 100 * total
+				if strings.EqualFold(targetUnit, "b") {
+					return math.Round(val)
+				}
+
+				return utils.ToPrecision(val, 3)
 			}
-			cond.unit = targetUnit
-		case targetUnit == "%":
-			val := convert.Float64(cond.value)
+
+			val := convert.Float64(value)
 			pct := (val * 100) / total
-			cond.value = utils.ToPrecision(pct, 2)
-			cond.unit = targetUnit
-		default:
-			log.Errorf("unsupported src unit in threshold transition: %s", srcUnit)
+
+			return utils.ToPrecision(pct, 2)
+		}
+
+		// ranges contain a list of lower and upper limit
+		if list, ok := cond.value.([]string); ok && (cond.operator == Between || cond.operator == NotBetween) {
+			transformed := make([]string, 0, len(list))
+			for _, val := range list {
+				transformed = append(transformed, convert.Num2String(transform(val)))
+			}
+			cond.value = transformed
+		} else {
+			cond.value = transform(cond.value)
 		}
+		cond.unit = targetUnit
 
 		return true
 	}
@@ -1065,6 +1304,14 @@ func (cd *CheckData) ExpandMetricMacros(srcThreshold ConditionList, data map[str
 		if cond.value == nil {
 			return true
 		}
+		if cond.valueExpr != nil {
+			if num, ok := cond.valueExpr.Evaluate(data); ok {
+				cond.value = num
+				cond.valueExpr = nil
+			}
+
+			return true
+		}
 		switch v := cond.value.(type) {
 		case string:
 			cond.value = ReplaceMacros(v, cd.timezone, data)
@@ -24,9 +24,14 @@ type CheckMetric struct {
 	Min           *float64
 	Max           *float64
 	PerfConfig    *PerfConfig // apply perf tweaks
+	Raw           string      // unparsed performance data, used unchanged unless perf tweaks are applied
 }
 
 func (m *CheckMetric) String() string {
+	if m.Raw != "" && m.PerfConfig == nil {
+		return m.Raw
+	}
+
 	var res bytes.Buffer
 
 	name := m.Name
@@ -122,6 +122,18 @@ func (cr *CheckResult) EscalateStatus(state int64) {
 	}
 }
 
+// Clone returns a copy of the result which can be finalized and modified without changing the original.
+func (cr *CheckResult) Clone() *CheckResult {
+	res := *cr
+	res.Metrics = make([]*CheckMetric, len(cr.Metrics))
+	for i, metric := range cr.Metrics {
+		m := *metric
+		res.Metrics[i] = &m
+	}
+
+	return &res
+}
+
 func (cr *CheckResult) BuildPluginOutput() []byte {
 	output := []byte(cr.Output)
 	if cr.Details != "" {
@@ -164,6 +176,16 @@ func (cr *CheckResult) ParsePerformanceDataFromOutputCond(command string, conf *
 
 // Parse performance data from the Output and put them into Metrics
 func (cr *CheckResult) ParsePerformanceDataFromOutput() {
+	cr.parsePerformanceDataFromOutput(false)
+}
+
+// ParsePerformanceDataFromOutputRaw extracts performance data like ParsePerformanceDataFromOutput
+// but keeps the raw perf data strings, so they will be printed unchanged.
+func (cr *CheckResult) ParsePerformanceDataFromOutputRaw() {
+	cr.parsePerformanceDataFromOutput(true)
+}
+
+func (cr *CheckResult) parsePerformanceDataFromOutput(keepRaw bool) {
 	if cr.Metrics == nil {
 		cr.Metrics = []*CheckMetric{}
 	}
@@ -183,7 +205,7 @@ func (cr *CheckResult) ParsePerformanceDataFromOutput() {
 		// remove perf data from normal output
 		trimmedOutput = append(trimmedOutput, strings.TrimSpace(line[:pipeIndex]))
 
-		metrics := cr.extractMetrics(rawPerfData)
+		metrics := cr.extractMetrics(rawPerfData, keepRaw)
 		cr.Metrics = append(cr.Metrics, metrics...)
 	}
 
@@ -207,7 +229,7 @@ func (cr *CheckResult) findPipeIndex(str string) int {
 	return -1
 }
 
-func (cr *CheckResult) extractMetrics(str string) []*CheckMetric {
+func (cr *CheckResult) extractMetrics(str string, keepRaw bool) []*CheckMetric {
 	metrics := []*CheckMetric{}
 
 	for _, raw := range utils.Tokenize(str) {
@@ -227,6 +249,9 @@ func (cr *CheckResult) extractMetrics(str string) []*CheckMetric {
 			continue
 		}
 		metric.Name = name
+		if keepRaw {
+			metric.Raw = raw
+		}
 
 		values := strings.SplitN(splitted[1], ";", 5)
 
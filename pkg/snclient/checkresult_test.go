@@ -1,6 +1,7 @@
 package snclient
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -147,3 +148,45 @@ func TestCheckResultNestedMacro(t *testing.T) {
 
 	assert.Equalf(t, `OK - 123 topLvl Macro`, string(result.BuildPluginOutput()), "plugin output")
 }
+
+func TestCheckResultListOptions(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"sort=rss desc", "limit=2"}, "CRITICAL - 2/4 critical(c) a"},
+		{[]string{"sort=name desc"}, "CRITICAL - 4/4 critical(c) d, b, a"},
+		{[]string{"sort=user, rss desc", "limit=3"}, "CRITICAL - 3/4 critical(c) a, b"},
+		{[]string{"group-by=user", "group-sum=rss", "detail-syntax=%(user):%(group_count):%(rss)"}, "CRITICAL - 2/4 critical(bob:2:350) alice:2:110"},
+		{[]string{"group-by=user", "group-sum=rss", "sort=group_count desc, rss desc", "limit=1", "detail-syntax=%(user):%(rss)"}, "CRITICAL - 1/4 critical(bob:350)"},
+		{[]string{"group-by=user", "detail-syntax=%(user):%(group_count):%(name):%(pid)"}, "CRITICAL - 2/4 critical(bob:2:%(name):%(pid)) alice:2:%(name):%(pid)"},
+	} {
+		check := &CheckData{
+			result:       &CheckResult{},
+			topSyntax:    "%(status) - %(list_count)/%(count) %(problem_list) %(ok_list)",
+			detailSyntax: "%(name)",
+		}
+		_, err := check.parseArgs(append([]string{"crit=rss > 200"}, test.args...))
+		require.NoErrorf(t, err, "parsed args: %v", test.args)
+		check.listData = []map[string]string{
+			{"name": "a", "user": "alice", "rss": "100", "pid": "1"},
+			{"name": "b", "user": "alice", "rss": "10", "pid": "2"},
+			{"name": "c", "user": "bob", "rss": "300", "pid": "3"},
+			{"name": "d", "user": "bob", "rss": "50", "pid": "4"},
+		}
+		result, err := check.Finalize()
+		require.NoErrorf(t, err, "Finalize worked")
+		assert.Equalf(t, test.expect, strings.TrimSpace(result.Output), "output with %v", test.args)
+	}
+
+	_, err := (&CheckData{}).parseArgs([]string{"sort=rss down"})
+	require.Errorf(t, err, "invalid sort order")
+	_, err = (&CheckData{}).parseArgs([]string{"limit=-1"})
+	require.Errorf(t, err, "invalid limit")
+
+	// passthrough checks keep list options as arguments
+	passthrough := &CheckData{argsPassthrough: true}
+	argList, err := passthrough.parseArgs([]string{"limit=abc", "sort=foo"})
+	require.NoErrorf(t, err, "passthrough arguments")
+	assert.Lenf(t, argList, 2, "arguments passed through")
+}
@@ -3,26 +3,33 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strings"
 	"syscall"
 
 	"github.com/consol-monitoring/snclient/pkg/convert"
 	"github.com/consol-monitoring/snclient/pkg/snclient"
-	"github.com/consol-monitoring/snclient/pkg/utils"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
 func init() {
+	algorithm := snclient.PasswordHashDefault
 	hashCmd := &cobra.Command{
 		Use:   "hash",
 		Short: "Hash password string",
 		Long: `Hash can be used to create hashed password strings.
 
+Supported algorithms are: ` + strings.Join(snclient.PasswordHashAlgorithms, ", ") + `
+The salted bcrypt and argon2id hashes should be preferred, sha256 is deprecated.
+
 Examples:
 
 # simply convert text to hash:
 snclient hash <password>
 
+# use argon2id instead of bcrypt:
+snclient hash --algorithm=argon2id <password>
+
 # ask password from user input and convert this:
 snclient hash
 `,
@@ -33,7 +40,11 @@ snclient hash
 			if len(args) > 0 {
 				input = args[0]
 			} else {
-				input = readPassword(cmd)
+				input = readPassword(cmd, "enter password to hash or hit ctrl+c to exit.")
+				if input != "" && readPassword(cmd, "enter password again to confirm.") != input {
+					fmt.Fprintf(cmd.OutOrStderr(), "passwords do not match\n")
+					os.Exit(3)
+				}
 			}
 
 			if input == "" {
@@ -41,20 +52,21 @@ snclient hash
 				os.Exit(3)
 			}
 
-			sum, err := utils.Sha256Sum(input)
+			sum, err := snclient.HashPassword(algorithm, input)
 			if err != nil {
-				fmt.Fprintf(cmd.OutOrStderr(), "calculating hash sum failed: %s", err.Error())
+				fmt.Fprintf(cmd.OutOrStderr(), "calculating hash sum failed: %s\n", err.Error())
 				os.Exit(3)
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), "hash sum: %s:%s\n", "SHA256", sum)
+			fmt.Fprintf(cmd.OutOrStdout(), "hash sum: %s\n", sum)
 			os.Exit(snclient.ExitCodeOK)
 		},
 	}
+	hashCmd.Flags().StringVarP(&algorithm, "algorithm", "a", algorithm, "hash algorithm, one of: "+strings.Join(snclient.PasswordHashAlgorithms, ", "))
 	rootCmd.AddCommand(hashCmd)
 }
 
-func readPassword(cmd *cobra.Command) string {
-	fmt.Fprintf(cmd.OutOrStdout(), "enter password to hash or hit ctrl+c to exit.\n")
+func readPassword(cmd *cobra.Command, prompt string) string {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", prompt)
 	stdinFD, err := convert.IntE(syscall.Stdin)
 	if err != nil {
 		fmt.Fprintf(cmd.OutOrStdout(), "failed to convert stdin to int: %s", err.Error())
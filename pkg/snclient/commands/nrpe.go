@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/nrpe"
+	"github.com/consol-monitoring/snclient/pkg/snclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// nrpeFlags contains the check_nrpe compatible command line options
+type nrpeFlags struct {
+	host       string
+	port       string
+	command    string
+	args       []string
+	timeout    int
+	noSSL      bool
+	v2Packets  bool
+	v3Packets  bool
+	adh        int
+	adhSet     bool
+	caFile     string
+	certFile   string
+	keyFile    string
+	unknownTOs bool
+	help       bool
+}
+
+func init() {
+	nrpeCmd := &cobra.Command{
+		Use:   "nrpe -H <host> [-c <command>] [-a <args>...]",
+		Short: "Run query against remote nrpe server",
+		Long: `Nrpe sends a query to a remote nrpe server and prints the result with
+plugin compatible exit code. It can be used as replacement for check_nrpe
+and supports the same options.
+
+Examples:
+
+# check version of remote agent
+snclient nrpe -H 192.168.1.10
+
+# run check_load with arguments
+snclient nrpe -H 192.168.1.10 -c check_load -a 'warn=load > 5' 'crit=load > 10'
+
+# connect to legacy nrpe server without certificates
+snclient nrpe -H 192.168.1.10 -d 2 -c check_users
+
+Anonymous diffie hellman (ADH) is only supported with TLS 1.2. It is never used
+as fallback if the server certificate is verified (-A) or a client certificate
+is used (-C).
+
+# use client certificates and verify server certificate
+snclient nrpe -H 192.168.1.10 -C client.crt -K client.key -A ca.crt -c check_cpu
+`,
+		DisableFlagParsing: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			agentFlags.Mode = snclient.ModeOneShot
+			flags, flagSet, err := parseNrpeFlags(args)
+			if err != nil {
+				fmt.Fprintf(cmd.OutOrStderr(), "UNKNOWN - %s\n", err.Error())
+				os.Exit(snclient.ExitCodeUnknown)
+			}
+			if flags.help || flags.host == "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\nUsage:\n  snclient %s\n\nFlags:\n%s", cmd.Long, cmd.Use, flagSet.FlagUsages())
+				os.Exit(snclient.ExitCodeUnknown)
+			}
+
+			os.Exit(nrpeRunQuery(cmd, flags))
+		},
+	}
+	rootCmd.AddCommand(nrpeCmd)
+}
+
+// parseNrpeFlags parses check_nrpe style options, everything after -a is used as arguments.
+func parseNrpeFlags(args []string) (*nrpeFlags, *pflag.FlagSet, error) {
+	flags := &nrpeFlags{}
+	flagSet := pflag.NewFlagSet("nrpe", pflag.ContinueOnError)
+	flagSet.SortFlags = false
+	flagSet.StringVarP(&flags.host, "host", "H", "", "The address of the host running the nrpe server")
+	flagSet.StringVarP(&flags.port, "port", "p", nrpe.DefaultPort, "The port on which the server is listening")
+	flagSet.StringVarP(&flags.command, "command", "c", "_NRPE_CHECK", "The name of the command that the remote server should run")
+	flagSet.BoolP("args", "a", false, "Arguments that should be passed to the command, must be the last option")
+	flagSet.IntVarP(&flags.timeout, "timeout", "t", int(nrpe.DefaultTimeout.Seconds()), "Number of seconds before connection times out")
+	flagSet.BoolVarP(&flags.unknownTOs, "unknown-timeout", "u", false, "Make connection problems return UNKNOWN instead of CRITICAL")
+	flagSet.BoolVarP(&flags.noSSL, "no-ssl", "n", false, "Do not use ssl")
+	flagSet.BoolVarP(&flags.v2Packets, "v2-packets-only", "2", false, "Only use version 2 packets, not version 4")
+	flagSet.BoolVarP(&flags.v3Packets, "v3-packets-only", "3", false, "Only use version 3 packets, not version 4")
+	flagSet.IntVarP(&flags.adh, "use-adh", "d", int(nrpe.ADHAllowed), "Anonymous Diffie Hellman use (TLS 1.2 only): 0 = don't use (default with -A or -C), 1 = allow, 2 = force")
+	flagSet.StringVarP(&flags.caFile, "ca-cert-file", "A", "", "The CA to verify the server certificate")
+	flagSet.StringVarP(&flags.certFile, "client-cert", "C", "", "The client certificate to use for ssl")
+	flagSet.StringVarP(&flags.keyFile, "key-file", "K", "", "The private key to use with the client certificate")
+	flagSet.BoolVarP(&flags.help, "help", "h", false, "Print help and exit")
+	// global verbose flags are moved in front of the subcommand, so accept them here
+	flagSet.CountVarP(&agentFlags.Verbose, "verbose", "v", "Increase loglevel")
+
+	// arguments may contain anything including dashes, so split them off first
+	for idx, arg := range args {
+		if arg == "-a" || arg == "--args" {
+			flags.args = args[idx+1:]
+			args = args[:idx]
+
+			break
+		}
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, flagSet, fmt.Errorf("nrpe: %s", err.Error())
+	}
+	if flagSet.NArg() > 0 {
+		return nil, flagSet, fmt.Errorf("nrpe: unexpected arguments: %v, use -a to pass arguments", flagSet.Args())
+	}
+	flags.adhSet = flagSet.Changed("use-adh")
+	if flags.v2Packets && flags.v3Packets {
+		return nil, flagSet, fmt.Errorf("nrpe: -2 and -3 cannot be used together")
+	}
+
+	return flags, flagSet, nil
+}
+
+func nrpeRunQuery(cmd *cobra.Command, flags *nrpeFlags) int {
+	client, err := nrpeBuildClient(flags)
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "UNKNOWN - %s\n", err.Error())
+
+		return snclient.ExitCodeUnknown
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.Timeout)
+	defer cancel()
+
+	res, err := client.Query(ctx, flags.command, flags.args)
+	if err != nil {
+		if flags.unknownTOs {
+			fmt.Fprintf(cmd.OutOrStdout(), "UNKNOWN - %s\n", err.Error())
+
+			return snclient.ExitCodeUnknown
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "CRITICAL - %s\n", err.Error())
+
+		return int(snclient.CheckExitCritical)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", res.Output)
+
+	return int(res.State)
+}
+
+func nrpeBuildClient(flags *nrpeFlags) (*nrpe.Client, error) {
+	client := nrpe.NewClient(net.JoinHostPort(flags.host, flags.port))
+	client.Timeout = time.Duration(flags.timeout) * time.Second
+	client.UseSSL = !flags.noSSL
+
+	switch {
+	case flags.v2Packets:
+		client.Version = nrpe.NrpeV2PacketVersion
+	case flags.v3Packets:
+		client.Version = nrpe.NrpeV3PacketVersion
+	}
+
+	// like check_nrpe, the server certificate is only verified if a ca is given
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: flags.caFile == "", //nolint:gosec // check_nrpe compatible, verification is enabled by -A
+	}
+	if flags.caFile != "" {
+		caPEM, err := os.ReadFile(flags.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading ca file failed: %s", err.Error())
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in ca file %s", flags.caFile)
+		}
+	}
+	if flags.certFile != "" || flags.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(flags.certFile, flags.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate failed: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	client.TLSConfig = tlsConfig
+
+	verify := flags.caFile != "" || flags.certFile != ""
+	switch {
+	case !flags.adhSet:
+		// adh is disabled by default when verifying the server or using client certificates
+		if verify {
+			client.ADH = nrpe.ADHDisabled
+		}
+	case flags.adh == 2 && verify:
+		return nil, fmt.Errorf("-d 2 cannot be used together with -A or -C, anonymous diffie hellman has no certificates")
+	case flags.adh == 0:
+		client.ADH = nrpe.ADHDisabled
+	case flags.adh == 1:
+		client.ADH = nrpe.ADHAllowed
+	case flags.adh == 2:
+		client.ADH = nrpe.ADHForced
+	default:
+		return nil, fmt.Errorf("invalid -d value %d, must be 0, 1 or 2", flags.adh)
+	}
+
+	return client, nil
+}
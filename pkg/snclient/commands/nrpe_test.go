@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/consol-monitoring/snclient/pkg/nrpe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNrpeFlags(t *testing.T) {
+	flags, _, err := parseNrpeFlags([]string{"-H", "localhost", "-p", "5667", "-2", "-n", "-c", "check_load", "-a", "warn=load > 5", "-c", "test"})
+	require.NoErrorf(t, err, "flags parsed")
+	assert.Equalf(t, "localhost", flags.host, "host")
+	assert.Equalf(t, "5667", flags.port, "port")
+	assert.Equalf(t, "check_load", flags.command, "command")
+	assert.Equalf(t, []string{"warn=load > 5", "-c", "test"}, flags.args, "args")
+
+	client, err := nrpeBuildClient(flags)
+	require.NoErrorf(t, err, "client created")
+	assert.Equalf(t, "localhost:5667", client.Address, "address")
+	assert.Equalf(t, uint16(nrpe.NrpeV2PacketVersion), client.Version, "version")
+	assert.Falsef(t, client.UseSSL, "ssl disabled")
+	assert.Equalf(t, nrpe.ADHAllowed, client.ADH, "adh allowed by default")
+
+	_, _, err = parseNrpeFlags([]string{"-H", "localhost", "-2", "-3"})
+	require.Errorf(t, err, "-2 and -3 are exclusive")
+
+	_, _, err = parseNrpeFlags([]string{"-H", "localhost", "check_load"})
+	require.Errorf(t, err, "arguments require -a")
+}
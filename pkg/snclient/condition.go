@@ -18,6 +18,7 @@ var (
 	reConditionValueUnit = regexp.MustCompile(`^(\-?\d+\.\d+|\-?\d+)\s*(\D+)$`)
 	reCuddleKeyword      = regexp.MustCompile(`^([A-Za-z_]+)([!=><~]+)(.*)$`)
 	reCuddleOperator     = regexp.MustCompile(`^([!=><~]+)(.*?)$`)
+	reConditionFunction  = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*\(`)
 )
 
 // Condition defines a condition as used in filters or thresholds
@@ -36,11 +37,18 @@ type Condition struct {
 	// if filter is a simple "none"
 	isNone bool
 
+	// negated condition, ex.: not (hour() between 2 and 4)
+	negate bool
+
 	// store initial string
 	original string
 
 	// reference to check attributes (used to expand by unit)
 	attr *[]CheckAttribute
+
+	// arithmetic expressions, ex.: used > size * 0.9 or rss / 1024 > 500
+	keywordExpr *ConditionExpression
+	valueExpr   *ConditionExpression
 }
 
 // Operator defines a filter operator.
@@ -72,6 +80,10 @@ const (
 	// Lists
 	InList    // in
 	NotInList // not in
+
+	// Ranges
+	Between    // between
+	NotBetween // not between
 )
 
 func OperatorParse(str string) (Operator, error) {
@@ -108,6 +120,10 @@ func OperatorParse(str string) (Operator, error) {
 		return InList, nil
 	case "not in":
 		return NotInList, nil
+	case "between":
+		return Between, nil
+	case "not between":
+		return NotBetween, nil
 	}
 
 	return 0, fmt.Errorf("unknown operator: %s", str)
@@ -147,6 +163,10 @@ func (o *Operator) String() string {
 		return ("in")
 	case NotInList:
 		return ("not in")
+	case Between:
+		return ("between")
+	case NotBetween:
+		return ("not between")
 	}
 
 	return ("unknown")
@@ -231,21 +251,43 @@ func (c *Condition) String() string {
 		return c.original
 	}
 
-	if len(c.group) > 0 {
+	str := ""
+	switch {
+	case len(c.group) > 0:
 		groups := []string{}
 		for _, g := range c.group {
 			groups = append(groups, g.String())
 		}
 
-		return " (" + strings.Join(groups, " "+c.groupOperator.String()+" ") + ") "
+		str = " (" + strings.Join(groups, " "+c.groupOperator.String()+" ") + ") "
+	case c.operator == Between, c.operator == NotBetween:
+		if list, ok := c.value.([]string); ok && len(list) == 2 {
+			str = fmt.Sprintf("%s %s %s%s and %s%s", c.keyword, c.operator.String(), list[0], c.unit, list[1], c.unit)
+		}
+	default:
+		str = fmt.Sprintf("%s %s %v%s", c.keyword, c.operator.String(), c.value, c.unit)
 	}
 
-	return fmt.Sprintf("%s %s %v%s", c.keyword, c.operator.String(), c.value, c.unit)
+	if c.negate {
+		return "not (" + strings.TrimSpace(str) + ")"
+	}
+
+	return str
 }
 
 // Match checks if given map matches current condition
 // returns either the result or not ok if the result cannot be determined because of none-existing values
 func (c *Condition) Match(data map[string]string) (res, ok bool) {
+	res, ok = c.match(data)
+	if c.negate && ok {
+		return !res, ok
+	}
+
+	return res, ok
+}
+
+// match checks the condition without negation
+func (c *Condition) match(data map[string]string) (res, ok bool) {
 	if c.isNone {
 		return false, true
 	}
@@ -314,7 +356,10 @@ func (c *Condition) matchSingle(data map[string]string) (res, ok bool) {
 	if !ok {
 		return false, false
 	}
-	condStr := fmt.Sprintf("%v", c.value)
+	condStr, ok := c.getCondValue(data)
+	if !ok {
+		return false, false
+	}
 	varNum, err1 := strconv.ParseFloat(varStr, 64)
 	condNum, err2 := strconv.ParseFloat(condStr, 64)
 	if c.keyword == "version" {
@@ -424,14 +469,48 @@ func (c *Condition) matchSingle(data map[string]string) (res, ok bool) {
 		}
 
 		return true, true
+	case Between, NotBetween:
+		low, high, err := c.rangeValues()
+		if err1 != nil || err != nil {
+			return c.operator == NotBetween, true
+		}
+		inRange := varNum >= low && varNum <= high
+
+		return inRange == (c.operator == Between), true
 	}
 
 	return false, true
 }
 
+// rangeValues returns lower and upper limit for between conditions
+func (c *Condition) rangeValues() (low, high float64, err error) {
+	list, ok := c.value.([]string)
+	if !ok || len(list) != 2 {
+		return 0, 0, fmt.Errorf("invalid range")
+	}
+	low, err = strconv.ParseFloat(list[0], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range: %s", err.Error())
+	}
+	high, err = strconv.ParseFloat(list[1], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range: %s", err.Error())
+	}
+
+	return low, high, nil
+}
+
 // compareEmpty returns if the current condition operator is successful for an none-existing value.
 // basically it returns false for positive comparisons and true for negative ones.
 func (c *Condition) compareEmpty() bool {
+	if c.negate {
+		return !c.compareEmptyOperator()
+	}
+
+	return c.compareEmptyOperator()
+}
+
+func (c *Condition) compareEmptyOperator() bool {
 	switch c.operator {
 	case Equal,
 		Contains,
@@ -440,7 +519,8 @@ func (c *Condition) compareEmpty() bool {
 		Greater,
 		RegexMatch,
 		RegexMatchNoCase,
-		InList:
+		InList,
+		Between:
 		return false
 	case Unequal,
 		ContainsNot,
@@ -449,7 +529,8 @@ func (c *Condition) compareEmpty() bool {
 		Lower,
 		RegexMatchNot,
 		RegexMatchNotNoCase,
-		NotInList:
+		NotInList,
+		NotBetween:
 		return true
 	}
 
@@ -461,6 +542,15 @@ func (c *Condition) compareEmpty() bool {
 // returns value from keyword unless found already
 func (c *Condition) getVarValue(data map[string]string) (varStr string, ok bool) {
 	switch {
+	case c.keywordExpr != nil:
+		num, ok := c.keywordExpr.Evaluate(data)
+
+		return strconv.FormatFloat(num, 'f', -1, 64), ok
+	case c.valueExpr != nil:
+		// expressions are always numeric, so use the raw number if available
+		if num, ok := conditionAttributeNumber(data, c.keyword); ok {
+			return strconv.FormatFloat(num, 'f', -1, 64), ok
+		}
 	case c.unit == "%":
 		varStr, ok = data[c.keyword+"_pct"]
 		if ok {
@@ -483,6 +573,70 @@ func (c *Condition) getVarValue(data map[string]string) (varStr string, ok bool)
 	return varStr, ok
 }
 
+// getCondValue returns the value to compare with, arithmetic expressions will be calculated with the given data
+func (c *Condition) getCondValue(data map[string]string) (condStr string, ok bool) {
+	if c.valueExpr == nil {
+		return fmt.Sprintf("%v", c.value), true
+	}
+
+	num, ok := c.valueExpr.Evaluate(data)
+	if ok {
+		return strconv.FormatFloat(num, 'f', -1, 64), true
+	}
+
+	// single words which are no attributes are compared as string
+	if c.valueExpr.attribute != "" {
+		return fmt.Sprintf("%v", c.value), true
+	}
+
+	return "", false
+}
+
+// hasExpression returns true if keyword or value is an arithmetic expression
+func (c *Condition) hasExpression() bool {
+	return c.keywordExpr != nil || c.valueExpr != nil
+}
+
+// usesAttribute returns true if this condition uses the given attribute as keyword or inside an expression
+func (c *Condition) usesAttribute(name string) bool {
+	if c.keyword == name {
+		return true
+	}
+	for _, expr := range []*ConditionExpression{c.keywordExpr, c.valueExpr} {
+		if expr != nil && slices.Contains(expr.Attributes(), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attributes returns all attributes used in this condition
+func (c *Condition) attributes() (attributes []string) {
+	for i := range c.group {
+		attributes = append(attributes, c.group[i].attributes()...)
+	}
+	if c.keyword != "" && c.keywordExpr == nil {
+		attributes = append(attributes, c.keyword)
+	}
+	for _, expr := range []*ConditionExpression{c.keywordExpr, c.valueExpr} {
+		if expr != nil {
+			attributes = append(attributes, expr.Attributes()...)
+		}
+	}
+
+	return attributes
+}
+
+// setTimezone sets the timezone used by date/time functions, ex.: today()
+func (c *Condition) setTimezone(timezone *time.Location) {
+	for i := range c.group {
+		c.group[i].setTimezone(timezone)
+	}
+	c.keywordExpr.setTimezone(timezone)
+	c.valueExpr.setTimezone(timezone)
+}
+
 // Clone returns a new copy of this condition
 func (c *Condition) Clone() *Condition {
 	clone := &Condition{
@@ -490,9 +644,12 @@ func (c *Condition) Clone() *Condition {
 		operator:      c.operator,
 		unit:          c.unit,
 		value:         c.value,
+		negate:        c.negate,
 		groupOperator: c.groupOperator,
 		group:         make(ConditionList, 0),
 		attr:          c.attr,
+		keywordExpr:   c.keywordExpr,
+		valueExpr:     c.valueExpr,
 	}
 
 	for i := range c.group {
@@ -533,8 +690,30 @@ func conditionAdd(token []string, attr *[]CheckAttribute) (cond *Condition, rema
 			groupOp = operator
 		}
 
+		// negated group, ex.: not (hour() between 2 and 4)
+		negate := false
+		switch {
+		case strings.EqualFold(token[0], "not") && len(token) > 1 && strings.HasPrefix(token[1], "("):
+			negate = true
+			token = token[1:]
+		case len(token[0]) > 4 && strings.EqualFold(token[0][:4], "not("):
+			negate = true
+			token[0] = token[0][3:]
+		}
+
 		// check if we start with a bracket
 		if strings.HasPrefix(token[0], "(") {
+			// brackets might be part of an arithmetic expression, ex.: (rss + vms) / 1024 > 500
+			if !negate {
+				exprCond, rem, err := conditionNext(slices.Clone(token), attr)
+				if err == nil && exprCond.keywordExpr != nil {
+					token = rem
+					conditions = append(conditions, exprCond)
+
+					continue
+				}
+			}
+
 			token[0] = strings.TrimPrefix(token[0], "(")
 			// advance token if it was only the bracket itself
 			if token[0] == "" {
@@ -552,6 +731,7 @@ func conditionAdd(token []string, attr *[]CheckAttribute) (cond *Condition, rema
 			}
 
 			token = rem[1:] // excluding closing bracket
+			condsub.negate = condsub.negate != negate
 			conditions = append(conditions, condsub)
 
 			continue
@@ -580,6 +760,8 @@ func conditionAdd(token []string, attr *[]CheckAttribute) (cond *Condition, rema
 
 // parse and remove next keyword/op/value combo from token list
 func conditionNext(token []string, attr *[]CheckAttribute) (cond *Condition, remaining []string, err error) {
+	// keyword might be an arithmetic expression
+	token = conditionJoinExpression(token)
 	keyword := token[0]
 	token = token[1:]
 
@@ -606,6 +788,7 @@ func conditionNext(token []string, attr *[]CheckAttribute) (cond *Condition, rem
 	}
 
 	// trim quotes from keyword
+	quoted := strings.HasPrefix(keyword, "'") || strings.HasPrefix(keyword, `"`)
 	keyword, err = utils.TrimQuotes(keyword)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%s", err.Error())
@@ -616,6 +799,13 @@ func conditionNext(token []string, attr *[]CheckAttribute) (cond *Condition, rem
 		attr:    attr,
 	}
 
+	if !quoted && strings.ContainsAny(keyword, conditionArithmeticChars+"()") {
+		cond.keywordExpr, err = NewConditionExpression(keyword, attr, cond.expandExpressionNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	token = conditionFixTokenOperator(token)
 
 	operator, err := OperatorParse(token[0])
@@ -626,6 +816,10 @@ func conditionNext(token []string, attr *[]CheckAttribute) (cond *Condition, rem
 	token = token[1:]
 	cond.operator = operator
 
+	if cond.keywordExpr != nil && !cond.isNumericOperator() && operator != Equal && operator != Unequal && operator != Between && operator != NotBetween {
+		return nil, nil, fmt.Errorf("arithmetic expressions can only be compared by =, !=, <, <=, >, >= or between: %s", query)
+	}
+
 	if len(token) == 0 {
 		return nil, nil, fmt.Errorf("expected value after '%s'", query)
 	}
@@ -640,6 +834,20 @@ func conditionNext(token []string, attr *[]CheckAttribute) (cond *Condition, rem
 
 // parse and remove condition value
 func (c *Condition) conditionValue(token []string) (remaining []string, err error) {
+	// check for ranges like: between 2 and 4
+	if c.operator == Between || c.operator == NotBetween {
+		return c.conditionRangeValue(token)
+	}
+
+	// check for arithmetic expressions like: size * 0.9
+	rem, ok, err := c.conditionExpressionValue(token)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return rem, nil
+	}
+
 	// check for list values like ("a", "b",...)
 	if strings.HasPrefix(token[0], "(") {
 		rem, err2 := c.conditionListValue(token)
@@ -669,6 +877,86 @@ func (c *Condition) conditionValue(token []string) (remaining []string, err erro
 	return token, nil
 }
 
+// parse and remove range value, ex.: 2 and 4
+func (c *Condition) conditionRangeValue(token []string) (remaining []string, err error) {
+	if len(token) < 3 || !strings.EqualFold(token[1], "and") {
+		return nil, fmt.Errorf("expected range after '%s', ex.: between 1 and 5", c.operator.String())
+	}
+
+	high := token[2]
+	remaining = token[3:]
+
+	// check for trailing closing brackets
+	for strings.HasSuffix(high, ")") {
+		high = strings.TrimSuffix(high, ")")
+		remaining = append([]string{")"}, remaining...)
+	}
+
+	values := []string{}
+	for _, str := range []string{token[0], high} {
+		expand := &Condition{keyword: c.keyword, attr: c.attr}
+		err = expand.conditionSetValue(str, true)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, fmt.Sprintf("%v", expand.value))
+		c.unit = expand.unit
+	}
+	c.value = values
+
+	if _, _, err = c.rangeValues(); err != nil {
+		return nil, fmt.Errorf("%s: %s", err.Error(), strings.Join(token[:3], " "))
+	}
+
+	return remaining, nil
+}
+
+// parse and remove arithmetic expression value, returns ok if the value is an expression
+func (c *Condition) conditionExpressionValue(token []string) (remaining []string, ok bool, err error) {
+	if !c.isNumericOperator() && c.operator != Equal && c.operator != Unequal {
+		return nil, false, nil
+	}
+	if strings.HasPrefix(token[0], "'") || strings.HasPrefix(token[0], `"`) {
+		return nil, false, nil
+	}
+
+	// consume all token belonging to the expression
+	str := token[0]
+	num := 1
+	for num < len(token) && conditionExpressionContinues(str, token[num]) {
+		str += " " + token[num]
+		num++
+	}
+	remaining = token[num:]
+
+	// trailing closing brackets belong to the surrounding group
+	for strings.HasSuffix(str, ")") && conditionBracketDepth(str) < 0 {
+		str = strings.TrimSuffix(str, ")")
+		remaining = append([]string{")"}, remaining...)
+	}
+
+	// values with whitespace or compared to expressions must be expressions,
+	// single token values are only used if they reference other attributes or functions, ex.: received > sent
+	required := num > 1 || c.keywordExpr != nil
+	function := reConditionFunction.MatchString(str)
+	if !required && !function && (!c.isNumericOperator() || !strings.ContainsAny(str, conditionArithmeticChars) && !reConditionAttribute.MatchString(str)) {
+		return nil, false, nil
+	}
+
+	expr, err := NewConditionExpression(str, c.attr, c.expandExpressionNumber)
+	switch {
+	case err != nil && (required || function):
+		return nil, false, err
+	case err != nil, !required && expr.isConstant():
+		return nil, false, nil
+	}
+
+	c.valueExpr = expr
+	c.value = str
+
+	return remaining, true, nil
+}
+
 // parse and remove condition list value
 func (c *Condition) conditionListValue(token []string) (remaining []string, err error) {
 	token[0] = strings.TrimPrefix(token[0], "(")
@@ -824,6 +1112,50 @@ func (c *Condition) expandUnitByType(str string) error {
 	return nil
 }
 
+// expandExpressionNumber expands units of numbers used in arithmetic expressions.
+// Units are taken from the keyword or the first attribute of the keyword expression.
+func (c *Condition) expandExpressionNumber(str string) (float64, error) {
+	keyword := c.keyword
+	if c.keywordExpr != nil {
+		keyword = ""
+		if attributes := c.keywordExpr.Attributes(); len(attributes) > 0 {
+			keyword = attributes[0]
+		}
+	}
+
+	expand := &Condition{keyword: keyword, attr: c.attr}
+	switch c.getUnit(keyword) { //nolint:exhaustive // only dates need special handling
+	case UDate, UTimestamp:
+		// timestamps in expressions are calculated with relative durations
+		expand.attr = &[]CheckAttribute{{name: keyword, unit: UDuration}}
+	}
+
+	err := expand.expandUnitByType(str)
+	if err != nil {
+		return 0, err
+	}
+	if expand.unit == "%" {
+		return 0, fmt.Errorf("percent values are not supported in expressions: %s", str)
+	}
+
+	num, err := strconv.ParseFloat(fmt.Sprintf("%v", expand.value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number in expression: %s", str)
+	}
+
+	return num, nil
+}
+
+// isNumericOperator returns true for operators comparing numbers
+func (c *Condition) isNumericOperator() bool {
+	switch c.operator { //nolint:exhaustive // only numeric operators are relevant
+	case Lower, LowerEqual, Greater, GreaterEqual:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *Condition) expandUnitByName(str string) error {
 	// best effort unit expansion
 	switch strings.ToLower(c.unit) {
@@ -846,6 +1178,36 @@ func (c *Condition) expandUnitByName(str string) error {
 	return nil
 }
 
+// conditionJoinExpression joins token from arithmetic expressions on the left side into a single token, ex.: rss / 1024 > 500
+func conditionJoinExpression(token []string) []string {
+	if strings.HasPrefix(token[0], "'") || strings.HasPrefix(token[0], `"`) {
+		return token
+	}
+
+	expr := token[0]
+	num := 1
+	for num < len(token) && conditionIndexOutside(expr, conditionCompareChars) < 0 && conditionExpressionContinues(expr, token[num]) {
+		expr += " " + token[num]
+		num++
+	}
+	remaining := token[num:]
+
+	// split cuddled operator, ex.: rss/1024>500
+	if idx := conditionIndexOutside(expr, conditionCompareChars); idx > 0 {
+		if !strings.ContainsAny(expr[:idx], conditionArithmeticChars+"()") {
+			return token
+		}
+		remaining = append([]string{expr[idx:]}, remaining...)
+		expr = strings.TrimSpace(expr[:idx])
+	}
+
+	if num == 1 && expr == token[0] {
+		return token
+	}
+
+	return append([]string{expr}, remaining...)
+}
+
 // fix some cornercases in token lists, ex.:
 func conditionFixTokenOperator(token []string) []string {
 	if len(token) >= 2 {
@@ -900,13 +1262,16 @@ func ThresholdString(name []string, conditions ConditionList, numberFormat func(
 	filtered := make(ConditionList, 0)
 	var group GroupOperator
 	for num := range conditions {
-		if slices.Contains(name, conditions[num].keyword) {
+		if conditions[num].negate {
+			continue
+		}
+		if slices.Contains(name, conditions[num].keyword) && !conditions[num].hasExpression() {
 			filtered = append(filtered, conditions[num])
 		}
 		if conditions[num].groupOperator == GroupOr {
 			group = conditions[num].groupOperator
 			for i := range conditions[num].group {
-				if slices.Contains(name, conditions[num].group[i].keyword) {
+				if slices.Contains(name, conditions[num].group[i].keyword) && !conditions[num].group[i].hasExpression() {
 					filtered = append(filtered, conditions[num].group[i])
 				}
 			}
@@ -914,7 +1279,7 @@ func ThresholdString(name []string, conditions ConditionList, numberFormat func(
 		if conditions[num].groupOperator == GroupAnd {
 			group = conditions[num].groupOperator
 			for i := range conditions[num].group {
-				if slices.Contains(name, conditions[num].group[i].keyword) {
+				if slices.Contains(name, conditions[num].group[i].keyword) && !conditions[num].group[i].hasExpression() {
 					filtered = append(filtered, conditions[num].group[i])
 				}
 			}
@@ -938,6 +1303,16 @@ func ThresholdString(name []string, conditions ConditionList, numberFormat func(
 			}
 
 			return numberFormat(nextNumber) + ":"
+		case Between, NotBetween:
+			low, high, err := filtered[0].rangeValues()
+			if err != nil {
+				return ""
+			}
+			if filtered[0].operator == Between {
+				return fmt.Sprintf("@%s:%s", numberFormat(low), numberFormat(high))
+			}
+
+			return fmt.Sprintf("%s:%s", numberFormat(low), numberFormat(high))
 		default:
 			return numberFormat(filtered[0].value)
 		}
@@ -0,0 +1,567 @@
+package snclient
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// conditionArithmeticChars contains the operators which can be used in arithmetic expressions
+	conditionArithmeticChars = "+-*/"
+
+	// conditionCompareChars contains all characters used by comparison operators
+	conditionCompareChars = "!=><~"
+)
+
+var reConditionAttribute = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// conditionFunctions contains all functions which can be used in conditions, ex.: written < today()
+var conditionFunctions = map[string]func(now time.Time) float64{
+	// current unix timestamp
+	"now": func(now time.Time) float64 {
+		return float64(now.Unix())
+	},
+	// unix timestamp of today 00:00
+	"today": func(now time.Time) float64 {
+		year, month, day := now.Date()
+
+		return float64(time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Unix())
+	},
+	// day of week, 0 = sunday, 6 = saturday
+	"weekday": func(now time.Time) float64 {
+		return float64(now.Weekday())
+	},
+	// hour of the day from 0 to 23
+	"hour": func(now time.Time) float64 {
+		return float64(now.Hour())
+	},
+}
+
+// conditionAggregates contains all aggregate functions which can be used in thresholds, ex.: sum(rss) > 4GB
+var conditionAggregates = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"min":   true,
+	"max":   true,
+	"avg":   true,
+}
+
+// ConditionExpression is an arithmetic expression used in conditions, ex.: size * 0.9
+type ConditionExpression struct {
+	// operator is one of + - * / or 0 for values
+	operator byte
+	left     *ConditionExpression
+	right    *ConditionExpression
+
+	// values are either references to other attributes, functions or numbers
+	attribute string
+	function  string
+	literal   string
+	number    float64
+
+	// aggregate functions over all list entries, ex.: count(state = 'stopped') or sum(rss)
+	// the calculated value is stored with the original text as key in the final macros.
+	aggregate     string
+	aggregateKey  string
+	aggregateCond *Condition
+	aggregateExpr *ConditionExpression
+
+	// timezone used by date/time functions, defaults to local time
+	timezone *time.Location
+}
+
+// NewConditionExpression parses given arithmetic expression.
+// Numbers may have units which will be expanded by the expand function.
+func NewConditionExpression(input string, attr *[]CheckAttribute, expand func(string) (float64, error)) (*ConditionExpression, error) {
+	token, err := conditionExpressionTokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &conditionExpressionParser{token: token, attr: attr, expand: expand}
+	expr, err := parser.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if len(parser.token) > 0 {
+		return nil, fmt.Errorf("unexpected '%s' in expression: %s", parser.token[0], input)
+	}
+
+	err = expr.expand(expand)
+	if err != nil {
+		return nil, err
+	}
+
+	return expr, nil
+}
+
+// Evaluate calculates the expression with values from given data.
+// It returns not ok if attributes are missing or not numeric.
+func (e *ConditionExpression) Evaluate(data map[string]string) (res float64, ok bool) {
+	switch {
+	case e.attribute != "":
+		return conditionAttributeNumber(data, e.attribute)
+	case e.function != "":
+		now := time.Now()
+		if e.timezone != nil {
+			now = now.In(e.timezone)
+		}
+
+		return conditionFunctions[e.function](now), true
+	case e.aggregate != "":
+		str, ok := data[e.aggregateKey]
+		if !ok {
+			return 0, false
+		}
+		num, err := strconv.ParseFloat(str, 64)
+
+		return num, err == nil
+	case e.operator == 0:
+		return e.number, true
+	}
+
+	left, ok := e.left.Evaluate(data)
+	if !ok {
+		return 0, false
+	}
+	right, ok := e.right.Evaluate(data)
+	if !ok {
+		return 0, false
+	}
+
+	switch e.operator {
+	case '+':
+		return left + right, true
+	case '-':
+		return left - right, true
+	case '*':
+		return left * right, true
+	case '/':
+		if right == 0 {
+			return 0, false
+		}
+
+		return left / right, true
+	}
+
+	return 0, false
+}
+
+// Attributes returns all attributes referenced in this expression.
+func (e *ConditionExpression) Attributes() (attributes []string) {
+	switch {
+	case e.attribute != "":
+		return []string{e.attribute}
+	case e.aggregate == "count":
+		attributes = append(attributes, "count")
+		if e.aggregateCond != nil {
+			attributes = append(attributes, e.aggregateCond.attributes()...)
+		}
+
+		return attributes
+	case e.aggregateExpr != nil:
+		return e.aggregateExpr.Attributes()
+	}
+	if e.left != nil {
+		attributes = append(attributes, e.left.Attributes()...)
+	}
+	if e.right != nil {
+		attributes = append(attributes, e.right.Attributes()...)
+	}
+
+	return attributes
+}
+
+// setTimezone sets the timezone used by date/time functions in this expression.
+func (e *ConditionExpression) setTimezone(timezone *time.Location) {
+	if e == nil {
+		return
+	}
+	e.timezone = timezone
+	e.left.setTimezone(timezone)
+	e.right.setTimezone(timezone)
+	e.aggregateExpr.setTimezone(timezone)
+	if e.aggregateCond != nil {
+		e.aggregateCond.setTimezone(timezone)
+	}
+}
+
+// isConstant returns true if the expression does not use any attributes or functions
+func (e *ConditionExpression) isConstant() bool {
+	if e.attribute != "" || e.function != "" || e.aggregate != "" {
+		return false
+	}
+	if e.left != nil && !e.left.isConstant() {
+		return false
+	}
+	if e.right != nil && !e.right.isConstant() {
+		return false
+	}
+
+	return true
+}
+
+// aggregates returns all aggregate functions used in this expression
+func (e *ConditionExpression) aggregates() (aggregates []*ConditionExpression) {
+	if e == nil {
+		return nil
+	}
+	if e.aggregate != "" {
+		return []*ConditionExpression{e}
+	}
+	aggregates = append(aggregates, e.left.aggregates()...)
+	aggregates = append(aggregates, e.right.aggregates()...)
+
+	return aggregates
+}
+
+// aggregateValue calculates the aggregate function over all list entries.
+// It returns not ok if there are no values to calculate min/max/avg from.
+func (e *ConditionExpression) aggregateValue(list []map[string]string) (res float64, ok bool) {
+	values := []float64{}
+	for _, entry := range list {
+		if entry["_skip"] == "1" {
+			continue
+		}
+
+		switch e.aggregate {
+		case "count":
+			if e.aggregateCond != nil {
+				if res, ok := e.aggregateCond.Match(entry); !res || !ok {
+					continue
+				}
+			}
+			weight := float64(1)
+			if w, ok := entry["_count"]; ok {
+				weight, _ = strconv.ParseFloat(w, 64)
+			}
+			values = append(values, weight)
+		default:
+			if num, ok := e.aggregateExpr.Evaluate(entry); ok {
+				values = append(values, num)
+			}
+		}
+	}
+
+	switch e.aggregate {
+	case "count", "sum":
+		sum := float64(0)
+		for _, val := range values {
+			sum += val
+		}
+
+		return sum, true
+	}
+
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	switch e.aggregate {
+	case "min":
+		return slices.Min(values), true
+	case "max":
+		return slices.Max(values), true
+	case "avg":
+		sum := float64(0)
+		for _, val := range values {
+			sum += val
+		}
+
+		return sum / float64(len(values)), true
+	}
+
+	return 0, false
+}
+
+// expand converts all literal numbers including their units
+func (e *ConditionExpression) expand(expand func(string) (float64, error)) error {
+	if e.literal != "" {
+		num, err := expand(e.literal)
+		if err != nil {
+			return err
+		}
+		e.number = num
+
+		return nil
+	}
+	if e.left != nil {
+		if err := e.left.expand(expand); err != nil {
+			return err
+		}
+	}
+	if e.right != nil {
+		if err := e.right.expand(expand); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// conditionAttributeNumber returns the numeric value of given attribute.
+// tries attribute_value and attribute_bytes first, since plain attributes might be human readable
+func conditionAttributeNumber(data map[string]string, name string) (num float64, ok bool) {
+	for _, key := range []string{name + "_value", name + "_bytes", name} {
+		str, ok := data[key]
+		if !ok {
+			continue
+		}
+		num, err := strconv.ParseFloat(str, 64)
+		if err == nil {
+			return num, true
+		}
+	}
+
+	return 0, false
+}
+
+// conditionExpressionContinues returns true if next token is part of the same arithmetic expression
+func conditionExpressionContinues(expr, next string) bool {
+	if expr == "" || next == "" {
+		return false
+	}
+	if conditionBracketDepth(expr) > 0 {
+		return true
+	}
+	if strings.ContainsAny(expr[len(expr)-1:], conditionArithmeticChars) {
+		return true
+	}
+
+	return strings.ContainsAny(next[0:1], conditionArithmeticChars)
+}
+
+// conditionBracketDepth returns the number of unclosed brackets, quoted text is ignored
+func conditionBracketDepth(str string) int {
+	depth := 0
+	quote := rune(0)
+	for _, char := range str {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		}
+	}
+
+	return depth
+}
+
+// conditionIndexOutside returns the index of the first character from chars which is not inside brackets or quotes or -1
+func conditionIndexOutside(str, chars string) int {
+	depth := 0
+	quote := rune(0)
+	for idx, char := range str {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		case depth == 0 && strings.ContainsRune(chars, char):
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// conditionExpressionTokenize splits expression into numbers (including units), attributes, functions, operators and brackets
+func conditionExpressionTokenize(input string) (token []string, err error) {
+	runes := []rune(input)
+	for idx := 0; idx < len(runes); {
+		char := runes[idx]
+		switch {
+		case unicode.IsSpace(char):
+			idx++
+		case strings.ContainsRune(conditionArithmeticChars+"()", char):
+			token = append(token, string(char))
+			idx++
+		case unicode.IsDigit(char) || char == '.':
+			start := idx
+			for idx < len(runes) && (unicode.IsDigit(runes[idx]) || runes[idx] == '.') {
+				idx++
+			}
+			// optional unit
+			for idx < len(runes) && (unicode.IsLetter(runes[idx]) || runes[idx] == '%') {
+				idx++
+			}
+			token = append(token, string(runes[start:idx]))
+		case unicode.IsLetter(char) || char == '_':
+			start := idx
+			for idx < len(runes) && (unicode.IsLetter(runes[idx]) || unicode.IsDigit(runes[idx]) || runes[idx] == '_') {
+				idx++
+			}
+			switch {
+			case idx+1 < len(runes) && runes[idx] == '(' && runes[idx+1] == ')':
+				// function calls, ex.: now()
+				idx += 2
+			case idx < len(runes) && runes[idx] == '(' && conditionAggregates[strings.ToLower(string(runes[start:idx]))]:
+				// aggregate functions with arguments, ex.: count(state = 'stopped')
+				end := idx + 1
+				for end <= len(runes) && conditionBracketDepth(string(runes[idx:end])) > 0 {
+					end++
+				}
+				if end > len(runes) {
+					return nil, fmt.Errorf("missing closing bracket in expression: %s", input)
+				}
+				idx = end
+			}
+			token = append(token, string(runes[start:idx]))
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' in expression: %s", char, input)
+		}
+	}
+
+	if len(token) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	return token, nil
+}
+
+// conditionExpressionParser is a recursive descent parser for arithmetic expressions
+type conditionExpressionParser struct {
+	token  []string
+	attr   *[]CheckAttribute
+	expand func(string) (float64, error)
+}
+
+func (p *conditionExpressionParser) next() string {
+	if len(p.token) == 0 {
+		return ""
+	}
+
+	return p.token[0]
+}
+
+// parseSum parses additions and subtractions
+func (p *conditionExpressionParser) parseSum() (*ConditionExpression, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.next() == "+" || p.next() == "-" {
+		operator := p.next()[0]
+		p.token = p.token[1:]
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &ConditionExpression{operator: operator, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseProduct parses multiplications and divisions
+func (p *conditionExpressionParser) parseProduct() (*ConditionExpression, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.next() == "*" || p.next() == "/" {
+		operator := p.next()[0]
+		p.token = p.token[1:]
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &ConditionExpression{operator: operator, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseFactor parses numbers, attributes, negations and brackets
+func (p *conditionExpressionParser) parseFactor() (*ConditionExpression, error) {
+	token := p.next()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.token = p.token[1:]
+
+	switch {
+	case token == "-":
+		factor, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+
+		return &ConditionExpression{operator: '-', left: &ConditionExpression{}, right: factor}, nil
+	case token == "(":
+		expr, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("expected closing bracket in expression")
+		}
+		p.token = p.token[1:]
+
+		return expr, nil
+	case strings.HasSuffix(token, ")"):
+		return p.parseFunction(token)
+	case reConditionAttribute.MatchString(token):
+		return &ConditionExpression{attribute: token}, nil
+	case token[0] >= '0' && token[0] <= '9' || token[0] == '.':
+		return &ConditionExpression{literal: token}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected '%s' in expression", token)
+}
+
+// parseFunction parses functions and aggregates, ex.: now() or sum(rss)
+func (p *conditionExpressionParser) parseFunction(token string) (*ConditionExpression, error) {
+	idx := strings.Index(token, "(")
+	name := strings.ToLower(token[:idx])
+	args := strings.TrimSpace(token[idx+1 : len(token)-1])
+
+	if !conditionAggregates[name] {
+		if _, ok := conditionFunctions[name]; !ok || args != "" {
+			return nil, fmt.Errorf("unknown function: %s", token)
+		}
+
+		return &ConditionExpression{function: name}, nil
+	}
+
+	expr := &ConditionExpression{aggregate: name, aggregateKey: token}
+	switch {
+	case name == "count" && args == "":
+		// count all entries
+	case name == "count":
+		cond, err := NewCondition(args, p.attr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", token, err.Error())
+		}
+		expr.aggregateCond = cond
+	case args == "":
+		return nil, fmt.Errorf("missing argument for %s", token)
+	default:
+		aggregateExpr, err := NewConditionExpression(args, p.attr, p.expand)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", token, err.Error())
+		}
+		expr.aggregateExpr = aggregateExpr
+	}
+
+	return expr, nil
+}
@@ -1,7 +1,9 @@
 package snclient
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/consol-monitoring/snclient/pkg/convert"
 	"github.com/stretchr/testify/assert"
@@ -181,6 +183,9 @@ func TestConditionThresholdString(t *testing.T) {
 		{"test < 3 or test > 5", "test", "3:5"},
 		{"test > 10 and test < 20", "test", "@10:20"},
 		{"test < 20 and test > 10", "test", "@10:20"},
+		{"test between 10 and 20", "test", "@10:20"},
+		{"test not between 10 and 20", "test", "10:20"},
+		{"not (test > 5)", "test", ""},
 	} {
 		threshold, err := NewCondition(check.threshold, nil)
 		require.NoErrorf(t, err, "parsed threshold")
@@ -233,6 +238,167 @@ func TestConditionAlias(t *testing.T) {
 	assert.Containsf(t, check.filter[0].String(), `state = running`, "filter condition replaced")
 }
 
+func TestConditionExpression(t *testing.T) {
+	attributes := []CheckAttribute{
+		{name: "used", unit: UByte},
+		{name: "size", unit: UByte},
+		{name: "modified", unit: UTimestamp},
+	}
+	for _, check := range []struct {
+		threshold     string
+		data          map[string]string
+		expect        bool
+		deterministic bool
+	}{
+		{"used > size * 0.9", map[string]string{"used": "95", "size": "100"}, true, true},
+		{"used > size * 0.9", map[string]string{"used": "85", "size": "100"}, false, true},
+		{"used>size*0.9", map[string]string{"used": "95", "size": "100"}, true, true},
+		{"used > size*0.9", map[string]string{"used": "95 B", "used_bytes": "95", "size": "100 B", "size_bytes": "100"}, true, true},
+		{"used > size - 1KB", map[string]string{"used": "9500", "size": "10000"}, true, true},
+		{"used > size - 1KB", map[string]string{"used": "8500", "size": "10000"}, false, true},
+		{"used > (size - 100) * 0.5", map[string]string{"used": "46", "size": "190"}, true, true},
+		{"used > size * 0.9", map[string]string{"used": "95"}, false, false},
+		{"rss / 1024 > 500", map[string]string{"rss": "600000"}, true, true},
+		{"rss / 1024 > 500", map[string]string{"rss": "400000"}, false, true},
+		{"rss/1024>500", map[string]string{"rss": "600000"}, true, true},
+		{"rss/1024 > 500", map[string]string{"rss": "600000"}, true, true},
+		{"(rss + vms) / 2 > 500", map[string]string{"rss": "600", "vms": "600"}, true, true},
+		{"(rss + vms) / 2 > 500 and rss > 100", map[string]string{"rss": "600", "vms": "600"}, true, true},
+		{"((rss + vms) / 2 > 500 or rss > 1000)", map[string]string{"rss": "100", "vms": "600"}, false, true},
+		{"rss / 0 > 500", map[string]string{"rss": "600000"}, false, false},
+		{"received > sent", map[string]string{"received": "10", "sent": "5"}, true, true},
+		{"received > sent", map[string]string{"received": "5", "sent": "10"}, false, true},
+		{"received <= sent", map[string]string{"received": "5", "sent": "10"}, true, true},
+		{"received = sent + 5", map[string]string{"received": "15", "sent": "10"}, true, true},
+		{"received != sent * 2", map[string]string{"received": "20", "sent": "10"}, false, true},
+		{"received + sent >= 20", map[string]string{"received": "15", "sent": "10"}, true, true},
+		{"(received > sent)", map[string]string{"received": "15", "sent": "10"}, true, true},
+		{"state = running", map[string]string{"state": "running", "running": "1"}, true, true},
+		{"modified > created + 1h", map[string]string{"modified": "10000", "created": "5000"}, true, true},
+		{"modified > created + 2h", map[string]string{"modified": "10000", "created": "5000"}, false, true},
+		{"test > -5", map[string]string{"test": "1"}, true, true},
+		{"test < 5-3", map[string]string{"test": "1"}, false, true},
+	} {
+		threshold, err := NewCondition(check.threshold, &attributes)
+		require.NoErrorf(t, err, "parsed threshold: %s", check.threshold)
+		res, ok := threshold.Match(check.data)
+		assert.Equalf(t, check.expect, res, "Compare(%s) -> (%v) %v", check.threshold, check.data, check.expect)
+		assert.Equalf(t, check.deterministic, ok, "Compare(%s) -> determined: (%v) %v", check.threshold, check.data, check.deterministic)
+	}
+}
+
+func TestConditionExpressionErrors(t *testing.T) {
+	for _, threshold := range []string{
+		"used > size * ",
+		"used > (size * 2",
+		"used > size * 90%",
+		"rss / 1024 like 5",
+		"rss / > 5",
+		"used > size ** 2",
+	} {
+		cond, err := NewCondition(threshold, nil)
+		require.Errorf(t, err, "ConditionParse(%s) should error", threshold)
+		assert.Nilf(t, cond, "ConditionParse(%s) errors should not return condition", threshold)
+	}
+}
+
+func TestConditionExpressionThreshold(t *testing.T) {
+	cond, err := NewCondition("used > size * 0.9", nil)
+	require.NoError(t, err)
+	assert.Equalf(t, "", ThresholdString([]string{"used"}, ConditionList{cond}, convert.Num2String), "no static threshold for expressions")
+
+	check := &CheckData{warnThreshold: ConditionList{cond}}
+	assert.Truef(t, check.HasThreshold("size"), "attributes in expressions are used")
+
+	expanded := check.ExpandMetricMacros(check.warnThreshold, map[string]string{"size": "100"})
+	assert.Equalf(t, "90", ThresholdString([]string{"used"}, expanded, convert.Num2String), "expression expanded with entry data")
+	assert.Equalf(t, "size * 0.9", cond.value, "original condition unchanged")
+}
+
+func TestConditionNegateAndFunctions(t *testing.T) {
+	now := time.Now()
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Unix()
+	hour := fmt.Sprintf("%d", now.Hour())
+	nextHour := fmt.Sprintf("%d", now.Hour()+1)
+
+	attributes := []CheckAttribute{{name: "written", unit: UDate}}
+	for _, check := range []struct {
+		threshold     string
+		data          map[string]string
+		expect        bool
+		deterministic bool
+	}{
+		{"not (test > 5)", map[string]string{"test": "3"}, true, true},
+		{"not (test > 5)", map[string]string{"test": "7"}, false, true},
+		{"not(test > 5)", map[string]string{"test": "7"}, false, true},
+		{"NOT ( test > 5 )", map[string]string{"test": "3"}, true, true},
+		{"not (not (test > 5))", map[string]string{"test": "7"}, true, true},
+		{"not (test > 5 or test < 1)", map[string]string{"test": "3"}, true, true},
+		{"count > 0 and not (state = 'ok' or state = 'pending')", map[string]string{"count": "1", "state": "failed"}, true, true},
+		{"count > 0 and not (state = 'ok' or state = 'pending')", map[string]string{"count": "1", "state": "ok"}, false, true},
+		{"not (unknown > 5)", map[string]string{"test": "3"}, false, false},
+		{"test between 2 and 4", map[string]string{"test": "3"}, true, true},
+		{"test between 2 and 4", map[string]string{"test": "4"}, true, true},
+		{"test between 2 and 4", map[string]string{"test": "5"}, false, true},
+		{"test not between 2 and 4", map[string]string{"test": "5"}, true, true},
+		{"test between 1KB and 2KB", map[string]string{"test": "1500"}, true, true},
+		{"(test between 2 and 4)", map[string]string{"test": "3"}, true, true},
+		{"written < today()", map[string]string{"written": fmt.Sprintf("%d", today-10)}, true, true},
+		{"written < today()", map[string]string{"written": fmt.Sprintf("%d", today+10)}, false, true},
+		{"written < now() - 1h", map[string]string{"written": fmt.Sprintf("%d", now.Unix()-7200)}, true, true},
+		{"written < now() - 1h", map[string]string{"written": fmt.Sprintf("%d", now.Unix()-60)}, false, true},
+		{"weekday() = " + fmt.Sprintf("%d", now.Weekday()), map[string]string{}, true, true},
+		{"hour() = " + hour, map[string]string{}, true, true},
+		{"hour()>=" + hour, map[string]string{}, true, true},
+		{"hour() between " + hour + " and " + nextHour, map[string]string{}, true, true},
+		{"count > 0 and not (hour() between " + hour + " and " + nextHour + ")", map[string]string{"count": "1"}, false, true},
+		{"count > 0 and not (hour() not between " + hour + " and " + nextHour + ")", map[string]string{"count": "1"}, true, true},
+	} {
+		threshold, err := NewCondition(check.threshold, &attributes)
+		require.NoErrorf(t, err, "parsed threshold: %s", check.threshold)
+		res, ok := threshold.Match(check.data)
+		assert.Equalf(t, check.expect, res, "Compare(%s) -> (%v) %v", check.threshold, check.data, check.expect)
+		assert.Equalf(t, check.deterministic, ok, "Compare(%s) -> determined: (%v) %v", check.threshold, check.data, check.deterministic)
+	}
+
+	for _, threshold := range []string{
+		"test between 2",
+		"test between 2 or 4",
+		"test between a and 4",
+		"written < yesterday()",
+		"not test > 5",
+	} {
+		cond, err := NewCondition(threshold, &attributes)
+		require.Errorf(t, err, "ConditionParse(%s) should error", threshold)
+		assert.Nilf(t, cond, "ConditionParse(%s) errors should not return condition", threshold)
+	}
+
+	// date/time functions use the timezone of the check
+	zone := time.FixedZone("test", 13*3600+1800)
+	zoneNow := time.Now().In(zone)
+	cond, err := NewCondition(fmt.Sprintf("count > 0 and not (hour() != %d)", zoneNow.Hour()), &attributes)
+	require.NoError(t, err)
+	cond.setTimezone(zone)
+	res, ok := cond.Match(map[string]string{"count": "1"})
+	assert.Truef(t, res && ok, "hour() uses timezone")
+	zoneYear, zoneMonth, zoneDay := zoneNow.Date()
+	zoneToday := time.Date(zoneYear, zoneMonth, zoneDay, 0, 0, 0, 0, zone).Unix()
+	cond, err = NewCondition("written >= today()", &attributes)
+	require.NoError(t, err)
+	cond.setTimezone(zone)
+	res, ok = cond.Match(map[string]string{"written": fmt.Sprintf("%d", zoneToday)})
+	assert.Truef(t, res && ok, "today() uses timezone")
+	res, ok = cond.Match(map[string]string{"written": fmt.Sprintf("%d", zoneToday-1)})
+	assert.Truef(t, !res && ok, "today() uses timezone")
+
+	cond, err = NewCondition("not (test > 5)", nil)
+	require.NoError(t, err)
+	assert.Truef(t, cond.MatchAnyOrEmpty([]map[string]string{{"other": "1"}}), "negated empty compare")
+	clone := cond.Clone()
+	assert.Equalf(t, "not (test > 5)", clone.String(), "negated string")
+}
+
 func TestConditionStrOp(t *testing.T) {
 	input := "'blah' like str(Blah)"
 	output := replaceStrOp(input)
@@ -258,3 +424,59 @@ func TestConditionStrOp(t *testing.T) {
 	output = replaceStrOp(input)
 	assert.Equal(t, input, output)
 }
+
+func TestConditionAggregates(t *testing.T) {
+	listData := []map[string]string{
+		{"name": "a", "state": "stopped", "rss": "3000000000", "cpu": "20", "age": "100"},
+		{"name": "b", "state": "stopped", "rss": "2000000000", "cpu": "90", "age": "90000"},
+		{"name": "c", "state": "running", "rss": "1000000", "cpu": "70", "age": "200"},
+		{"name": "d", "state": "stopped", "rss": "1000000", "cpu": "10", "age": "300", "_skip": "1"},
+	}
+	attributes := []CheckAttribute{{name: "rss", unit: UByte}, {name: "age", unit: UDuration}}
+	for _, check := range []struct {
+		threshold string
+		expect    bool
+	}{
+		{"count(state = 'stopped') > 1", true},
+		{"count(state = 'stopped') > 2", false},
+		{"count(state='stopped')>1", true},
+		{"count(state = 'stopped' or state = 'running') = 3", true},
+		{"count() = 3", true},
+		{"count(state = 'zombie') = 0", true},
+		{"sum(rss) > 4GB", true},
+		{"sum(rss) > 6GB", false},
+		{"sum(rss / 1024) > 4000000", true},
+		{"max(age) > 1d", true},
+		{"min(age) < 2m", true},
+		{"avg(cpu) = 60", true},
+		{"avg(cpu) > 50 and count(state = 'running') > 0", true},
+		{"not (avg(cpu) > 50)", false},
+		{"max(cpu) > min(cpu) * 4", true},
+		{"sum(rss) > 2 * max(rss)", false},
+	} {
+		threshold, err := NewCondition(check.threshold, &attributes)
+		require.NoErrorf(t, err, "parsed threshold: %s", check.threshold)
+
+		cd := &CheckData{listData: listData, warnThreshold: ConditionList{threshold}}
+		macros := map[string]string{}
+		cd.addAggregateMacros(macros)
+		res, ok := threshold.Match(macros)
+		assert.Truef(t, ok, "Compare(%s) -> determined", check.threshold)
+		assert.Equalf(t, check.expect, res, "Compare(%s) -> %v (%v)", check.threshold, check.expect, macros)
+
+		// aggregates cannot be calculated from single entries
+		_, ok = threshold.Match(listData[0])
+		assert.Falsef(t, ok, "Compare(%s) -> not determined on single entry", check.threshold)
+	}
+
+	for _, threshold := range []string{
+		"count(state = ) > 1",
+		"sum() > 1",
+		"sum(rss > 1",
+		"median(rss) > 1",
+	} {
+		cond, err := NewCondition(threshold, nil)
+		require.Errorf(t, err, "ConditionParse(%s) should error", threshold)
+		assert.Nilf(t, cond, "ConditionParse(%s) errors should not return condition", threshold)
+	}
+}
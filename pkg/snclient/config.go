@@ -46,6 +46,7 @@ var DefaultConfig = map[string]ConfigData{
 		"CheckExternalScripts": "enabled",
 		"CheckDisk":            "enabled",
 		"CheckWMI":             "disabled",
+		"CheckRemote":          "disabled",
 		"NRPEServer":           "disabled",
 		"WEBServer":            "enabled",
 		"PrometheusServer":     "disabled",
@@ -54,6 +55,15 @@ var DefaultConfig = map[string]ConfigData{
 	"/settings/default": {
 		"nasty characters": DefaultNastyCharacters,
 	},
+	"/settings/cache": {
+		"cache ttl":     "0",
+		"max cache ttl": "5m",
+	},
+	"/settings/limits": {
+		"max concurrent checks":             "0",
+		"max concurrent checks per command": "0",
+		"queue timeout":                     "30s",
+	},
 	"/settings/updates": {
 		"channel": "stable",
 	},
@@ -88,6 +98,8 @@ var DefaultListenTCPConfig = ConfigData{
 	"certificate key":     "${certificate-path}/server.key",
 	"timeout":             "30",
 	"use ssl":             "0",
+	"rate limit":          "0",
+	"rate limit burst":    "0",
 }
 
 var DefaultListenHTTPConfig = ConfigData{
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"time"
@@ -37,8 +38,24 @@ func (snc *Agent) httpClient(options *HTTPClientOptions) *http.Client {
 }
 
 func (snc *Agent) httpDo(ctx context.Context, options *HTTPClientOptions, method, url string, header map[string]string) (*http.Response, error) {
+	resp, err := snc.httpDoBody(ctx, options, method, url, header, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("http fetch failed %s: %s", url, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// httpDoBody sends a request with given body and returns the response regardless of its status code
+func (snc *Agent) httpDoBody(ctx context.Context, options *HTTPClientOptions, method, url string, header map[string]string, body io.Reader) (*http.Response, error) {
 	client := snc.httpClient(options)
-	req, err := http.NewRequestWithContext(ctx, method, url, http.NoBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %s", err.Error())
 	}
@@ -59,10 +76,6 @@ func (snc *Agent) httpDo(ctx context.Context, options *HTTPClientOptions, method
 		return nil, fmt.Errorf("http fetch failed %s: %s", url, err.Error())
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http fetch failed %s: %s", url, resp.Status)
-	}
-
 	return resp, nil
 }
 
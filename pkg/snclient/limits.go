@@ -0,0 +1,249 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// remove rate limit buckets of clients which have not been seen for this duration
+	rateLimitBucketExpire = 10 * time.Minute
+
+	// cleanup rate limit buckets after this number of requests
+	rateLimitCleanupInterval = 1000
+)
+
+var (
+	promCheckQueueLength = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "snclient_check_queue_length",
+			Help: "number of checks waiting for a free execution slot",
+		})
+
+	promCheckLimitExceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "snclient_check_limit_exceeded_total",
+			Help: "total number of requests rejected by the concurrency or rate limits",
+		},
+		[]string{"reason"})
+)
+
+// CheckLimiter limits the number of concurrently running checks globally and per command.
+type CheckLimiter struct {
+	maxGlobal     int64
+	maxPerCommand int64
+	commandLimits map[string]int64
+	queueTimeout  time.Duration
+
+	global   chan struct{}
+	lock     sync.Mutex
+	commands map[string]chan struct{}
+}
+
+// NewCheckLimiter creates a new limiter from the /settings/limits section.
+func NewCheckLimiter(conf *Config) (*CheckLimiter, error) {
+	section := conf.Section("/settings/limits")
+	limiter := &CheckLimiter{
+		commandLimits: map[string]int64{},
+		commands:      map[string]chan struct{}{},
+	}
+
+	var err error
+	limiter.maxGlobal, _, err = section.GetInt("max concurrent checks")
+	if err != nil {
+		return nil, fmt.Errorf("max concurrent checks: %s", err.Error())
+	}
+
+	limiter.maxPerCommand, _, err = section.GetInt("max concurrent checks per command")
+	if err != nil {
+		return nil, fmt.Errorf("max concurrent checks per command: %s", err.Error())
+	}
+
+	queueTimeout, _, err := section.GetDuration("queue timeout")
+	if err != nil {
+		return nil, fmt.Errorf("queue timeout: %s", err.Error())
+	}
+	limiter.queueTimeout = time.Duration(queueTimeout * float64(time.Second))
+
+	commands := conf.Section("/settings/limits/commands")
+	for _, command := range commands.Keys() {
+		limit, _, err := commands.GetInt(command)
+		if err != nil {
+			return nil, fmt.Errorf("/settings/limits/commands: %s: %s", command, err.Error())
+		}
+		limiter.commandLimits[command] = limit
+	}
+
+	if limiter.maxGlobal > 0 {
+		limiter.global = make(chan struct{}, limiter.maxGlobal)
+	}
+
+	return limiter, nil
+}
+
+// Acquire waits for a free slot for given command. The returned function must be called once the check is finished.
+func (cl *CheckLimiter) Acquire(ctx context.Context, command string) (release func(), err error) {
+	if cl == nil {
+		return func() {}, nil
+	}
+
+	slots := []chan struct{}{}
+	if cmdSlot := cl.commandSlot(command); cmdSlot != nil {
+		slots = append(slots, cmdSlot)
+	}
+	if cl.global != nil {
+		slots = append(slots, cl.global)
+	}
+
+	if len(slots) == 0 {
+		return func() {}, nil
+	}
+
+	releaseAll := func(acquired []chan struct{}) {
+		for _, slot := range acquired {
+			<-slot
+		}
+	}
+
+	timer := time.NewTimer(cl.queueTimeout)
+	defer timer.Stop()
+
+	acquired := make([]chan struct{}, 0, len(slots))
+	for _, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+			acquired = append(acquired, slot)
+
+			continue
+		default:
+		}
+
+		// no free slot, wait in queue
+		promCheckQueueLength.Inc()
+		select {
+		case slot <- struct{}{}:
+			promCheckQueueLength.Dec()
+			acquired = append(acquired, slot)
+		case <-timer.C:
+			promCheckQueueLength.Dec()
+			releaseAll(acquired)
+			promCheckLimitExceeded.WithLabelValues("queue_timeout").Inc()
+
+			return nil, fmt.Errorf("too many concurrent checks, no free slot for %s within %s", command, cl.queueTimeout)
+		case <-ctx.Done():
+			promCheckQueueLength.Dec()
+			releaseAll(acquired)
+
+			return nil, fmt.Errorf("waiting for a free slot for %s: %s", command, ctx.Err().Error())
+		}
+	}
+
+	return func() { releaseAll(acquired) }, nil
+}
+
+// commandSlot returns the semaphore channel for the command or nil if the command is not limited.
+func (cl *CheckLimiter) commandSlot(command string) chan struct{} {
+	limit, ok := cl.commandLimits[command]
+	if !ok {
+		limit = cl.maxPerCommand
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+
+	slot, ok := cl.commands[command]
+	if !ok {
+		slot = make(chan struct{}, limit)
+		cl.commands[command] = slot
+	}
+
+	return slot
+}
+
+// RateLimiter limits the number of requests per remote address using a token bucket for each client.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	lock     sync.Mutex
+	buckets  map[string]*rateLimitBucket
+	requests int
+}
+
+type rateLimitBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a new limiter from the rate limit options of a listener.
+func NewRateLimiter(conf *ConfigSection) (*RateLimiter, error) {
+	rate, _, err := conf.GetInt("rate limit")
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %s", err.Error())
+	}
+
+	burst, _, err := conf.GetInt("rate limit burst")
+	if err != nil {
+		return nil, fmt.Errorf("rate limit burst: %s", err.Error())
+	}
+	burst = max(burst, rate)
+
+	return &RateLimiter{
+		rate:    float64(rate),
+		burst:   float64(burst),
+		buckets: map[string]*rateLimitBucket{},
+	}, nil
+}
+
+// Allow returns true if the client with given remote address has not exceeded its rate limit.
+func (rl *RateLimiter) Allow(remoteAddr string) bool {
+	if rl == nil || rl.rate <= 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	now := time.Now()
+
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.requests++
+	if rl.requests%rateLimitCleanupInterval == 0 {
+		for addr, bucket := range rl.buckets {
+			if now.Sub(bucket.lastSeen) > rateLimitBucketExpire {
+				delete(rl.buckets, addr)
+			}
+		}
+	}
+
+	bucket, ok := rl.buckets[host]
+	if !ok {
+		bucket = &rateLimitBucket{tokens: rl.burst}
+		rl.buckets[host] = bucket
+	} else {
+		bucket.tokens = min(rl.burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*rl.rate)
+	}
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		promCheckLimitExceeded.WithLabelValues("rate_limit").Inc()
+		log.Warnf("rate limit exceeded for %s -> 429", host)
+
+		return false
+	}
+	bucket.tokens--
+
+	return true
+}
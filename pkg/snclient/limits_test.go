@@ -0,0 +1,93 @@
+package snclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLimiter(t *testing.T) {
+	cfg := NewConfig(true)
+	require.NoErrorf(t, cfg.ParseINI(`
+[/settings/limits]
+max concurrent checks = 3
+queue timeout = 200ms
+
+[/settings/limits/commands]
+check_files = 1
+`, "test.ini", nil), "config parsed")
+
+	limiter, err := NewCheckLimiter(cfg)
+	require.NoErrorf(t, err, "limiter created")
+
+	release1, err := limiter.Acquire(context.TODO(), "check_files")
+	require.NoErrorf(t, err, "first check_files")
+
+	_, err = limiter.Acquire(context.TODO(), "check_files")
+	require.Errorf(t, err, "second check_files exceeds command limit")
+	assert.Containsf(t, err.Error(), "too many concurrent checks", "error message")
+
+	release2, err := limiter.Acquire(context.TODO(), "check_cpu")
+	require.NoErrorf(t, err, "other command is not limited per command")
+	release3, err := limiter.Acquire(context.TODO(), "check_cpu")
+	require.NoErrorf(t, err, "other command is not limited per command")
+
+	_, err = limiter.Acquire(context.TODO(), "check_memory")
+	require.Errorf(t, err, "global limit reached")
+
+	// queued check starts once a slot is free
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		release, err := limiter.Acquire(context.TODO(), "check_files")
+		assert.NoErrorf(t, err, "queued check_files got slot")
+		if err == nil {
+			release()
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	release1()
+	wg.Wait()
+
+	release2()
+	release3()
+}
+
+func TestRateLimit(t *testing.T) {
+	config := `
+[/modules]
+WEBServer = enabled
+
+[/settings/WEB/server]
+port = 45666
+use ssl = false
+password = test
+rate limit = 1
+rate limit burst = 2
+`
+	snc := StartTestAgent(t, config)
+
+	request := func() int {
+		t.Helper()
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, "http://127.0.0.1:45666/api/v1/inventory", http.NoBody)
+		require.NoErrorf(t, err, "request created")
+		req.Header.Set("password", "test")
+		res, err := snc.httpClient(&HTTPClientOptions{reqTimeout: DefaultSocketTimeout}).Do(req)
+		require.NoErrorf(t, err, "request sent")
+		res.Body.Close()
+
+		return res.StatusCode
+	}
+
+	assert.Equalf(t, http.StatusOK, request(), "first request")
+	assert.Equalf(t, http.StatusOK, request(), "burst request")
+	assert.Equalf(t, http.StatusTooManyRequests, request(), "rate limit exceeded")
+
+	StopTestAgent(t, snc)
+}
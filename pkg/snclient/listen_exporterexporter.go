@@ -92,6 +92,9 @@ func (l *HandlerExporterExporter) Init(snc *Agent, conf *ConfigSection, _ *Confi
 	if password, ok := conf.GetString("password"); ok {
 		l.password = password
 	}
+	if err := checkPasswordConfig(conf, l.password); err != nil {
+		return err
+	}
 
 	listener, err := SharedWebListener(snc, conf, l, runSet)
 	if err != nil {
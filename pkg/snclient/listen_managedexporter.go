@@ -103,6 +103,9 @@ func (l *HandlerManagedExporter) Init(snc *Agent, conf *ConfigSection, _ *Config
 	if password, ok := conf.GetString("password"); ok {
 		l.password = password
 	}
+	if err := checkPasswordConfig(conf, l.password); err != nil {
+		return err
+	}
 
 	listener, err := SharedWebListener(snc, conf, l, runSet)
 	if err != nil {
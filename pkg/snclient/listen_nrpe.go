@@ -2,6 +2,8 @@ package snclient
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
 
 	"github.com/consol-monitoring/snclient/pkg/convert"
@@ -31,6 +33,7 @@ type HandlerNRPE struct {
 	noCopy       noCopy
 	snc          *Agent
 	conf         *ConfigSection
+	user         *AccessUser
 	listener     *Listener
 	allowedHosts *AllowedHostConfig
 }
@@ -42,9 +45,21 @@ func NewHandlerNRPE() Module {
 	return &HandlerNRPE{}
 }
 
-func (l *HandlerNRPE) Init(snc *Agent, conf *ConfigSection, _ *Config, _ *AgentRunSet) error {
+func (l *HandlerNRPE) Init(snc *Agent, conf *ConfigSection, cfg *Config, _ *AgentRunSet) error {
 	l.snc = snc
 	l.conf = conf
+
+	// nrpe has no credentials, so all queries run with the permissions of the configured user
+	if name, ok := conf.GetString("user"); ok && name != "" {
+		users, err := NewAccessUserList(cfg, nil)
+		if err != nil {
+			return err
+		}
+		l.user = users.Get(name)
+		if l.user == nil {
+			return fmt.Errorf("unknown user: %s (no section /settings/users/%s)", name, name)
+		}
+	}
 	listener, err := NewListener(snc, conf, l)
 	if err != nil {
 		return err
@@ -111,12 +126,30 @@ func (l *HandlerNRPE) ServeTCP(snc *Agent, con net.Conn) {
 		return
 	}
 
+	if !l.listener.rateLimiter.Allow(con.RemoteAddr().String()) {
+		response := nrpe.BuildPacket(request.Version(), nrpe.NrpeResponsePacket, uint16(CheckExitUnknown), []byte("UNKNOWN - rate limit exceeded"))
+		if err := response.Write(con); err != nil {
+			log.Errorf("nrpe write response error: %s", err.Error())
+		}
+
+		return
+	}
+
 	if cmd == "_NRPE_CHECK" {
 		// version check
 		cmd = "check_snclient_version"
 		args = []string{}
 	}
-	statusResult := snc.RunCheckWithContext(context.TODO(), cmd, args, 0, l.conf)
+	var tlsState *tls.ConnectionState
+	if tlsCon, ok := con.(*tls.Conn); ok {
+		state := tlsCon.ConnectionState()
+		tlsState = &state
+	}
+	ctx := contextWithAuditRequest(context.TODO(), l.Type(), con.RemoteAddr().String(), tlsState)
+	if l.user != nil {
+		ctx = contextWithAccessUser(ctx, l.user)
+	}
+	statusResult := snc.RunCheckWithContext(ctx, cmd, args, 0, l.conf)
 
 	output := statusResult.BuildPluginOutput()
 	state, err2 := convert.UInt16E(statusResult.State)
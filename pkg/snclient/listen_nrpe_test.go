@@ -1,6 +1,7 @@
 package snclient
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
@@ -45,3 +46,39 @@ use ssl = false
 
 	StopTestAgent(t, snc)
 }
+
+func TestNRPEClient(t *testing.T) {
+	config := `
+[/modules]
+NRPEServer = enabled
+
+[/settings/NRPE/server]
+port = 45669
+allow arguments = true
+use ssl = false
+`
+	snc := StartTestAgent(t, config)
+
+	client := nrpe.NewClient("127.0.0.1:45669")
+	client.UseSSL = false
+	for _, version := range []uint16{nrpe.NrpeV2PacketVersion, nrpe.NrpeV4PacketVersion} {
+		client.Version = version
+		res, err := client.Query(context.TODO(), "check_dummy", []string{"1", "client test"})
+		require.NoErrorf(t, err, "query v%d", version)
+		assert.Equalf(t, uint16(1), res.State, "state v%d", version)
+		assert.Equalf(t, "client test", res.Output, "output v%d", version)
+		assert.Equalf(t, version, res.Version, "response version v%d", version)
+	}
+
+	// deprecated v3 requests are not answered
+	client.Version = nrpe.NrpeV3PacketVersion
+	_, err := client.Query(context.TODO(), "check_dummy", []string{"1", "client test"})
+	require.Errorf(t, err, "v3 query is rejected")
+
+	sslClient := nrpe.NewClient("127.0.0.1:45669")
+	sslClient.Timeout = time.Second
+	res, err := sslClient.Query(context.TODO(), "_NRPE_CHECK", nil)
+	require.Errorf(t, err, "ssl query to plain server fails: %v", res)
+
+	StopTestAgent(t, snc)
+}
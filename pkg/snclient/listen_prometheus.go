@@ -65,6 +65,10 @@ var (
 		promHTTPDuration,
 		promTCPRequestsTotal,
 		promTCPDuration,
+		promCheckCollector,
+		promCheckQueueLength,
+		promCheckLimitExceeded,
+		promCheckCache,
 	}
 )
 
@@ -126,13 +130,19 @@ func (l *HandlerPrometheus) Stop() {
 	l.listener.Stop()
 }
 
-func (l *HandlerPrometheus) Init(snc *Agent, conf *ConfigSection, _ *Config, runSet *AgentRunSet) error {
+func (l *HandlerPrometheus) Init(snc *Agent, conf *ConfigSection, cfg *Config, runSet *AgentRunSet) error {
 	l.snc = snc
 	l.password = DefaultPassword
 	if password, ok := conf.GetString("password"); ok {
 		l.password = password
 	}
+	if err := checkPasswordConfig(conf, l.password); err != nil {
+		return err
+	}
 	registerMetrics()
+	if err := promCheckCollector.SetChecks(snc, cfg.Section("/settings/Prometheus/checks")); err != nil {
+		return err
+	}
 	if Revision != "" {
 		promInfoCount.WithLabelValues(VERSION+"."+Revision, Build, runtime.GOOS).Set(1)
 	} else {
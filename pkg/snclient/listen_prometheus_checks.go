@@ -0,0 +1,175 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/consol-monitoring/snclient/pkg/convert"
+	"github.com/consol-monitoring/snclient/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	promCheckMetricDesc = prometheus.NewDesc(
+		"snclient_check_metric",
+		"performance data value of configured checks",
+		[]string{"check", "label", "unit"}, nil,
+	)
+
+	promCheckWarningDesc = prometheus.NewDesc(
+		"snclient_check_metric_warning",
+		"warning threshold of configured checks",
+		[]string{"check", "label", "unit"}, nil,
+	)
+
+	promCheckCriticalDesc = prometheus.NewDesc(
+		"snclient_check_metric_critical",
+		"critical threshold of configured checks",
+		[]string{"check", "label", "unit"}, nil,
+	)
+
+	promCheckStateDesc = prometheus.NewDesc(
+		"snclient_check_state",
+		"state of configured checks (0 - ok, 1 - warning, 2 - critical, 3 - unknown)",
+		[]string{"check"}, nil,
+	)
+
+	promCheckCollector = &CheckMetricsCollector{}
+)
+
+// CheckMetricsCollector runs the configured checks on each scrape and exports their metrics.
+type CheckMetricsCollector struct {
+	mutex  sync.RWMutex
+	snc    *Agent
+	checks []exportCheck
+}
+
+// exportCheck is a single check whose metrics will be exported
+type exportCheck struct {
+	name    string
+	command string
+	args    []string
+}
+
+// parseExportChecks returns the list of checks from a config section.
+// The key is used as name, the value is the command including arguments.
+// An empty value uses the key as command.
+func parseExportChecks(section *ConfigSection) ([]exportCheck, error) {
+	checks := []exportCheck{}
+	for name := range section.data {
+		command, _, _ := section.GetStringRaw(name)
+		if strings.TrimSpace(command) == "" {
+			command = name
+		}
+		args := utils.Tokenize(command)
+		args, err := utils.TrimQuotesList(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse check %s: %s", name, err.Error())
+		}
+		checks = append(checks, exportCheck{
+			name:    name,
+			command: args[0],
+			args:    args[1:],
+		})
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	return checks, nil
+}
+
+// exportThreshold returns the threshold as number if it is a simple threshold.
+func exportThreshold(metric *CheckMetric, thresholdStr *string, threshold ConditionList) (float64, bool) {
+	str := metric.ThresholdString(threshold)
+	if thresholdStr != nil {
+		str = *thresholdStr
+	}
+	num, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return num, true
+}
+
+// ensure we fully implement the prometheus.Collector type
+var _ prometheus.Collector = &CheckMetricsCollector{}
+
+// SetChecks replaces the list of exported checks from the config section.
+func (c *CheckMetricsCollector) SetChecks(snc *Agent, section *ConfigSection) error {
+	checks, err := parseExportChecks(section)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.snc = snc
+	c.checks = checks
+	c.mutex.Unlock()
+
+	return nil
+}
+
+func (c *CheckMetricsCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- promCheckMetricDesc
+	descs <- promCheckWarningDesc
+	descs <- promCheckCriticalDesc
+	descs <- promCheckStateDesc
+}
+
+func (c *CheckMetricsCollector) Collect(metrics chan<- prometheus.Metric) {
+	c.mutex.RLock()
+	snc := c.snc
+	checks := c.checks
+	c.mutex.RUnlock()
+
+	waitGroup := &sync.WaitGroup{}
+	for _, chk := range checks {
+		waitGroup.Add(1)
+		go func(chk exportCheck) {
+			defer waitGroup.Done()
+			defer snc.logPanicRecover()
+
+			c.collectCheck(snc, chk, metrics)
+		}(chk)
+	}
+	waitGroup.Wait()
+}
+
+func (c *CheckMetricsCollector) collectCheck(snc *Agent, chk exportCheck, metrics chan<- prometheus.Metric) {
+	res := snc.RunCheckWithContext(context.Background(), chk.command, chk.args, 0, nil)
+	metrics <- prometheus.MustNewConstMetric(promCheckStateDesc, prometheus.GaugeValue, float64(res.State), chk.name)
+
+	// duplicate series would break the whole scrape
+	seen := map[string]bool{}
+	for _, metric := range res.Metrics {
+		if metric.PerfConfig != nil && metric.PerfConfig.Ignore {
+			continue
+		}
+		label := metric.tweakedName()
+		if seen[label] {
+			log.Debugf("[prometheus] skipping duplicate metric %s from check %s", label, chk.name)
+
+			continue
+		}
+		seen[label] = true
+
+		num, unit := metric.tweakedNum(metric.Value)
+		value, err := convert.Float64E(num)
+		if err != nil {
+			continue
+		}
+		metrics <- prometheus.MustNewConstMetric(promCheckMetricDesc, prometheus.GaugeValue, value, chk.name, label, unit)
+
+		if threshold, ok := exportThreshold(metric, metric.WarningStr, metric.Warning); ok {
+			metrics <- prometheus.MustNewConstMetric(promCheckWarningDesc, prometheus.GaugeValue, threshold, chk.name, label, unit)
+		}
+
+		if threshold, ok := exportThreshold(metric, metric.CriticalStr, metric.Critical); ok {
+			metrics <- prometheus.MustNewConstMetric(promCheckCriticalDesc, prometheus.GaugeValue, threshold, chk.name, label, unit)
+		}
+	}
+}
@@ -1,11 +1,45 @@
 package snclient
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandlerPrometheus(t *testing.T) {
 	assert.Implements(t, (*RequestHandlerHTTP)(nil), new(HandlerPrometheus))
 }
+
+func TestPrometheusCheckMetrics(t *testing.T) {
+	snc := StartTestAgent(t, "")
+
+	cfg := NewConfig(false)
+	section := cfg.Section("/settings/Prometheus/checks")
+	section.Set("load", `check_load "warn=load > 500" "crit=load > 1000"`)
+	section.Set("check_dummy", "")
+
+	collector := &CheckMetricsCollector{}
+	require.NoErrorf(t, collector.SetChecks(snc, section), "checks set")
+
+	registry := prometheus.NewRegistry()
+	require.NoErrorf(t, registry.Register(collector), "collector registered")
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(recorder, req)
+	require.Equalf(t, http.StatusOK, recorder.Code, "metrics served")
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `snclient_check_state{check="check_dummy"} 0`)
+	assert.Contains(t, body, `snclient_check_state{check="load"} 0`)
+	assert.Regexp(t, `snclient_check_metric\{check="load",label="load1",unit=""\} [\d.]+`, body)
+	assert.Contains(t, body, `snclient_check_metric_warning{check="load",label="load5",unit=""} 500`)
+	assert.Contains(t, body, `snclient_check_metric_critical{check="load",label="load15",unit=""} 1000`)
+
+	StopTestAgent(t, snc)
+}
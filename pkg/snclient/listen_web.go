@@ -75,6 +75,7 @@ type HandlerWeb struct {
 	handlerV1      http.Handler
 	conf           *ConfigSection
 	password       string
+	users          AccessUserList
 	snc            *Agent
 	listener       *Listener
 	allowedHosts   *AllowedHostConfig
@@ -118,13 +119,22 @@ func (l *HandlerWeb) Stop() {
 	}
 }
 
-func (l *HandlerWeb) Init(snc *Agent, conf *ConfigSection, _ *Config, runSet *AgentRunSet) error {
+func (l *HandlerWeb) Init(snc *Agent, conf *ConfigSection, cfg *Config, runSet *AgentRunSet) error {
 	l.snc = snc
 	l.conf = conf
 	l.password = DefaultPassword
 	if password, ok := conf.GetString("password"); ok {
 		l.password = password
 	}
+	if err := checkPasswordConfig(conf, l.password); err != nil {
+		return err
+	}
+
+	users, err := NewAccessUserList(cfg, conf)
+	if err != nil {
+		return err
+	}
+	l.users = users
 
 	listener, err := SharedWebListener(snc, conf, l, runSet)
 	if err != nil {
@@ -145,13 +155,35 @@ func (l *HandlerWeb) GetAllowedHosts() *AllowedHostConfig {
 	return l.allowedHosts
 }
 
-func (l *HandlerWeb) CheckPassword(req *http.Request, _ URLMapping) bool {
+func (l *HandlerWeb) CheckPassword(req *http.Request, mapping URLMapping) bool {
 	switch req.URL.Path {
 	case "/", "/index.html":
 		return true
-	default:
+	}
+
+	user, matched := l.users.Authenticate(l.snc, req)
+	if !matched {
 		return verifyRequestPassword(l.snc, req, l.password)
 	}
+	if user == nil {
+		return false
+	}
+	setRequestAccessUser(req, user)
+
+	// commands and cached results are restricted by the allowed commands of the user
+	for _, param := range []string{"command", "name"} {
+		if !strings.Contains(mapping.URL, "{"+param+"}") {
+			continue
+		}
+		command := chi.URLParam(req, param)
+		if !user.CommandAllowed(command) {
+			log.Warnf("user %s is not allowed to run %s -> 403", user.Name, command)
+
+			return false
+		}
+	}
+
+	return true
 }
 
 func (l *HandlerWeb) GetMappings(*Agent) []URLMapping {
@@ -161,6 +193,7 @@ func (l *HandlerWeb) GetMappings(*Agent) []URLMapping {
 		{URL: "/api/v1/inventory", Handler: l.handlerV1},
 		{URL: "/api/v1/inventory/", Handler: l.handlerV1},
 		{URL: "/api/v1/inventory/{module}", Handler: l.handlerV1},
+		{URL: "/api/v1/results/{name}", Handler: l.handlerV1},
 		{URL: "/index.html", Handler: l.handlerGeneric},
 		{URL: "/", Handler: l.handlerGeneric},
 	}
@@ -431,6 +464,8 @@ func (l *HandlerWebV1) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	switch {
 	case strings.HasPrefix(path, "/api/v1/inventory"):
 		l.serveInventory(res, req)
+	case strings.HasPrefix(path, "/api/v1/results/"):
+		l.serveResult(res, req)
 	default:
 		l.serveCommand(res, req)
 	}
@@ -464,6 +499,40 @@ func (l *HandlerWebV1) serveCommand(res http.ResponseWriter, req *http.Request)
 	}
 }
 
+func (l *HandlerWebV1) serveResult(res http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "name")
+	res.Header().Set("Content-Type", "application/json")
+
+	var result *CheckResult
+	var timezone *time.Location
+	found := false
+	scheduler := l.Handler.snc.getScheduler()
+	if scheduler != nil {
+		result, timezone, found = scheduler.GetResult(name)
+	}
+	if !found {
+		res.WriteHeader(http.StatusNotFound)
+		LogError(json.NewEncoder(res).Encode(map[string]interface{}{
+			"error": fmt.Sprintf("no such scheduled check: %s", name),
+		}))
+
+		return
+	}
+
+	result.Finalize(timezone)
+	response := map[string]interface{}{
+		"command": name,
+		"result":  result.State,
+		"lines":   l.Handler.result2V1(result),
+	}
+	if lastRun, ok := scheduler.LastRun(name); ok {
+		response["last_run"] = lastRun.Unix()
+	}
+
+	res.WriteHeader(http.StatusOK)
+	LogError(json.NewEncoder(res).Encode(response))
+}
+
 func (l *HandlerWebV1) serveInventory(res http.ResponseWriter, req *http.Request) {
 	res.Header().Set("Content-Type", "application/json")
 	res.WriteHeader(http.StatusOK)
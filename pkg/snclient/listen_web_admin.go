@@ -31,6 +31,7 @@ type HandlerAdmin struct {
 	noCopy       noCopy
 	handler      http.Handler
 	password     string
+	users        AccessUserList
 	snc          *Agent
 	listener     *Listener
 	allowedHosts *AllowedHostConfig
@@ -68,12 +69,21 @@ func (l *HandlerAdmin) Stop() {
 	}
 }
 
-func (l *HandlerAdmin) Init(snc *Agent, conf *ConfigSection, _ *Config, runSet *AgentRunSet) error {
+func (l *HandlerAdmin) Init(snc *Agent, conf *ConfigSection, cfg *Config, runSet *AgentRunSet) error {
 	l.snc = snc
 	l.password = DefaultPassword
 	if password, ok := conf.GetString("password"); ok {
 		l.password = password
 	}
+	if err := checkPasswordConfig(conf, l.password); err != nil {
+		return err
+	}
+
+	users, err := NewAccessUserList(cfg, conf)
+	if err != nil {
+		return err
+	}
+	l.users = users
 
 	listener, err := SharedWebListener(snc, conf, l, runSet)
 	if err != nil {
@@ -95,7 +105,21 @@ func (l *HandlerAdmin) GetAllowedHosts() *AllowedHostConfig {
 }
 
 func (l *HandlerAdmin) CheckPassword(req *http.Request, _ URLMapping) bool {
-	return verifyRequestPassword(l.snc, req, l.password)
+	user, matched := l.users.Authenticate(l.snc, req)
+	if !matched {
+		return verifyRequestPassword(l.snc, req, l.password)
+	}
+	if user == nil {
+		return false
+	}
+	if !user.IsAdmin() {
+		log.Warnf("user %s is not allowed to use the admin api -> 403", user.Name)
+
+		return false
+	}
+	setRequestAccessUser(req, user)
+
+	return true
 }
 
 func (l *HandlerAdmin) GetMappings(*Agent) []URLMapping {
@@ -27,6 +27,7 @@ type Listener struct {
 	bindAddress   string
 	tlsConfig     *tls.Config
 	socketTimeout time.Duration
+	rateLimiter   *RateLimiter
 }
 
 // NewListener creates a new Listener object.
@@ -108,6 +109,12 @@ func (l *Listener) setListenConfig(conf *ConfigSection) error {
 		l.socketTimeout = DefaultSocketTimeout * time.Second
 	}
 
+	rateLimiter, err := NewRateLimiter(conf)
+	if err != nil {
+		return err
+	}
+	l.rateLimiter = rateLimiter
+
 	// parse / set ssl config
 	useSsl, _, err := conf.GetBool("use ssl")
 	switch {
@@ -391,6 +398,11 @@ func (l *Listener) LogWrapHTTPHandler(next http.Handler, res http.ResponseWriter
 
 	logHTTPRequest(req)
 
+	// the user and listener are set later, but required for the audit log
+	ctx := contextWithAccessUser(req.Context(), nil)
+	ctx = contextWithAuditRequest(ctx, l.connType, req.RemoteAddr, req.TLS)
+	req = req.WithContext(ctx)
+
 	resCapture := &ResponseWriterCapture{
 		w:           res,
 		captureBody: log.IsV(LogVerbosityTrace2),
@@ -415,6 +427,8 @@ func (l *Listener) LogWrapHTTPHandler(next http.Handler, res http.ResponseWriter
 		req.Method,
 		req.URL.Path,
 	)
+
+	l.snc.auditHTTPRequest(req, resCapture.statusCode, startTime)
 }
 
 // wrapper for all known web requests to verify passwords and allowed hosts
@@ -427,6 +441,20 @@ func (l *Listener) WrappedCheckHTTPHandler(webHandler RequestHandlerHTTP, mappin
 		return
 	}
 
+	if !l.rateLimiter.Allow(req.RemoteAddr) {
+		http.Error(res, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+
+		return
+	}
+
+	if audit := auditRequestFromContext(req.Context()); audit != nil {
+		audit.listener = webHandler.Type()
+	}
+
+	// CheckPassword stores the authenticated user in the request context
+	if _, ok := req.Context().Value(accessUserContextKey{}).(*accessUserHolder); !ok {
+		req = req.WithContext(contextWithAccessUser(req.Context(), nil))
+	}
 	if !webHandler.CheckPassword(req, *mapping) {
 		http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 
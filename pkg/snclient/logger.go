@@ -55,6 +55,7 @@ var (
 	targetWriter      io.Writer
 	restoreLevel      string
 	LogFileHandle     *os.File
+	logTargetCloser   io.Closer
 )
 
 func setLogLevel(level string) {
@@ -110,16 +111,37 @@ func setLogFile(snc *Agent, conf *ConfigSection) {
 
 	LogFileHandle = nil
 	var logFormatter factorlog.Formatter
-	switch file {
-	case "stdout", "":
+	var severityWriter logSeverityWriter
+	switch {
+	case file == "stdout", file == "":
 		logFormatter = BuildFormatter(logColorOn + DateTimeLogFormat + LogFormat + logColorReset)
 		targetWriter = os.Stdout
-	case "stderr":
+	case file == "stderr":
 		logFormatter = BuildFormatter(logColorOn + DateTimeLogFormat + LogFormat + logColorReset)
 		targetWriter = os.Stderr
-	case "stdout-journal":
+	case file == "stdout-journal":
 		logFormatter = BuildFormatter(LogFormat)
 		targetWriter = os.Stdout
+	case strings.HasPrefix(file, "syslog:"):
+		writer, err := newSyslogLogWriter(strings.TrimPrefix(file, "syslog:"))
+		if err != nil {
+			log.Errorf("%s", err.Error())
+
+			return
+		}
+		logFormatter = BuildFormatter(LogFormat)
+		targetWriter = writer
+		severityWriter = writer
+	case strings.HasPrefix(file, "journald:"):
+		writer, err := newJournaldLogWriter()
+		if err != nil {
+			log.Errorf("%s", err.Error())
+
+			return
+		}
+		logFormatter = BuildFormatter(LogFormat)
+		targetWriter = writer
+		severityWriter = writer
 	default:
 		logFormatter = BuildFormatter(DateTimeLogFormat + LogFormat)
 		fHandle, err := buildLogHandle(file)
@@ -133,7 +155,7 @@ func setLogFile(snc *Agent, conf *ConfigSection) {
 	}
 
 	if IsInteractive() {
-		if targetWriter != os.Stdout && targetWriter != os.Stderr {
+		if targetWriter != os.Stdout && targetWriter != os.Stderr && severityWriter == nil {
 			doOnce.Do(func() {
 				abs, _ := filepath.Abs(file)
 				fmt.Fprintf(os.Stdout, "%s\n", snc.buildStartupMsg())
@@ -143,19 +165,52 @@ func setLogFile(snc *Agent, conf *ConfigSection) {
 	}
 
 	format, _ := conf.GetString("format")
+	logFormat, _ := conf.GetString("log format")
 	switch {
+	case strings.EqualFold(logFormat, "json"):
+		logFormatter = &LogFormatJSON{}
 	case format != "":
 		logFormatter = BuildFormatter(format)
 	case snc.flags.LogFormat != "":
 		logFormatter = BuildFormatter(snc.flags.LogFormat)
 	}
 
+	// syslog and journald need the severity of each message
+	if severityWriter != nil {
+		logFormatter = &logSeverityFormatter{Formatter: logFormatter, writer: severityWriter}
+	}
+
 	if runtime.GOOS == "windows" {
 		targetWriter = NewWindowsLineEndingWriter(targetWriter)
 	}
 
 	log.SetFormatter(logFormatter)
 	log.SetOutput(targetWriter)
+
+	// close previous syslog / journald connection
+	if logTargetCloser != nil {
+		LogError(logTargetCloser.Close())
+	}
+	logTargetCloser = severityWriter
+}
+
+// logSeverityWriter is implemented by log targets which use the severity of each message, ex.: syslog.
+type logSeverityWriter interface {
+	io.WriteCloser
+	setSeverity(severity factorlog.Severity)
+}
+
+// logSeverityFormatter passes the severity to the writer before the formatted message is written.
+// Format and Write are called while holding the logger lock.
+type logSeverityFormatter struct {
+	factorlog.Formatter
+	writer logSeverityWriter
+}
+
+func (f *logSeverityFormatter) Format(context factorlog.LogContext) []byte {
+	f.writer.setSeverity(context.Severity)
+
+	return f.Formatter.Format(context)
 }
 
 func buildLogHandle(file string) (*os.File, error) {
@@ -0,0 +1,77 @@
+package snclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kdar/factorlog"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journaldLogWriter sends log messages to journald using its native protocol.
+type journaldLogWriter struct {
+	con      *net.UnixConn
+	severity factorlog.Severity
+}
+
+func newJournaldLogWriter() (*journaldLogWriter, error) {
+	con, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journald: %s", err.Error())
+	}
+
+	return &journaldLogWriter{con: con, severity: factorlog.INFO}, nil
+}
+
+func (w *journaldLogWriter) setSeverity(severity factorlog.Severity) {
+	w.severity = severity
+}
+
+func (w *journaldLogWriter) Write(p []byte) (int, error) {
+	// syslog priorities
+	priority := "7"
+	switch w.severity {
+	case factorlog.PANIC, factorlog.FATAL, factorlog.CRITICAL, factorlog.STACK:
+		priority = "2"
+	case factorlog.ERROR:
+		priority = "3"
+	case factorlog.WARN:
+		priority = "4"
+	case factorlog.INFO:
+		priority = "6"
+	}
+
+	buf := &bytes.Buffer{}
+	journaldNativeField(buf, "PRIORITY", priority)
+	journaldNativeField(buf, "SYSLOG_IDENTIFIER", "snclient")
+	journaldNativeField(buf, "MESSAGE", strings.TrimSuffix(string(p), "\n"))
+
+	if _, err := w.con.Write(buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("journald: %s", err.Error())
+	}
+
+	return len(p), nil
+}
+
+func (w *journaldLogWriter) Close() error {
+	return w.con.Close()
+}
+
+// journaldNativeField appends a field in the journald native format, values containing newlines are length prefixed.
+func journaldNativeField(buf *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", key, value)
+
+		return
+	}
+
+	buf.WriteString(key)
+	buf.WriteByte('\n')
+	buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(value))))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package snclient
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kdar/factorlog"
+)
+
+// journaldLogWriter is only available on linux.
+type journaldLogWriter struct {
+	io.WriteCloser
+}
+
+func newJournaldLogWriter() (*journaldLogWriter, error) {
+	return nil, fmt.Errorf("journald is only supported on linux")
+}
+
+func (w *journaldLogWriter) setSeverity(_ factorlog.Severity) {}
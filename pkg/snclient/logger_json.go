@@ -0,0 +1,53 @@
+package snclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kdar/factorlog"
+)
+
+// LogFormatJSON formats each log entry as single json line.
+type LogFormatJSON struct{}
+
+// logEntryJSON contains the fields of a json log line.
+type logEntryJSON struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Caller    string `json:"caller,omitempty"`
+	Pid       int    `json:"pid"`
+	Message   string `json:"message"`
+}
+
+// Format returns the json representation of the log entry, it implements the factorlog.Formatter interface.
+func (f *LogFormatJSON) Format(context factorlog.LogContext) []byte {
+	entry := logEntryJSON{
+		Timestamp: context.Time.Format(time.RFC3339Nano),
+		Level:     factorlog.LcSeverityStrings[factorlog.SeverityToIndex(context.Severity)],
+		Pid:       context.Pid,
+	}
+	if context.File != "" {
+		entry.Caller = fmt.Sprintf("%s:%d", strings.TrimSuffix(filepath.Base(context.File), ".go"), context.Line)
+	}
+	if context.Format != nil {
+		entry.Message = fmt.Sprintf(*context.Format, context.Args...)
+	} else {
+		entry.Message = fmt.Sprint(context.Args...)
+	}
+	entry.Message = strings.TrimSuffix(entry.Message, "\n")
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"level":"error","message":%q}`, "json log format failed: "+err.Error()))
+	}
+
+	return append(data, '\n')
+}
+
+// ShouldRuntimeCaller returns true, the caller is always part of the json entry.
+func (f *LogFormatJSON) ShouldRuntimeCaller() bool {
+	return true
+}
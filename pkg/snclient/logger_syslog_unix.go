@@ -0,0 +1,78 @@
+//go:build !windows
+
+package snclient
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/url"
+
+	"github.com/kdar/factorlog"
+)
+
+// newSyslogWriter returns a writer which sends each write as single message to the local syslog daemon.
+func newSyslogWriter(tag string) (io.WriteCloser, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("syslog: %s", err.Error())
+	}
+
+	return writer, nil
+}
+
+// syslogLogWriter sends log messages to syslog with the severity of the log entry.
+type syslogLogWriter struct {
+	writer   *syslog.Writer
+	severity factorlog.Severity
+}
+
+// newSyslogLogWriter connects to the local syslog daemon or to the remote address, ex.: udp://syslog.example.com:514
+func newSyslogLogWriter(address string) (*syslogLogWriter, error) {
+	network := ""
+	if address != "" {
+		remote, err := url.Parse(address)
+		if err != nil || remote.Scheme == "" || remote.Host == "" {
+			return nil, fmt.Errorf("syslog: invalid address %s, expected ex.: udp://host:514", address)
+		}
+		network = remote.Scheme
+		address = remote.Host
+	}
+
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, "snclient")
+	if err != nil {
+		return nil, fmt.Errorf("syslog: %s", err.Error())
+	}
+
+	return &syslogLogWriter{writer: writer, severity: factorlog.INFO}, nil
+}
+
+func (w *syslogLogWriter) setSeverity(severity factorlog.Severity) {
+	w.severity = severity
+}
+
+func (w *syslogLogWriter) Write(p []byte) (int, error) {
+	msg := string(p)
+	var err error
+	switch w.severity {
+	case factorlog.PANIC, factorlog.FATAL, factorlog.CRITICAL, factorlog.STACK:
+		err = w.writer.Crit(msg)
+	case factorlog.ERROR:
+		err = w.writer.Err(msg)
+	case factorlog.WARN:
+		err = w.writer.Warning(msg)
+	case factorlog.INFO:
+		err = w.writer.Info(msg)
+	default:
+		err = w.writer.Debug(msg)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("syslog: %s", err.Error())
+	}
+
+	return len(p), nil
+}
+
+func (w *syslogLogWriter) Close() error {
+	return w.writer.Close()
+}
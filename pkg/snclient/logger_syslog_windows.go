@@ -0,0 +1,24 @@
+package snclient
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kdar/factorlog"
+)
+
+// newSyslogWriter is not available on windows.
+func newSyslogWriter(_ string) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("syslog is not supported on windows")
+}
+
+// syslogLogWriter is not available on windows.
+type syslogLogWriter struct {
+	io.WriteCloser
+}
+
+func newSyslogLogWriter(_ string) (*syslogLogWriter, error) {
+	return nil, fmt.Errorf("syslog is not supported on windows")
+}
+
+func (w *syslogLogWriter) setSeverity(_ factorlog.Severity) {}
@@ -0,0 +1,36 @@
+package snclient
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kdar/factorlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFormatJSON(t *testing.T) {
+	format := "check %s returned %d\n"
+	formatter := &LogFormatJSON{}
+	data := formatter.Format(factorlog.LogContext{
+		Time:     time.Date(2025, 5, 2, 10, 15, 1, 0, time.UTC),
+		Severity: factorlog.WARN,
+		File:     "/src/snclient/pkg/snclient/snclient.go",
+		Line:     42,
+		Pid:      123,
+		Format:   &format,
+		Args:     []interface{}{"check_dummy", 1},
+	})
+	assert.Equalf(t, byte('\n'), data[len(data)-1], "json line ends with newline")
+
+	entry := map[string]interface{}{}
+	require.NoErrorf(t, json.Unmarshal(data, &entry), "valid json")
+	assert.Equalf(t, map[string]interface{}{
+		"timestamp": "2025-05-02T10:15:01Z",
+		"level":     "warn",
+		"caller":    "snclient:42",
+		"pid":       float64(123),
+		"message":   "check check_dummy returned 1",
+	}, entry, "json log entry")
+}
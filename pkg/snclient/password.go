@@ -0,0 +1,240 @@
+package snclient
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// PasswordHashDefault is the algorithm used by "snclient hash" unless specified otherwise.
+	PasswordHashDefault = "bcrypt"
+
+	// argon2id parameters as recommended by OWASP
+	argon2Memory  = 19 * 1024
+	argon2Time    = 2
+	argon2Threads = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+
+	// limits for argon2id hashes from the config, argon2.IDKey panics on zero time or threads
+	argon2MaxMemory  = 256 * 1024 // in KiB
+	argon2MinSaltLen = 8
+	argon2MinKeyLen  = 16
+
+	// max number of cached successful password verifications
+	passwordCacheMaxSize = 1000
+)
+
+// PasswordHashAlgorithms lists all supported password hash algorithms.
+var PasswordHashAlgorithms = []string{"bcrypt", "argon2id", "sha256"}
+
+var (
+	// bcrypt and argon2id are expensive by design, so successful verifications are cached
+	passwordCache     = map[[sha256.Size]byte]bool{}
+	passwordCacheLock sync.Mutex
+
+	// insecure passwords are logged once per password
+	passwordWarnings sync.Map
+)
+
+// HashPassword returns the password hashed with given algorithm in the format <algorithm>:<hash>.
+func HashPassword(algorithm, password string) (string, error) {
+	switch strings.ToLower(algorithm) {
+	case "bcrypt":
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("bcrypt: %s", err.Error())
+		}
+
+		return "bcrypt:" + string(hash), nil
+	case "argon2id":
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("argon2id: %s", err.Error())
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+		return fmt.Sprintf("argon2id:$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	case "sha256":
+		sum := sha256.Sum256([]byte(password))
+
+		return "SHA256:" + hex.EncodeToString(sum[:]), nil
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm: %s (supported are: %s)", algorithm, strings.Join(PasswordHashAlgorithms, ", "))
+	}
+}
+
+// passwordMatches compares the user password against the configured, optionally hashed, password.
+func passwordMatches(confPassword, userPassword string) bool {
+	cacheKey := sha256.Sum256([]byte(confPassword + "\x00" + userPassword))
+	passwordCacheLock.Lock()
+	cached := passwordCache[cacheKey]
+	passwordCacheLock.Unlock()
+	if cached {
+		return true
+	}
+
+	if !passwordVerify(confPassword, userPassword) {
+		return false
+	}
+
+	passwordCacheLock.Lock()
+	if len(passwordCache) >= passwordCacheMaxSize {
+		clear(passwordCache)
+	}
+	passwordCache[cacheKey] = true
+	passwordCacheLock.Unlock()
+
+	return true
+}
+
+func passwordVerify(confPassword, userPassword string) bool {
+	algorithm, hash, found := strings.Cut(confPassword, ":")
+	if !found {
+		return subtle.ConstantTimeCompare([]byte(confPassword), []byte(userPassword)) == 1
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		sum := sha256.Sum256([]byte(userPassword))
+
+		return subtle.ConstantTimeCompare([]byte(strings.ToLower(hash)), []byte(hex.EncodeToString(sum[:]))) == 1
+	case "bcrypt":
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(userPassword)) == nil
+	case "argon2id":
+		matches, err := argon2Verify(hash, userPassword)
+		if err != nil {
+			log.Errorf("argon2id: %s", err.Error())
+
+			return false
+		}
+
+		return matches
+	default:
+		log.Errorf("unsupported hash algorithm: %s", algorithm)
+
+		// passwords may contain colons as well
+		return subtle.ConstantTimeCompare([]byte(confPassword), []byte(userPassword)) == 1
+	}
+}
+
+// argon2Hash contains the parsed parameters of an argon2id hash.
+type argon2Hash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// argon2Verify checks the password against a hash in the PHC string format: $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func argon2Verify(hash, password string) (bool, error) {
+	parsed, err := argon2Parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	keyLen := uint32(len(parsed.key)) //nolint:gosec // length of a decoded hash cannot overflow
+	userKey := argon2.IDKey([]byte(password), parsed.salt, parsed.time, parsed.memory, parsed.threads, keyLen)
+
+	return subtle.ConstantTimeCompare(parsed.key, userKey) == 1, nil
+}
+
+// argon2Parse parses and validates an argon2id hash in the PHC string format.
+func argon2Parse(hash string) (*argon2Hash, error) {
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[1] != "argon2id" {
+		return nil, fmt.Errorf("invalid hash format, expected $argon2id$v=...$m=...,t=...,p=...$<salt>$<key>")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid version: %s", err.Error())
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported version: %d", version)
+	}
+
+	parsed := &argon2Hash{}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &parsed.memory, &parsed.time, &parsed.threads); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %s", err.Error())
+	}
+	switch {
+	case parsed.time < 1:
+		return nil, fmt.Errorf("invalid parameters: t must be at least 1")
+	case parsed.threads < 1:
+		return nil, fmt.Errorf("invalid parameters: p must be at least 1")
+	case parsed.memory < 8*uint32(parsed.threads):
+		return nil, fmt.Errorf("invalid parameters: m must be at least 8 * p")
+	case parsed.memory > argon2MaxMemory:
+		return nil, fmt.Errorf("invalid parameters: m must not exceed %d", argon2MaxMemory)
+	}
+
+	var err error
+	parsed.salt, err = base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %s", err.Error())
+	}
+	if len(parsed.salt) < argon2MinSaltLen {
+		return nil, fmt.Errorf("invalid salt: must be at least %d bytes", argon2MinSaltLen)
+	}
+
+	parsed.key, err = base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %s", err.Error())
+	}
+	if len(parsed.key) < argon2MinKeyLen {
+		return nil, fmt.Errorf("invalid key: must be at least %d bytes", argon2MinKeyLen)
+	}
+
+	return parsed, nil
+}
+
+// checkPasswordConfig rejects invalid password hashes and warns about insecure passwords.
+func checkPasswordConfig(conf *ConfigSection, password string) error {
+	algorithm, hash, found := strings.Cut(password, ":")
+	if found && strings.EqualFold(algorithm, "argon2id") {
+		if _, err := argon2Parse(hash); err != nil {
+			return fmt.Errorf("[%s] invalid argon2id password hash: %s", conf.name, err.Error())
+		}
+	}
+
+	warnInsecurePassword(conf, password)
+
+	return nil
+}
+
+// warnInsecurePassword logs a deprecation warning for plain text and unsalted password hashes.
+func warnInsecurePassword(conf *ConfigSection, password string) {
+	if password == "" || password == DefaultPassword {
+		return
+	}
+
+	algorithm, _, _ := strings.Cut(password, ":")
+	kind := "plain text"
+	switch strings.ToLower(algorithm) {
+	case "bcrypt", "argon2id":
+		return
+	case "sha256":
+		kind = "unsalted sha256"
+	}
+
+	if _, warned := passwordWarnings.LoadOrStore(password, true); warned {
+		return
+	}
+
+	log.Warnf("[%s] using %s passwords is deprecated, use 'snclient hash' to create a bcrypt or argon2id hash", conf.name, kind)
+}
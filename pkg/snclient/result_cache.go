@@ -0,0 +1,183 @@
+package snclient
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+var promCheckCache = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "snclient_check_cache_total",
+		Help: "total number of check requests using the result cache by result (hit, miss, coalesced)",
+	},
+	[]string{"result"})
+
+// ResultCache caches check results for a short time and combines concurrent identical requests into a single check run.
+type ResultCache struct {
+	defaultTTL  time.Duration
+	maxTTL      time.Duration // maximum ttl requested by the cache-ttl argument
+	maxAge      time.Duration // entries older than this cannot be used by any request
+	commandTTLs map[string]time.Duration
+
+	lock    sync.Mutex
+	entries map[string]*resultCacheEntry
+	group   singleflight.Group
+}
+
+type resultCacheEntry struct {
+	result   *CheckResult
+	timezone *time.Location
+	created  time.Time
+}
+
+// NewResultCache creates a new result cache from the /settings/cache section.
+func NewResultCache(conf *Config) (*ResultCache, error) {
+	cache := &ResultCache{
+		commandTTLs: map[string]time.Duration{},
+		entries:     map[string]*resultCacheEntry{},
+	}
+
+	section := conf.Section("/settings/cache")
+	ttl, _, err := section.GetDuration("cache ttl")
+	if err != nil {
+		return nil, fmt.Errorf("cache ttl: %s", err.Error())
+	}
+	cache.defaultTTL = time.Duration(ttl * float64(time.Second))
+
+	maxTTL, _, err := section.GetDuration("max cache ttl")
+	if err != nil {
+		return nil, fmt.Errorf("max cache ttl: %s", err.Error())
+	}
+	cache.maxTTL = time.Duration(maxTTL * float64(time.Second))
+	cache.maxAge = max(cache.defaultTTL, cache.maxTTL)
+
+	commands := conf.Section("/settings/cache/commands")
+	for _, command := range commands.Keys() {
+		ttl, _, err := commands.GetDuration(command)
+		if err != nil {
+			return nil, fmt.Errorf("/settings/cache/commands: %s: %s", command, err.Error())
+		}
+		cache.commandTTLs[command] = time.Duration(ttl * float64(time.Second))
+		cache.maxAge = max(cache.maxAge, cache.commandTTLs[command])
+	}
+
+	return cache, nil
+}
+
+// TTL returns the cache ttl for given command. The requested ttl from the cache-ttl argument
+// overrides the configured ttl if it is not negative and is capped at the max cache ttl.
+func (rc *ResultCache) TTL(command string, requested time.Duration) time.Duration {
+	if rc == nil {
+		return 0
+	}
+
+	if requested >= 0 {
+		if requested > rc.maxTTL {
+			log.Debugf("cache-ttl %s for %s exceeds max cache ttl, using %s", requested, command, rc.maxTTL)
+
+			return rc.maxTTL
+		}
+
+		return requested
+	}
+
+	if ttl, ok := rc.commandTTLs[command]; ok {
+		return ttl
+	}
+
+	return rc.defaultTTL
+}
+
+// Get returns a copy of the cached result for given key if it is not older than ttl. If there is no valid
+// cached result, the run function is called once for all concurrent requests with the same key.
+// Results are only cached if cacheable returns true.
+func (rc *ResultCache) Get(key string, ttl time.Duration, run func() (res *CheckResult, timezone *time.Location, cacheable bool)) (*CheckResult, *time.Location) {
+	rc.lock.Lock()
+	entry, ok := rc.entries[key]
+	rc.lock.Unlock()
+	if ok && time.Since(entry.created) < ttl {
+		log.Debugf("result cache hit for %s (age %s)", resultCacheLogKey(key), time.Since(entry.created).Truncate(time.Millisecond))
+		promCheckCache.WithLabelValues("hit").Inc()
+
+		return entry.result.Clone(), entry.timezone
+	}
+
+	executed := false
+	shared, _, _ := rc.group.Do(key, func() (interface{}, error) {
+		executed = true
+		res, timezone, cacheable := run()
+		entry := &resultCacheEntry{result: res, timezone: timezone, created: time.Now()}
+		if cacheable {
+			rc.store(key, entry)
+		}
+
+		return entry, nil
+	})
+
+	if executed {
+		promCheckCache.WithLabelValues("miss").Inc()
+	} else {
+		log.Debugf("result cache coalesced request for %s", resultCacheLogKey(key))
+		promCheckCache.WithLabelValues("coalesced").Inc()
+	}
+
+	entry, _ = shared.(*resultCacheEntry)
+
+	return entry.result.Clone(), entry.timezone
+}
+
+// store adds the entry to the cache and removes entries too old for any request.
+func (rc *ResultCache) store(key string, entry *resultCacheEntry) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	for k, e := range rc.entries {
+		if time.Since(e.created) > rc.maxAge {
+			delete(rc.entries, k)
+		}
+	}
+	rc.entries[key] = entry
+}
+
+// resultCacheKey returns the cache key for a check request. Requests are only shared between
+// identical commands and arguments from the same user and listener.
+func resultCacheKey(user *AccessUser, transportConf *ConfigSection, command string, args []string, timeout float64) string {
+	parts := []string{"", "", command, fmt.Sprintf("%f", timeout)}
+	if user != nil {
+		parts[0] = user.Name
+	}
+	if transportConf != nil {
+		parts[1] = transportConf.name
+	}
+	parts = append(parts, args...)
+
+	return strings.Join(parts, "\x00")
+}
+
+// resultCacheArgs returns the arguments used for the cache key. The cache-ttl argument of built-in
+// checks is removed, so requests with different ttls share the same cache entry.
+func resultCacheArgs(chk *CheckData, args []string) []string {
+	if chk.argsPassthrough {
+		return args
+	}
+
+	return slices.DeleteFunc(slices.Clone(args), func(arg string) bool {
+		return strings.HasPrefix(arg, "cache-ttl=")
+	})
+}
+
+// resultCacheLogKey returns the command and arguments from the cache key for logging.
+func resultCacheLogKey(key string) string {
+	parts := strings.Split(key, "\x00")
+	if len(parts) < 4 {
+		return key
+	}
+
+	return strings.Join(append([]string{parts[2]}, parts[4:]...), " ")
+}
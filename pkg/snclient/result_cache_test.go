@@ -0,0 +1,135 @@
+package snclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultCacheTTL(t *testing.T) {
+	cfg := NewConfig(true)
+	require.NoErrorf(t, cfg.ParseINI(`
+[/settings/cache]
+cache ttl = 5s
+max cache ttl = 5m
+
+[/settings/cache/commands]
+check_files = 1m
+check_cpu = 0
+`, "test.ini", nil), "config parsed")
+
+	cache, err := NewResultCache(cfg)
+	require.NoErrorf(t, err, "cache created")
+
+	assert.Equalf(t, 5*time.Second, cache.TTL("check_memory", -1), "default ttl")
+	assert.Equalf(t, time.Minute, cache.TTL("check_files", -1), "command ttl")
+	assert.Equalf(t, time.Duration(0), cache.TTL("check_cpu", -1), "command ttl disabled")
+	assert.Equalf(t, 10*time.Second, cache.TTL("check_cpu", 10*time.Second), "ttl from argument")
+	assert.Equalf(t, time.Duration(0), cache.TTL("check_files", 0), "cache disabled by argument")
+	assert.Equalf(t, 5*time.Minute, cache.TTL("check_cpu", 24*time.Hour), "ttl capped at max cache ttl")
+
+	var disabled *ResultCache
+	assert.Equalf(t, time.Duration(0), disabled.TTL("check_cpu", time.Minute), "no cache")
+}
+
+func TestResultCacheGet(t *testing.T) {
+	cache, err := NewResultCache(NewConfig(true))
+	require.NoErrorf(t, err, "cache created")
+
+	runs := atomic.Int32{}
+	run := func() (*CheckResult, *time.Location, bool) {
+		runs.Add(1)
+		time.Sleep(100 * time.Millisecond)
+
+		return &CheckResult{State: CheckExitOK, Output: "ok", Metrics: []*CheckMetric{{Name: "m", Value: 1}}}, nil, true
+	}
+
+	key := resultCacheKey(nil, nil, "check_dummy", []string{"0"}, 0)
+
+	// concurrent requests are coalesced into a single run
+	wg := sync.WaitGroup{}
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, _ := cache.Get(key, time.Minute, run)
+			assert.Equalf(t, "ok", res.Output, "shared result")
+		}()
+	}
+	wg.Wait()
+	assert.Equalf(t, int32(1), runs.Load(), "check run once")
+
+	// results are copies
+	res, _ := cache.Get(key, time.Minute, run)
+	res.Output = "changed"
+	res.Metrics = nil
+	res, _ = cache.Get(key, time.Minute, run)
+	assert.Equalf(t, "ok", res.Output, "cached result not modified")
+	assert.Lenf(t, res.Metrics, 1, "cached metrics not modified")
+	assert.Equalf(t, int32(1), runs.Load(), "cache hit")
+
+	// other users do not share results
+	otherKey := resultCacheKey(&AccessUser{Name: "helpdesk"}, nil, "check_dummy", []string{"0"}, 0)
+	cache.Get(otherKey, time.Minute, run)
+	assert.Equalf(t, int32(2), runs.Load(), "separate cache entry")
+
+	// expired entries are refreshed
+	expKey := resultCacheKey(nil, nil, "check_dummy", []string{"1"}, 0)
+	cache.Get(expKey, time.Millisecond, run)
+	time.Sleep(10 * time.Millisecond)
+	cache.Get(expKey, time.Millisecond, run)
+	assert.Equalf(t, int32(4), runs.Load(), "expired entry")
+
+	// freshness depends on the ttl of the current request
+	cache.Get(expKey, time.Minute, run)
+	assert.Equalf(t, int32(4), runs.Load(), "entry from short ttl request used by long ttl request")
+
+	// uncacheable results are shared with concurrent requests only
+	failKey := resultCacheKey(nil, nil, "check_dummy", []string{"3"}, 0)
+	fail := func() (*CheckResult, *time.Location, bool) {
+		runs.Add(1)
+
+		return &CheckResult{State: CheckExitUnknown, Output: "failed"}, nil, false
+	}
+	cache.Get(failKey, time.Minute, fail)
+	res, _ = cache.Get(failKey, time.Minute, fail)
+	assert.Equalf(t, "failed", res.Output, "failed result")
+	assert.Equalf(t, int32(6), runs.Load(), "failed result not cached")
+}
+
+func TestResultCacheCheck(t *testing.T) {
+	snc := StartTestAgent(t, `
+[/settings/cache]
+cache ttl = 0
+max cache ttl = 1m
+`)
+
+	// unknown results are not cached
+	res := snc.RunCheck("check_uptime", []string{"cache-ttl=1m", "warn=invalid"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "parse error")
+
+	// cache-ttl is an argument of built-in checks and subject to the allow arguments setting
+	transportConf := NewConfig(true).Section("/settings/NRPE/server")
+	transportConf.Set("allow arguments", "false")
+	res = snc.RunCheckWithContext(context.TODO(), "check_uptime", []string{"cache-ttl=1m"}, 0, transportConf)
+	assert.Equalf(t, CheckExitUnknown, res.State, "cache-ttl not allowed")
+	assert.Containsf(t, res.Output, "check the allow arguments option", "cache-ttl not allowed")
+
+	res = snc.RunCheck("check_uptime", []string{"cache-ttl=-5s"})
+	assert.Equalf(t, CheckExitUnknown, res.State, "negative cache-ttl")
+
+	assert.Emptyf(t, snc.runSet.cache.entries, "nothing cached")
+
+	res = snc.RunCheck("check_uptime", []string{"cache-ttl=1m"})
+	assert.NotEqualf(t, CheckExitUnknown, res.State, "check run")
+	cached := snc.RunCheck("check_uptime", []string{"cache-ttl=30s"})
+	assert.Equalf(t, res.Output, cached.Output, "cached result shared between ttls")
+	assert.Lenf(t, snc.runSet.cache.entries, 1, "single cache entry")
+
+	StopTestAgent(t, snc)
+}
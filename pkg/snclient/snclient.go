@@ -173,6 +173,8 @@ type AgentRunSet struct {
 	files      []string
 	cmdAliases map[string]CheckEntry // contains all registered check handler aliases
 	cmdWraps   map[string]CheckEntry // contains all registered wrapped check handler
+	limiter    *CheckLimiter         // limits concurrent checks
+	cache      *ResultCache          // caches check results
 }
 
 // NewAgent returns a new Agent object ready to be started by Run()
@@ -438,6 +440,16 @@ func (snc *Agent) Init() (*AgentRunSet, error) {
 		return initSet, err
 	}
 
+	initSet.limiter, err = NewCheckLimiter(initSet.config)
+	if err != nil {
+		return initSet, fmt.Errorf("/settings/limits: %s", err.Error())
+	}
+
+	initSet.cache, err = NewResultCache(initSet.config)
+	if err != nil {
+		return initSet, fmt.Errorf("/settings/cache: %s", err.Error())
+	}
+
 	initSet.tasks = NewModuleSet("tasks")
 	err = snc.initModules("tasks", AvailableTasks, initSet, initSet.tasks)
 	if err != nil {
@@ -733,19 +745,73 @@ func (snc *Agent) RunCheck(name string, args []string) *CheckResult {
 // RunCheckWithContext calls check by name and returns the check result.
 // secCon configuration section will be used to check for nasty characters and allowed arguments.
 func (snc *Agent) RunCheckWithContext(ctx context.Context, name string, args []string, timeoutOveride float64, transportConf *ConfigSection) *CheckResult {
-	res, chk := snc.runCheck(ctx, name, args, timeoutOveride, transportConf, false)
+	startTime := time.Now()
+	var cache *ResultCache
+	if snc.runSet != nil {
+		cache = snc.runSet.cache
+	}
+
+	res, timezone := snc.runCachedCheck(ctx, cache, name, args, timeoutOveride, transportConf)
+	defer snc.auditCheck(ctx, name, args, res, startTime)
+
 	if res.Raw == nil || res.Raw.showHelp == 0 {
-		if chk != nil {
-			res.Finalize(chk.timezone)
-		} else {
-			res.Finalize(nil)
-		}
+		res.Finalize(timezone)
 	}
 
 	return res
 }
 
+// runCachedCheck runs the check and uses the result cache if a cache ttl is set for this request.
+func (snc *Agent) runCachedCheck(ctx context.Context, cache *ResultCache, name string, args []string, timeoutOveride float64, transportConf *ConfigSection) (*CheckResult, *time.Location) {
+	handler, chk, parsedArgs, res := snc.prepareCheck(ctx, name, args, transportConf, false)
+	if res != nil {
+		if chk == nil {
+			return res, nil
+		}
+
+		return res, chk.timezone
+	}
+
+	cacheTTL := cache.TTL(name, time.Duration(chk.cacheTTL*float64(time.Second)))
+	if cacheTTL <= 0 {
+		res, err := snc.execCheck(ctx, name, handler, chk, parsedArgs, timeoutOveride, false)
+		if err != nil {
+			return checkErrorResult(err), chk.timezone
+		}
+
+		return res, chk.timezone
+	}
+
+	key := resultCacheKey(accessUserFromContext(ctx), transportConf, name, resultCacheArgs(chk, args), timeoutOveride)
+
+	return cache.Get(key, cacheTTL, func() (*CheckResult, *time.Location, bool) {
+		// the shared run must not be canceled when the first requesting client disconnects
+		res, err := snc.execCheck(context.WithoutCancel(ctx), name, handler, chk, parsedArgs, timeoutOveride, false)
+		if err != nil {
+			return checkErrorResult(err), chk.timezone, false
+		}
+
+		return res, chk.timezone, res.State != CheckExitUnknown
+	})
+}
+
 func (snc *Agent) runCheck(ctx context.Context, name string, args []string, timeoutOveride float64, transportConf *ConfigSection, skipAllowedCheck bool) (*CheckResult, *CheckData) {
+	handler, chk, parsedArgs, res := snc.prepareCheck(ctx, name, args, transportConf, skipAllowedCheck)
+	if res != nil {
+		return res, chk
+	}
+
+	res, err := snc.execCheck(ctx, name, handler, chk, parsedArgs, timeoutOveride, skipAllowedCheck)
+	if err != nil {
+		return checkErrorResult(err), chk
+	}
+
+	return res, chk
+}
+
+// prepareCheck builds the check, parses the arguments and verifies the request is allowed.
+// It returns a result instead if the check cannot be run or the help has been requested.
+func (snc *Agent) prepareCheck(ctx context.Context, name string, args []string, transportConf *ConfigSection, skipAllowedCheck bool) (CheckHandler, *CheckData, []Argument, *CheckResult) {
 	log.Tracef("command: %s", name)
 	log.Tracef("args: %#v", args)
 	if deadline, ok := ctx.Deadline(); ok {
@@ -753,35 +819,37 @@ func (snc *Agent) runCheck(ctx context.Context, name string, args []string, time
 	}
 	check, ok := snc.getCheck(name)
 	if !ok {
-		return &CheckResult{
+		return nil, nil, nil, &CheckResult{
 			State:  CheckExitUnknown,
 			Output: fmt.Sprintf("${status} - No such check: %s", name),
-		}, nil
+		}
 	}
 
 	handler := check.Handler()
 	chk := handler.Build()
 	parsedArgs, err := chk.parseArgs(args)
 	if err != nil {
-		return &CheckResult{
-			State:  CheckExitUnknown,
-			Output: fmt.Sprintf("${status} - %s", err.Error()),
-		}, chk
+		return nil, chk, nil, checkErrorResult(err)
 	}
 
 	if chk.showHelp > 0 {
-		return snc.runHelp(ctx, chk, handler), chk
+		return nil, chk, nil, snc.runHelp(ctx, chk, handler)
 	}
 	if !skipAllowedCheck {
-		err = snc.checkAllowed(name, chk, handler, parsedArgs, transportConf)
+		err = snc.checkAllowed(name, chk, handler, parsedArgs, transportConf, accessUserFromContext(ctx))
 		if err != nil {
-			return &CheckResult{
+			return nil, chk, nil, &CheckResult{
 				State:  CheckExitUnknown,
 				Output: err.Error(),
-			}, chk
+			}
 		}
 	}
 
+	return handler, chk, parsedArgs, nil
+}
+
+// execCheck runs the prepared check with its timeout and the concurrency limits applied.
+func (snc *Agent) execCheck(ctx context.Context, name string, handler CheckHandler, chk *CheckData, parsedArgs []Argument, timeoutOveride float64, skipLimiter bool) (*CheckResult, error) {
 	if timeoutOveride > 0 {
 		chk.timeout = timeoutOveride
 	}
@@ -789,20 +857,37 @@ func (snc *Agent) runCheck(ctx context.Context, name string, args []string, time
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(chk.timeout+1)*time.Second)
 	defer cancel()
 
-	res, err := handler.Check(ctx, snc, chk, parsedArgs)
-	if err != nil {
-		return &CheckResult{
-			State:  CheckExitUnknown,
-			Output: fmt.Sprintf("${status} - %s", err.Error()),
-		}, chk
+	// nested checks from aliases already hold a slot
+	if !skipLimiter && snc.runSet != nil {
+		release, err := snc.runSet.limiter.Acquire(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 
-	return res, chk
+	return handler.Check(ctx, snc, chk, parsedArgs)
 }
 
-// check allowed arguments and nasty characters settings.
-func (snc *Agent) checkAllowed(command string, chk *CheckData, handler CheckHandler, parsedArgs []Argument, transportConf *ConfigSection) error {
+// checkErrorResult returns the unknown result for a check which could not be run.
+func checkErrorResult(err error) *CheckResult {
+	return &CheckResult{
+		State:  CheckExitUnknown,
+		Output: fmt.Sprintf("${status} - %s", err.Error()),
+	}
+}
+
+// check allowed arguments and nasty characters settings along with the permissions of the authenticated user.
+func (snc *Agent) checkAllowed(command string, chk *CheckData, handler CheckHandler, parsedArgs []Argument, transportConf *ConfigSection, user *AccessUser) error {
 	log.Tracef("check allowed: chk:%T cmd:%s: %#v // %#v", handler, command, parsedArgs, chk.rawArgs)
+	if user != nil {
+		if !user.CommandAllowed(command) {
+			return fmt.Errorf("exception processing request: user %s is not allowed to run %s", user.Name, command)
+		}
+		if err := user.ArgumentsAllowed(chk.rawArgs); err != nil {
+			return fmt.Errorf("exception processing request: %s", err.Error())
+		}
+	}
 	var chkConfig *ConfigSection
 	switch hdl := handler.(type) {
 	case *CheckAlias:
@@ -1229,25 +1314,7 @@ func (snc *Agent) verifyPassword(confPassword, userPassword string) bool {
 		return false
 	}
 
-	// hashed password?
-	fields := strings.SplitN(confPassword, ":", 2)
-	if len(fields) == 2 {
-		switch strings.ToLower(fields[0]) {
-		case "sha256":
-			confPassword = fields[1]
-			var err error
-			userPassword, err = utils.Sha256Sum(userPassword)
-			if err != nil {
-				log.Errorf("sha256 failed: %s", err.Error())
-
-				return false
-			}
-		default:
-			log.Errorf("unsupported hash algorithm: %s", fields[0])
-		}
-	}
-
-	if confPassword == userPassword {
+	if passwordMatches(confPassword, userPassword) {
 		return true
 	}
 
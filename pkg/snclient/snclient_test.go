@@ -17,6 +17,8 @@ password0 =
 password1 = %s
 password2 = secret
 password3 = SHA256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+password4 = bcrypt:$2a$10$/AD8UQU0j.EKMSAVBbvb...kSHAi4DLkRYd3j6d.fiSfJDzzyBdqS
+password5 = argon2id:$argon2id$v=19$m=19456,t=2,p=1$ttx6gqLByx9eUa6WSwn3mA$RdmSdZPkUviOgciwoKqCUipI6whnsHGp0wcYzz4StA4
 `, DefaultPassword)
 
 	snc := StartTestAgent(t, config)
@@ -41,9 +43,56 @@ password3 = SHA256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00
 	assert.Truef(t, snc.verifyPassword(p3, "test"), "hashed password -> ok")
 	assert.Falsef(t, snc.verifyPassword(p3, "wrong"), "hashed password wrong")
 
+	p4, _ := conf.GetString("password4")
+	assert.Truef(t, snc.verifyPassword(p4, "test"), "bcrypt password -> ok")
+	assert.Truef(t, snc.verifyPassword(p4, "test"), "cached bcrypt password -> ok")
+	assert.Falsef(t, snc.verifyPassword(p4, "wrong"), "bcrypt password wrong")
+
+	p5, _ := conf.GetString("password5")
+	assert.Truef(t, snc.verifyPassword(p5, "test"), "argon2id password -> ok")
+	assert.Falsef(t, snc.verifyPassword(p5, "wrong"), "argon2id password wrong")
+	assert.Falsef(t, snc.verifyPassword("argon2id:$argon2id$v=19$broken", "test"), "broken argon2id hash")
+
 	StopTestAgent(t, snc)
 }
 
+func TestPasswordHash(t *testing.T) {
+	for _, algorithm := range PasswordHashAlgorithms {
+		hash, err := HashPassword(algorithm, "secret")
+		require.NoErrorf(t, err, "%s hash created", algorithm)
+		assert.Truef(t, passwordVerify(hash, "secret"), "%s password verified", algorithm)
+		assert.Falsef(t, passwordVerify(hash, "wrong"), "%s password wrong", algorithm)
+	}
+
+	hash1, _ := HashPassword("bcrypt", "secret")
+	hash2, _ := HashPassword("bcrypt", "secret")
+	assert.NotEqualf(t, hash1, hash2, "hashes are salted")
+
+	_, err := HashPassword("md5", "secret")
+	require.Errorf(t, err, "unsupported algorithm")
+}
+
+func TestPasswordArgon2Parameters(t *testing.T) {
+	disableLogsTemporarily()
+	defer restoreLogLevel()
+
+	salt := "ttx6gqLByx9eUa6WSwn3mA"
+	key := "RdmSdZPkUviOgciwoKqCUipI6whnsHGp0wcYzz4StA4"
+	for _, params := range []string{"m=19456,t=0,p=1", "m=19456,t=2,p=0", "m=4,t=2,p=1", "m=4294967295,t=2,p=1"} {
+		hash := fmt.Sprintf("argon2id:$argon2id$v=19$%s$%s$%s", params, salt, key)
+		assert.Falsef(t, passwordVerify(hash, "test"), "invalid parameters %s rejected", params)
+
+		err := checkPasswordConfig(&ConfigSection{name: "/settings/WEB/server"}, hash)
+		require.Errorf(t, err, "invalid parameters %s rejected in config", params)
+	}
+
+	// empty keys would match any password
+	hash := fmt.Sprintf("argon2id:$argon2id$v=19$m=19456,t=2,p=1$%s$", salt)
+	assert.Falsef(t, passwordVerify(hash, "test"), "empty key rejected")
+
+	require.NoErrorf(t, checkPasswordConfig(&ConfigSection{name: "/settings/WEB/server"}, "argon2id:$argon2id$v=19$m=19456,t=2,p=1$"+salt+"$"+key), "valid hash")
+}
+
 func TestConfigInheritance(t *testing.T) {
 	tmpInclude, err := os.CreateTemp(t.TempDir(), "testconfig")
 	require.NoErrorf(t, err, "tmp config created")
@@ -0,0 +1,320 @@
+package snclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/humanize"
+)
+
+func init() {
+	RegisterModule(
+		&AvailableTasks,
+		"AuditLog",
+		"/settings/log/audit",
+		NewAuditLogHandler,
+		ConfigInit{
+			ConfigData{
+				"file name":           "",
+				"syslog":              "false",
+				"max size":            "0",
+				"sensitive arguments": "*password*, *secret*, *token*, pass, community",
+			},
+		},
+	)
+}
+
+// AuditLogHandler writes all remotely executed commands as json lines into a separate audit log.
+type AuditLogHandler struct {
+	noCopy noCopy
+
+	snc *Agent
+
+	fileName           string
+	maxSize            uint64
+	sensitiveArguments []string
+
+	lock     sync.Mutex
+	file     *os.File
+	fileSize uint64
+	syslog   io.WriteCloser
+}
+
+// AuditLogEntry is a single line in the audit log.
+type AuditLogEntry struct {
+	Timestamp  string   `json:"timestamp"`
+	Listener   string   `json:"listener"`
+	RemoteAddr string   `json:"remote_addr"`
+	User       string   `json:"user,omitempty"`
+	ClientCN   string   `json:"client_cn,omitempty"`
+	Command    string   `json:"command"`
+	Args       []string `json:"args"`
+	State      string   `json:"state"`
+	Duration   float64  `json:"duration"`
+}
+
+// auditRequest contains the connection details of a remote request.
+type auditRequest struct {
+	listener   string
+	remoteAddr string
+	clientCN   string
+}
+
+// auditRequestContextKey is used to store the audit details in the request context.
+type auditRequestContextKey struct{}
+
+func NewAuditLogHandler() Module {
+	return &AuditLogHandler{}
+}
+
+func (a *AuditLogHandler) Init(snc *Agent, section *ConfigSection, _ *Config, _ *AgentRunSet) error {
+	a.snc = snc
+
+	a.fileName, _ = section.GetString("file name")
+	useSyslog, _, err := section.GetBool("syslog")
+	if err != nil {
+		return fmt.Errorf("syslog: %s", err.Error())
+	}
+	if a.fileName == "" && !useSyslog {
+		return fmt.Errorf("audit log requires either a file name or syslog")
+	}
+
+	maxSize, _, err := section.GetBytes("max size")
+	if err != nil {
+		return fmt.Errorf("max size: %s", err.Error())
+	}
+	a.maxSize = maxSize
+
+	sensitive, _ := section.GetString("sensitive arguments")
+	a.sensitiveArguments = accessUserList(strings.ToLower(sensitive))
+	for _, pattern := range a.sensitiveArguments {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("sensitive arguments: invalid pattern %s: %s", pattern, err.Error())
+		}
+	}
+
+	if useSyslog {
+		a.syslog, err = newSyslogWriter("snclient-audit")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *AuditLogHandler) Start() error {
+	if a.fileName == "" {
+		return nil
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.openFile()
+}
+
+func (a *AuditLogHandler) Stop() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.file != nil {
+		LogError(a.file.Close())
+		a.file = nil
+	}
+	if a.syslog != nil {
+		LogError(a.syslog.Close())
+		a.syslog = nil
+	}
+}
+
+// Log writes the entry to the audit file and syslog.
+func (a *AuditLogHandler) Log(entry *AuditLogEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Errorf("audit log: json error: %s", err.Error())
+
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.syslog != nil {
+		_, err = a.syslog.Write(data)
+		if err != nil {
+			log.Errorf("audit log: %s", err.Error())
+		}
+	}
+
+	if a.file == nil {
+		return
+	}
+
+	data = append(data, '\n')
+	written, err := a.file.Write(data)
+	if err != nil {
+		log.Errorf("audit log: write to %s failed: %s", a.fileName, err.Error())
+	}
+	a.fileSize += uint64(written) //nolint:gosec // written is never negative
+
+	if a.maxSize > 0 && a.fileSize > a.maxSize {
+		a.rotate()
+	}
+}
+
+// SanitizeArgs replaces the values of sensitive arguments like passwords.
+func (a *AuditLogHandler) SanitizeArgs(args []string) []string {
+	sanitized := make([]string, 0, len(args))
+	for _, arg := range args {
+		name, _, found := strings.Cut(arg, "=")
+		if found && accessUserMatch(a.sensitiveArguments, strings.ToLower(strings.TrimSpace(name))) {
+			arg = name + "=***"
+		}
+		sanitized = append(sanitized, arg)
+	}
+
+	return sanitized
+}
+
+// openFile opens the audit file in append mode, lock must be held.
+func (a *AuditLogHandler) openFile() error {
+	file, err := os.OpenFile(a.fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit log: %s", err.Error())
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("audit log: %s", err.Error())
+	}
+
+	a.file = file
+	a.fileSize = uint64(fileInfo.Size()) //nolint:gosec // file size is never negative
+
+	return nil
+}
+
+// rotate moves the audit file to .old and reopens it, lock must be held.
+func (a *AuditLogHandler) rotate() {
+	log.Debugf("rotating audit log %s (threshold %s)", a.fileName, humanize.IBytes(a.maxSize))
+
+	// remove previously rotated logfile
+	os.Remove(a.fileName + ".old")
+
+	LogError(a.file.Close())
+	a.file = nil
+
+	err := os.Rename(a.fileName, a.fileName+".old")
+	if err != nil {
+		log.Errorf("failed to rename audit log %s %s.old: %s", a.fileName, a.fileName, err.Error())
+	}
+
+	if err := a.openFile(); err != nil {
+		log.Errorf("failed to reopen audit log: %s", err.Error())
+	}
+}
+
+// auditCheck writes an audit entry for a check which has been run by a remote request.
+func (snc *Agent) auditCheck(ctx context.Context, command string, args []string, res *CheckResult, startTime time.Time) {
+	audit := snc.getAuditLog()
+	if audit == nil {
+		return
+	}
+
+	request := auditRequestFromContext(ctx)
+	if request == nil {
+		return
+	}
+
+	audit.Log(&AuditLogEntry{
+		Timestamp:  startTime.Format(time.RFC3339Nano),
+		Listener:   request.listener,
+		RemoteAddr: request.remoteAddr,
+		User:       auditUserName(ctx),
+		ClientCN:   request.clientCN,
+		Command:    command,
+		Args:       audit.SanitizeArgs(args),
+		State:      res.StateString(),
+		Duration:   time.Since(startTime).Seconds(),
+	})
+}
+
+// auditHTTPRequest writes an audit entry for admin api requests, which do not run checks.
+func (snc *Agent) auditHTTPRequest(req *http.Request, statusCode int, startTime time.Time) {
+	audit := snc.getAuditLog()
+	if audit == nil {
+		return
+	}
+
+	request := auditRequestFromContext(req.Context())
+	if request == nil || request.listener != "admin" {
+		return
+	}
+
+	audit.Log(&AuditLogEntry{
+		Timestamp:  startTime.Format(time.RFC3339Nano),
+		Listener:   request.listener,
+		RemoteAddr: request.remoteAddr,
+		User:       auditUserName(req.Context()),
+		ClientCN:   request.clientCN,
+		Command:    req.Method + " " + req.URL.Path,
+		Args:       []string{},
+		State:      fmt.Sprintf("%d", statusCode),
+		Duration:   time.Since(startTime).Seconds(),
+	})
+}
+
+func (snc *Agent) getAuditLog() *AuditLogHandler {
+	if snc.runSet == nil || snc.runSet.tasks == nil {
+		return nil
+	}
+
+	if audit, ok := snc.runSet.tasks.Get("AuditLog").(*AuditLogHandler); ok {
+		return audit
+	}
+
+	return nil
+}
+
+// contextWithAuditRequest returns a new context containing the connection details for the audit log.
+func contextWithAuditRequest(ctx context.Context, listener, remoteAddr string, tlsState *tls.ConnectionState) context.Context {
+	request := &auditRequest{
+		listener:   listener,
+		remoteAddr: remoteAddr,
+	}
+	if tlsState != nil && len(tlsState.PeerCertificates) > 0 {
+		request.clientCN = tlsState.PeerCertificates[0].Subject.CommonName
+	}
+
+	return context.WithValue(ctx, auditRequestContextKey{}, request)
+}
+
+// auditRequestFromContext returns the audit details or nil if the context does not belong to a remote request.
+func auditRequestFromContext(ctx context.Context) *auditRequest {
+	if request, ok := ctx.Value(auditRequestContextKey{}).(*auditRequest); ok {
+		return request
+	}
+
+	return nil
+}
+
+// auditUserName returns the name of the authenticated user or an empty string.
+func auditUserName(ctx context.Context) string {
+	if user := accessUserFromContext(ctx); user != nil {
+		return user.Name
+	}
+
+	return ""
+}
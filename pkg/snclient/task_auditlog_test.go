@@ -0,0 +1,131 @@
+package snclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/nrpe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	config := fmt.Sprintf(`
+[/modules]
+AuditLog = enabled
+NRPEServer = enabled
+WEBServer = enabled
+WEBAdminServer = enabled
+
+[/settings/NRPE/server]
+port = 45667
+use ssl = false
+allow arguments = true
+
+[/settings/WEB/server]
+port = 45666
+use ssl = false
+password = test
+
+[/settings/WEBAdmin/server]
+password = test
+
+[/settings/users/helpdesk]
+password = secret
+allowed commands = check_dummy
+
+[/settings/log/audit]
+file name = %s
+`, auditFile)
+	snc := StartTestAgent(t, config)
+
+	// nrpe request
+	con, err := net.DialTimeout("tcp", "127.0.0.1:45667", 10*time.Second)
+	require.NoErrorf(t, err, "connection established")
+	req := nrpe.BuildPacketV4(nrpe.NrpeQueryPacket, 0, []byte("check_dummy!1!warning text"))
+	require.NoErrorf(t, req.Write(con), "request send")
+	_, err = nrpe.ReadNrpePacket(con)
+	require.NoErrorf(t, err, "response read")
+	con.Close()
+
+	// rest request with user and sensitive arguments
+	httpReq, err := http.NewRequestWithContext(context.TODO(), http.MethodGet,
+		"http://127.0.0.1:45666/api/v1/queries/check_dummy/commands/execute?0&password=topsecret", http.NoBody)
+	require.NoErrorf(t, err, "request created")
+	httpReq.SetBasicAuth("helpdesk", "secret")
+	res, err := snc.httpClient(&HTTPClientOptions{reqTimeout: DefaultSocketTimeout}).Do(httpReq)
+	require.NoErrorf(t, err, "request sent")
+	res.Body.Close()
+
+	// admin request
+	httpReq, err = http.NewRequestWithContext(context.TODO(), http.MethodGet, "http://127.0.0.1:45666/api/v1/admin/unknown", http.NoBody)
+	require.NoErrorf(t, err, "request created")
+	httpReq.Header.Set("password", "test")
+	res, err = snc.httpClient(&HTTPClientOptions{reqTimeout: DefaultSocketTimeout}).Do(httpReq)
+	require.NoErrorf(t, err, "request sent")
+	res.Body.Close()
+
+	// checks run locally are not audited
+	snc.RunCheck("check_dummy", []string{"0"})
+
+	StopTestAgent(t, snc)
+
+	file, err := os.Open(auditFile)
+	require.NoErrorf(t, err, "audit log exists")
+	defer file.Close()
+
+	entries := []AuditLogEntry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := AuditLogEntry{}
+		require.NoErrorf(t, json.Unmarshal(scanner.Bytes(), &entry), "json line")
+		entries = append(entries, entry)
+	}
+	require.Lenf(t, entries, 3, "audit entries")
+
+	assert.Equalf(t, "nrpe", entries[0].Listener, "nrpe listener")
+	assert.Equalf(t, "check_dummy", entries[0].Command, "nrpe command")
+	assert.Equalf(t, []string{"1", "warning text"}, entries[0].Args, "nrpe args")
+	assert.Equalf(t, "WARNING", entries[0].State, "nrpe state")
+	assert.Containsf(t, entries[0].RemoteAddr, "127.0.0.1:", "remote address")
+
+	assert.Equalf(t, "http", entries[1].Listener, "web listener")
+	assert.Equalf(t, "helpdesk", entries[1].User, "authenticated user")
+	assert.Equalf(t, []string{"0", "password=***"}, entries[1].Args, "sanitized args")
+	assert.Equalf(t, "OK", entries[1].State, "web state")
+
+	assert.Equalf(t, "admin", entries[2].Listener, "admin listener")
+	assert.Equalf(t, "GET /api/v1/admin/unknown", entries[2].Command, "admin command")
+	assert.Equalf(t, "404", entries[2].State, "admin status code")
+}
+
+func TestAuditLogRotate(t *testing.T) {
+	audit := &AuditLogHandler{
+		fileName: filepath.Join(t.TempDir(), "audit.log"),
+		maxSize:  200,
+	}
+	require.NoErrorf(t, audit.openFile(), "audit log opened")
+
+	entry := &AuditLogEntry{Command: "check_dummy", Args: []string{"0"}, State: "OK"}
+	audit.Log(entry)
+	_, err := os.Stat(audit.fileName + ".old")
+	require.Truef(t, os.IsNotExist(err), "not yet rotated")
+
+	audit.Log(entry)
+	_, err = os.Stat(audit.fileName + ".old")
+	require.NoErrorf(t, err, "rotated")
+
+	fileInfo, err := os.Stat(audit.fileName)
+	require.NoErrorf(t, err, "new audit log created")
+	assert.Zerof(t, fileInfo.Size(), "new audit log is empty")
+	audit.Stop()
+}
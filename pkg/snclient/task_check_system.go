@@ -24,9 +24,27 @@ const (
 var DefaultSystemTaskConfig = ConfigData{
 	"default buffer length": "15m",
 	"device filter":         "^veth",
+	"disk device filter":    `^(loop|ram|zram)\d+$`,
 	"metrics interval":      "5s",
 }
 
+// diskstats columns stored as counter, see https://www.kernel.org/doc/Documentation/ABI/testing/procfs-diskstats
+var linuxDiskStatsColumns = map[int]string{
+	3:  "reads",
+	5:  "read_bytes", // sectors
+	6:  "read_ms",
+	7:  "writes",
+	9:  "write_bytes", // sectors
+	10: "write_ms",
+	12: "io_ms",
+}
+
+// diskstats always uses 512 byte sectors, regardless of the device
+const linuxDiskStatsSectorSize = 512
+
+// resources with pressure stall information
+var linuxPressureResources = []string{"cpu", "memory", "io"}
+
 type CheckSystemHandler struct {
 	noCopy noCopy
 
@@ -36,6 +54,7 @@ type CheckSystemHandler struct {
 	bufferLength    time.Duration
 	metricsInterval time.Duration
 	deviceFilter    []regexp.Regexp
+	diskFilter      []regexp.Regexp
 }
 
 func NewCheckSystemHandler() Module {
@@ -69,6 +88,14 @@ func (c *CheckSystemHandler) Init(snc *Agent, section *ConfigSection, _ *Config,
 		c.deviceFilter = []regexp.Regexp{*deviceFilter}
 	}
 
+	diskFilter, ok, err := section.GetRegexp("disk device filter")
+	if err != nil {
+		return fmt.Errorf("disk device filter: %s", err.Error())
+	}
+	if ok && diskFilter != nil {
+		c.diskFilter = []regexp.Regexp{*diskFilter}
+	}
+
 	// create counter
 	c.update(true)
 
@@ -124,39 +151,46 @@ func (c *CheckSystemHandler) update(create bool) {
 	c.snc.Counter.Set("cpuinfo", "info", times)
 
 	// add interface traffic data
-	for key, val := range netdata {
+	c.setDeviceCounter("net", netdata, c.deviceFilter)
+
+	if runtime.GOOS == "linux" {
+		c.addLinuxKernelStats(create)
+		c.addLinuxDiskStats()
+		c.addLinuxPressureStats()
+	}
+}
+
+// setDeviceCounter sets the device counters unless filtered and removes devices which disappeared.
+func (c *CheckSystemHandler) setDeviceCounter(category string, data map[string]float64, filter []regexp.Regexp) {
+	for key, val := range data {
 		skipped := false
-		for i := range c.deviceFilter {
-			if c.deviceFilter[i].MatchString(key) {
+		for i := range filter {
+			if filter[i].MatchString(key) {
 				skipped = true
 
 				break
 			}
 		}
 		if skipped {
-			log.Tracef("skipped network device: %s", key)
+			log.Tracef("skipped %s device: %s", category, key)
 
 			continue
 		}
-		if c.snc.Counter.Get("net", key) == nil {
-			c.snc.counterCreate("net", key, c.bufferLength, c.metricsInterval)
+		if c.snc.Counter.Get(category, key) == nil {
+			c.snc.counterCreate(category, key, c.bufferLength, c.metricsInterval)
 		}
-		c.snc.Counter.Set("net", key, val)
+		c.snc.Counter.Set(category, key, val)
 	}
 
-	// remove interface not updated within the bufferLength
+	// remove devices not updated within the bufferLength
 	trimData := time.Now().Add(-c.bufferLength).UnixMilli()
-	for _, key := range c.snc.Counter.Keys("net") {
-		last := c.snc.Counter.Get("net", key).GetLast()
+	for _, key := range c.snc.Counter.Keys(category) {
+		last := c.snc.Counter.Get(category, key).GetLast()
 		if last.UnixMilli < trimData {
-			log.Tracef("removed old net device: %s (last update: %s)", key, time.UnixMilli(last.UnixMilli).String())
-			c.snc.Counter.Delete("net", key)
+			log.Tracef("removed old %s device: %s (last update: %s)", category, key, time.UnixMilli(last.UnixMilli).String())
+			c.snc.Counter.Delete(category, key)
 		}
 	}
-
-	if runtime.GOOS == "linux" {
-		c.addLinuxKernelStats(create)
-	}
 }
 
 func (c *CheckSystemHandler) fetch() (data map[string]float64, cputimes *cpuinfo.TimesStat, netdata map[string]float64, err error) {
@@ -222,3 +256,96 @@ func (c *CheckSystemHandler) addLinuxKernelStats(create bool) {
 		}
 	}
 }
+
+func (c *CheckSystemHandler) addLinuxDiskStats() {
+	content, err := os.ReadFile("/proc/diskstats")
+	if err != nil {
+		log.Tracef("reading /proc/diskstats failed: %s", err.Error())
+
+		return
+	}
+
+	// filter by device name, counter keys contain the column as suffix
+	devices := parseLinuxDiskStats(string(content))
+	diskdata := map[string]float64{}
+	for dev, columns := range devices {
+		skipped := false
+		for i := range c.diskFilter {
+			if c.diskFilter[i].MatchString(dev) {
+				skipped = true
+
+				break
+			}
+		}
+		if skipped {
+			continue
+		}
+		for col, val := range columns {
+			diskdata[dev+"_"+col] = val
+		}
+	}
+
+	c.setDeviceCounter("disk", diskdata, nil)
+}
+
+// parseLinuxDiskStats returns the counter values from /proc/diskstats by device name.
+func parseLinuxDiskStats(content string) map[string]map[string]float64 {
+	devices := map[string]map[string]float64{}
+	for _, line := range strings.Split(content, "\n") {
+		row := strings.Fields(line)
+		if len(row) < 14 {
+			continue
+		}
+
+		columns := map[string]float64{}
+		for idx, name := range linuxDiskStatsColumns {
+			columns[name] = convert.Float64(row[idx])
+		}
+		columns["read_bytes"] *= linuxDiskStatsSectorSize
+		columns["write_bytes"] *= linuxDiskStatsSectorSize
+		devices[row[2]] = columns
+	}
+
+	return devices
+}
+
+// addLinuxPressureStats stores the total stall time from /proc/pressure, ex.: pressure.cpu_some
+func (c *CheckSystemHandler) addLinuxPressureStats() {
+	for _, res := range linuxPressureResources {
+		content, err := os.ReadFile("/proc/pressure/" + res)
+		if err != nil {
+			// kernel without psi support
+			continue
+		}
+
+		for line, values := range parseLinuxPressure(string(content)) {
+			key := res + "_" + line
+			if c.snc.Counter.Get("pressure", key) == nil {
+				c.snc.counterCreate("pressure", key, c.bufferLength, c.metricsInterval)
+			}
+			c.snc.Counter.Set("pressure", key, values["total"])
+		}
+	}
+}
+
+// parseLinuxPressure returns the values of a pressure file by line, ex.: some -> avg10 -> 0.5
+func parseLinuxPressure(content string) map[string]map[string]float64 {
+	lines := map[string]map[string]float64{}
+	for _, line := range strings.Split(content, "\n") {
+		row := strings.Fields(line)
+		if len(row) < 2 {
+			continue
+		}
+
+		values := map[string]float64{}
+		for _, field := range row[1:] {
+			key, val, ok := strings.Cut(field, "=")
+			if ok {
+				values[key] = convert.Float64(val)
+			}
+		}
+		lines[row[0]] = values
+	}
+
+	return lines
+}
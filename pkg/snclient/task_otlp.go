@@ -0,0 +1,409 @@
+package snclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/convert"
+	"github.com/consol-monitoring/snclient/pkg/utils"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	RegisterModule(
+		&AvailableTasks,
+		"OTLPExporter",
+		"/settings/OTLP/exporter",
+		NewOTLPHandler,
+		ConfigInit{
+			ConfigData{
+				"endpoint": "http://127.0.0.1:4317",
+				"protocol": "grpc",
+				"interval": "60s",
+				"headers":  "",
+			},
+			DefaultHTTPClientConfig,
+		},
+	)
+}
+
+// OTLPHandler runs the configured checks periodically and exports their metrics by OTLP.
+type OTLPHandler struct {
+	noCopy noCopy
+
+	snc *Agent
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	endpoint    *url.URL
+	protocol    string
+	interval    time.Duration
+	headers     map[string]string
+	httpOptions *HTTPClientOptions
+	checks      []exportCheck
+
+	grpcConn *grpc.ClientConn
+	resource *resourcepb.Resource
+}
+
+func NewOTLPHandler() Module {
+	return &OTLPHandler{
+		headers: make(map[string]string),
+	}
+}
+
+func (o *OTLPHandler) Init(snc *Agent, section *ConfigSection, conf *Config, _ *AgentRunSet) error {
+	o.snc = snc
+	o.ctx, o.cancel = context.WithCancel(context.Background())
+
+	endpoint, _ := section.GetString("endpoint")
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return fmt.Errorf("endpoint: invalid url %s, expected ex.: http://127.0.0.1:4317", endpoint)
+	}
+	switch endpointURL.Scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("endpoint: unsupported scheme %s, must be http or https", endpointURL.Scheme)
+	}
+	o.endpoint = endpointURL
+
+	protocol, _ := section.GetString("protocol")
+	switch strings.ToLower(protocol) {
+	case "grpc":
+		o.protocol = "grpc"
+	case "http", "http/protobuf":
+		o.protocol = "http"
+	default:
+		return fmt.Errorf("protocol: unsupported protocol %s, must be grpc or http", protocol)
+	}
+
+	interval, _, err := section.GetDuration("interval")
+	if err != nil {
+		return fmt.Errorf("interval: %s", err.Error())
+	}
+	if interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+	o.interval = time.Duration(interval * float64(time.Second))
+
+	// headers use the OTEL_EXPORTER_OTLP_HEADERS format: key1=value1,key2=value2
+	if headers, ok := section.GetString("headers"); ok {
+		for _, header := range strings.Split(headers, ",") {
+			header = strings.TrimSpace(header)
+			if header == "" {
+				continue
+			}
+			key, val, found := strings.Cut(header, "=")
+			if !found {
+				return fmt.Errorf("headers: invalid header %s, expected key=value", header)
+			}
+			o.headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+
+	httpOptions, err := snc.buildClientHTTPOptions(section)
+	if err != nil {
+		return err
+	}
+	o.httpOptions = httpOptions
+
+	checks, err := parseExportChecks(conf.Section("/settings/OTLP/checks"))
+	if err != nil {
+		return err
+	}
+	o.checks = checks
+	if len(o.checks) == 0 {
+		log.Warnf("[OTLP] no checks configured in /settings/OTLP/checks")
+	}
+
+	if o.protocol == "grpc" {
+		creds := insecure.NewCredentials()
+		if o.endpoint.Scheme == "https" {
+			creds = credentials.NewTLS(o.httpOptions.tlsConfig)
+		}
+		conn, err := grpc.NewClient(o.endpoint.Host, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return fmt.Errorf("grpc client: %s", err.Error())
+		}
+		o.grpcConn = conn
+	}
+
+	return nil
+}
+
+func (o *OTLPHandler) Start() error {
+	go o.mainLoop()
+
+	return nil
+}
+
+func (o *OTLPHandler) Stop() {
+	o.cancel()
+	if o.grpcConn != nil {
+		LogDebug(o.grpcConn.Close())
+	}
+}
+
+func (o *OTLPHandler) mainLoop() {
+	defer o.snc.logPanicExit()
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	o.resource = o.buildResource()
+	o.export()
+	for {
+		select {
+		case <-o.ctx.Done():
+			log.Tracef("stopping OTLP mainLoop")
+
+			return
+		case <-ticker.C:
+			o.export()
+		}
+	}
+}
+
+// export runs all checks and sends the metrics to the collector.
+func (o *OTLPHandler) export() {
+	req := o.buildRequest()
+	if o.ctx.Err() != nil {
+		return
+	}
+
+	var err error
+	switch o.protocol {
+	case "grpc":
+		err = o.exportGRPC(req)
+	case "http":
+		err = o.exportHTTP(req)
+	}
+	if err != nil {
+		log.Warnf("[OTLP] exporting metrics to %s failed: %s", o.endpoint.String(), err.Error())
+
+		return
+	}
+
+	log.Debugf("[OTLP] exported metrics of %d checks to %s", len(o.checks), o.endpoint.String())
+}
+
+func (o *OTLPHandler) exportGRPC(req *colmetricspb.ExportMetricsServiceRequest) error {
+	ctx, cancel := context.WithTimeout(o.ctx, time.Duration(o.httpOptions.reqTimeout)*time.Second)
+	defer cancel()
+
+	if len(o.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.headers))
+	}
+
+	client := colmetricspb.NewMetricsServiceClient(o.grpcConn)
+	resp, err := client.Export(ctx, req)
+	if err != nil {
+		return fmt.Errorf("grpc export: %s", err.Error())
+	}
+
+	if partial := resp.GetPartialSuccess(); partial != nil && partial.GetRejectedDataPoints() > 0 {
+		log.Warnf("[OTLP] collector rejected %d data points: %s", partial.GetRejectedDataPoints(), partial.GetErrorMessage())
+	}
+
+	return nil
+}
+
+func (o *OTLPHandler) exportHTTP(req *colmetricspb.ExportMetricsServiceRequest) error {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("protobuf error: %s", err.Error())
+	}
+
+	target := *o.endpoint
+	if target.Path == "" || target.Path == "/" {
+		target.Path = "/v1/metrics"
+	}
+
+	header := map[string]string{
+		"Content-Type": "application/x-protobuf",
+	}
+	for key, val := range o.headers {
+		header[key] = val
+	}
+
+	resp, err := o.snc.httpDoBody(o.ctx, o.httpOptions, http.MethodPost, target.String(), header, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http export failed %s: %s", target.String(), resp.Status)
+	}
+
+	return nil
+}
+
+// buildResource returns the resource attributes describing this agent.
+func (o *OTLPHandler) buildResource() *resourcepb.Resource {
+	version := VERSION
+	if Revision != "" {
+		version = VERSION + "." + Revision
+	}
+	attributes := map[string]string{
+		"service.name":    "snclient",
+		"service.version": version,
+		"host.name":       o.snc.config.DefaultMacros()["hostname"],
+	}
+
+	_, chk := o.snc.runCheck(o.ctx, "check_os_version", []string{}, 0, nil, true)
+	if chk != nil && len(chk.listData) > 0 {
+		osData := chk.listData[0]
+		attributes["os.type"] = osData["os"]
+		attributes["os.name"] = osData["platform"]
+		attributes["os.version"] = osData["version"]
+		attributes["host.arch"] = osData["kernel_arch"]
+	}
+
+	resource := &resourcepb.Resource{}
+	for _, key := range utils.SortedKeys(attributes) {
+		if attributes[key] == "" {
+			continue
+		}
+		resource.Attributes = append(resource.Attributes, otlpStringAttribute(key, attributes[key]))
+	}
+
+	return resource
+}
+
+// buildRequest runs all checks and converts their metrics into an export request.
+func (o *OTLPHandler) buildRequest() *colmetricspb.ExportMetricsServiceRequest {
+	stateMetric := &metricspb.Metric{
+		Name:        "snclient.check.state",
+		Description: "state of configured checks (0 - ok, 1 - warning, 2 - critical, 3 - unknown)",
+		Data:        &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}},
+	}
+	valueMetric := &metricspb.Metric{
+		Name:        "snclient.check.metric",
+		Description: "performance data value of configured checks",
+		Data:        &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}},
+	}
+	warningMetric := &metricspb.Metric{
+		Name:        "snclient.check.metric.warning",
+		Description: "warning threshold of configured checks",
+		Data:        &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}},
+	}
+	criticalMetric := &metricspb.Metric{
+		Name:        "snclient.check.metric.critical",
+		Description: "critical threshold of configured checks",
+		Data:        &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}},
+	}
+
+	mutex := sync.Mutex{}
+	waitGroup := &sync.WaitGroup{}
+	for _, chk := range o.checks {
+		waitGroup.Add(1)
+		go func(chk exportCheck) {
+			defer waitGroup.Done()
+			defer o.snc.logPanicRecover()
+
+			res := o.snc.RunCheckWithContext(o.ctx, chk.command, chk.args, 0, nil)
+			now := uint64(time.Now().UnixNano()) //nolint:gosec // current time is always positive
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			checkAttributes := []*commonpb.KeyValue{
+				otlpStringAttribute("check", chk.name),
+				otlpIntAttribute("state", res.State),
+			}
+			stateMetric.GetGauge().DataPoints = append(stateMetric.GetGauge().DataPoints, otlpDataPoint(now, float64(res.State), checkAttributes))
+
+			seen := map[string]bool{}
+			for _, metric := range res.Metrics {
+				if metric.PerfConfig != nil && metric.PerfConfig.Ignore {
+					continue
+				}
+				label := metric.tweakedName()
+				if seen[label] {
+					continue
+				}
+				seen[label] = true
+
+				num, unit := metric.tweakedNum(metric.Value)
+				value, err := convert.Float64E(num)
+				if err != nil {
+					continue
+				}
+				attributes := append([]*commonpb.KeyValue{
+					otlpStringAttribute("label", label),
+					otlpStringAttribute("unit", unit),
+				}, checkAttributes...)
+				valueMetric.GetGauge().DataPoints = append(valueMetric.GetGauge().DataPoints, otlpDataPoint(now, value, attributes))
+
+				if threshold, ok := exportThreshold(metric, metric.WarningStr, metric.Warning); ok {
+					warningMetric.GetGauge().DataPoints = append(warningMetric.GetGauge().DataPoints, otlpDataPoint(now, threshold, attributes))
+				}
+				if threshold, ok := exportThreshold(metric, metric.CriticalStr, metric.Critical); ok {
+					criticalMetric.GetGauge().DataPoints = append(criticalMetric.GetGauge().DataPoints, otlpDataPoint(now, threshold, attributes))
+				}
+			}
+		}(chk)
+	}
+	waitGroup.Wait()
+
+	metrics := []*metricspb.Metric{stateMetric}
+	for _, metric := range []*metricspb.Metric{valueMetric, warningMetric, criticalMetric} {
+		if len(metric.GetGauge().GetDataPoints()) > 0 {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: o.resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope: &commonpb.InstrumentationScope{
+					Name:    "snclient",
+					Version: VERSION,
+				},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+func otlpDataPoint(timestamp uint64, value float64, attributes []*commonpb.KeyValue) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:   attributes,
+		TimeUnixNano: timestamp,
+		Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+func otlpStringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func otlpIntAttribute(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}},
+	}
+}
@@ -0,0 +1,184 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpTestCollector is a minimal otlp grpc metrics receiver
+type otlpTestCollector struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	requests chan *colmetricspb.ExportMetricsServiceRequest
+	headers  chan metadata.MD
+}
+
+func (c *otlpTestCollector) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.headers <- md
+	c.requests <- req
+
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestOTLPExportGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoErrorf(t, err, "grpc listener started")
+
+	collector := &otlpTestCollector{
+		requests: make(chan *colmetricspb.ExportMetricsServiceRequest, 10),
+		headers:  make(chan metadata.MD, 10),
+	}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, collector)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	config := fmt.Sprintf(`
+[/modules]
+OTLPExporter = enabled
+
+[/settings/OTLP/exporter]
+endpoint = http://%s
+protocol = grpc
+headers = x-api-key=secret
+
+[/settings/OTLP/checks]
+load = check_load "warn=load > 500" "crit=load > 1000"
+check_dummy =
+`, listener.Addr().String())
+	snc := StartTestAgent(t, config)
+
+	var req *colmetricspb.ExportMetricsServiceRequest
+	select {
+	case req = <-collector.requests:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no otlp request received")
+	}
+	md := <-collector.headers
+	assert.Equalf(t, []string{"secret"}, md.Get("x-api-key"), "header sent")
+
+	otlpTestAssertRequest(t, req)
+
+	StopTestAgent(t, snc)
+}
+
+func TestOTLPExportHTTP(t *testing.T) {
+	requests := make(chan *colmetricspb.ExportMetricsServiceRequest, 10)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/metrics" || req.Header.Get("Content-Type") != "application/x-protobuf" {
+			res.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+		data, _ := io.ReadAll(req.Body)
+		export := &colmetricspb.ExportMetricsServiceRequest{}
+		if proto.Unmarshal(data, export) == nil {
+			requests <- export
+		}
+		res.Header().Set("Content-Type", "application/x-protobuf")
+		data, _ = proto.Marshal(&colmetricspb.ExportMetricsServiceResponse{})
+		_, _ = res.Write(data)
+	}))
+	defer httpServer.Close()
+
+	config := fmt.Sprintf(`
+[/modules]
+OTLPExporter = enabled
+
+[/settings/OTLP/exporter]
+endpoint = %s
+protocol = http
+
+[/settings/OTLP/checks]
+load = check_load "warn=load > 500" "crit=load > 1000"
+check_dummy =
+`, httpServer.URL)
+	snc := StartTestAgent(t, config)
+
+	var req *colmetricspb.ExportMetricsServiceRequest
+	select {
+	case req = <-requests:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no otlp request received")
+	}
+
+	otlpTestAssertRequest(t, req)
+
+	StopTestAgent(t, snc)
+}
+
+func otlpTestAssertRequest(t *testing.T, req *colmetricspb.ExportMetricsServiceRequest) {
+	t.Helper()
+
+	require.Lenf(t, req.GetResourceMetrics(), 1, "resource metrics")
+	resourceMetrics := req.GetResourceMetrics()[0]
+
+	resource := otlpTestAttributes(resourceMetrics.GetResource().GetAttributes())
+	assert.Equalf(t, "snclient", resource["service.name"], "service name")
+	assert.NotEmptyf(t, resource["host.name"], "host name")
+	assert.NotEmptyf(t, resource["os.type"], "os type")
+
+	require.Lenf(t, resourceMetrics.GetScopeMetrics(), 1, "scope metrics")
+	metrics := map[string]*metricspb.Metric{}
+	for _, metric := range resourceMetrics.GetScopeMetrics()[0].GetMetrics() {
+		metrics[metric.GetName()] = metric
+	}
+
+	require.Containsf(t, metrics, "snclient.check.state", "state metric")
+	states := map[string]float64{}
+	for _, point := range metrics["snclient.check.state"].GetGauge().GetDataPoints() {
+		states[otlpTestAttributes(point.GetAttributes())["check"]] = point.GetAsDouble()
+	}
+	assert.Equalf(t, map[string]float64{"check_dummy": 0, "load": 0}, states, "check states")
+
+	require.Containsf(t, metrics, "snclient.check.metric", "value metric")
+	labels := []string{}
+	for _, point := range metrics["snclient.check.metric"].GetGauge().GetDataPoints() {
+		attributes := otlpTestAttributes(point.GetAttributes())
+		assert.Equalf(t, "load", attributes["check"], "check attribute")
+		assert.Equalf(t, "0", attributes["state"], "state attribute")
+		assert.Containsf(t, attributes, "unit", "unit attribute")
+		labels = append(labels, attributes["label"])
+	}
+	assert.ElementsMatchf(t, []string{"load1", "load5", "load15"}, labels, "metric labels")
+
+	require.Containsf(t, metrics, "snclient.check.metric.warning", "warning metric")
+	for _, point := range metrics["snclient.check.metric.warning"].GetGauge().GetDataPoints() {
+		assert.InDeltaf(t, 500, point.GetAsDouble(), 0, "warning threshold")
+	}
+	require.Containsf(t, metrics, "snclient.check.metric.critical", "critical metric")
+	for _, point := range metrics["snclient.check.metric.critical"].GetGauge().GetDataPoints() {
+		assert.InDeltaf(t, 1000, point.GetAsDouble(), 0, "critical threshold")
+	}
+}
+
+func otlpTestAttributes(attributes []*commonpb.KeyValue) map[string]string {
+	res := map[string]string{}
+	for _, attr := range attributes {
+		switch val := attr.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			res[attr.GetKey()] = val.StringValue
+		case *commonpb.AnyValue_IntValue:
+			res[attr.GetKey()] = fmt.Sprintf("%d", val.IntValue)
+		}
+	}
+
+	return res
+}
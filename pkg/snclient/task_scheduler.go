@@ -0,0 +1,335 @@
+package snclient
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/utils"
+)
+
+func init() {
+	RegisterModule(
+		&AvailableTasks,
+		"Scheduler",
+		"/settings/scheduler",
+		NewSchedulerHandler,
+		ConfigInit{
+			ConfigData{
+				"interval":   "5m",
+				"hostname":   "${hostname}",
+				"target":     "",
+				"type":       "nsca",
+				"encryption": "none",
+				"timeout":    "30",
+			},
+			DefaultHTTPClientConfig,
+		},
+	)
+}
+
+// SchedulerHandler runs checks periodically, caches the last result and submits it to passive receivers.
+type SchedulerHandler struct {
+	noCopy noCopy
+
+	snc *Agent
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	schedules []*schedulerEntry
+	targets   map[string]passiveTarget
+
+	results     map[string]*schedulerResult
+	resultsLock sync.RWMutex
+}
+
+// schedulerEntry contains a single scheduled check.
+type schedulerEntry struct {
+	name       string
+	command    string
+	args       []string
+	interval   time.Duration
+	staleAfter time.Duration
+	hostname   string
+	service    string
+	targets    []string
+}
+
+// schedulerResult contains the last result of a scheduled check.
+type schedulerResult struct {
+	result   *CheckResult
+	timezone *time.Location
+	lastRun  time.Time
+}
+
+func NewSchedulerHandler() Module {
+	return &SchedulerHandler{
+		targets: make(map[string]passiveTarget),
+		results: make(map[string]*schedulerResult),
+	}
+}
+
+func (s *SchedulerHandler) Init(snc *Agent, section *ConfigSection, conf *Config, runSet *AgentRunSet) error {
+	s.snc = snc
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	// merge schedule shortcuts into separate config sections
+	shortcuts := conf.Section("/settings/scheduler/schedules")
+	for name := range shortcuts.data {
+		schedConf := conf.Section("/settings/scheduler/schedules/" + name)
+		if !schedConf.HasKey("command") {
+			raw, _, _ := shortcuts.GetStringRaw(name)
+			schedConf.Set("command", raw)
+		}
+	}
+
+	for sectionName := range conf.SectionsByPrefix("/settings/scheduler/targets/") {
+		name := path.Base(sectionName)
+		if name == "default" {
+			continue
+		}
+		target, err := newPassiveTarget(snc, conf.Section(sectionName))
+		if err != nil {
+			return fmt.Errorf("target %s: %s", name, err.Error())
+		}
+		s.targets[name] = target
+	}
+
+	for sectionName := range conf.SectionsByPrefix("/settings/scheduler/schedules/") {
+		name := path.Base(sectionName)
+		if name == "default" {
+			continue
+		}
+		entry, err := s.newSchedule(name, conf.Section(sectionName))
+		if err != nil {
+			return fmt.Errorf("schedule %s: %s", name, err.Error())
+		}
+		s.schedules = append(s.schedules, entry)
+	}
+	sort.Slice(s.schedules, func(i, j int) bool { return s.schedules[i].name < s.schedules[j].name })
+
+	if len(s.schedules) == 0 {
+		log.Warnf("[Scheduler] %s: no schedules configured", section.name)
+	}
+
+	// make cached results available as commands
+	for _, entry := range s.schedules {
+		name := entry.name
+		_, isAlias := runSet.cmdAliases[name]
+		_, isWrap := runSet.cmdWraps[name]
+		_, isCheck := AvailableChecks[name]
+		if isAlias || isWrap || isCheck {
+			return fmt.Errorf("schedule %s: name conflicts with existing command", name)
+		}
+		runSet.cmdAliases[name] = CheckEntry{name, func() CheckHandler { return &CheckScheduled{name: name, scheduler: s} }}
+	}
+
+	return nil
+}
+
+func (s *SchedulerHandler) newSchedule(name string, section *ConfigSection) (*schedulerEntry, error) {
+	entry := &schedulerEntry{
+		name:    name,
+		service: name,
+	}
+
+	command, _, ok := section.GetStringRaw("command")
+	if !ok || strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("missing command")
+	}
+	args := utils.Tokenize(command)
+	args, err := utils.TrimQuotesList(args)
+	if err != nil {
+		return nil, fmt.Errorf("command: %s", err.Error())
+	}
+	entry.command = args[0]
+	entry.args = args[1:]
+
+	interval, _, err := section.GetDuration("interval")
+	if err != nil {
+		return nil, fmt.Errorf("interval: %s", err.Error())
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be greater than zero")
+	}
+	entry.interval = time.Duration(interval * float64(time.Second))
+
+	// cached results are considered stale after 3 intervals by default
+	entry.staleAfter = 3 * entry.interval
+	staleAfter, ok, err := section.GetDuration("stale after")
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("stale after: %s", err.Error())
+	case ok && staleAfter > 0:
+		entry.staleAfter = time.Duration(staleAfter * float64(time.Second))
+	}
+
+	if hostname, ok := section.GetString("hostname"); ok {
+		entry.hostname = hostname
+	}
+	if service, ok := section.GetString("service"); ok && service != "" {
+		entry.service = service
+	}
+
+	if targets, ok := section.GetString("target"); ok {
+		for _, target := range strings.Split(targets, ",") {
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue
+			}
+			if _, ok := s.targets[target]; !ok {
+				return nil, fmt.Errorf("unknown target: %s", target)
+			}
+			entry.targets = append(entry.targets, target)
+		}
+	}
+	if len(entry.targets) == 0 {
+		for target := range s.targets {
+			entry.targets = append(entry.targets, target)
+		}
+		sort.Strings(entry.targets)
+	}
+	if len(entry.targets) == 0 {
+		log.Warnf("[Scheduler] schedule %s: no targets configured, results will only be available from the local cache", name)
+	}
+
+	log.Tracef("registered schedule: %s -> %s (every %s)", name, command, entry.interval)
+
+	return entry, nil
+}
+
+func (s *SchedulerHandler) Start() error {
+	for _, entry := range s.schedules {
+		go s.mainLoop(entry)
+	}
+
+	return nil
+}
+
+func (s *SchedulerHandler) Stop() {
+	s.cancel()
+}
+
+func (s *SchedulerHandler) mainLoop(entry *schedulerEntry) {
+	defer s.snc.logPanicExit()
+
+	ticker := time.NewTicker(entry.interval)
+	defer ticker.Stop()
+
+	s.runSchedule(entry)
+	for {
+		select {
+		case <-s.ctx.Done():
+			log.Tracef("stopping schedule %s", entry.name)
+
+			return
+		case <-ticker.C:
+			s.runSchedule(entry)
+		}
+	}
+}
+
+// runSchedule executes the check, caches the result and submits it to all targets.
+func (s *SchedulerHandler) runSchedule(entry *schedulerEntry) {
+	log.Tracef("[Scheduler] running %s: %s %s", entry.name, entry.command, entry.args)
+	res, chk := s.snc.runCheck(s.ctx, entry.command, entry.args, 0, nil, false)
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	cached := &schedulerResult{
+		result:  res,
+		lastRun: time.Now(),
+	}
+	if chk != nil {
+		cached.timezone = chk.timezone
+	}
+	s.resultsLock.Lock()
+	s.results[entry.name] = cached
+	s.resultsLock.Unlock()
+
+	if len(entry.targets) == 0 {
+		return
+	}
+
+	final := cached.result.Clone()
+	final.Finalize(cached.timezone)
+	result := newPassiveResult(entry, final)
+	for _, name := range entry.targets {
+		err := s.targets[name].Submit(s.ctx, result)
+		if err != nil {
+			log.Warnf("[Scheduler] submitting %s to %s failed: %s", entry.name, name, err.Error())
+
+			continue
+		}
+		log.Debugf("[Scheduler] submitted %s to %s: %s", entry.name, name, result.Output)
+	}
+}
+
+// GetResult returns the cached result for given schedule along with the timezone used to finalize it.
+// The result will be UNKNOWN if there is no result yet or if it is stale.
+func (s *SchedulerHandler) GetResult(name string) (res *CheckResult, timezone *time.Location, ok bool) {
+	idx := slices.IndexFunc(s.schedules, func(e *schedulerEntry) bool { return e.name == name })
+	if idx == -1 {
+		return nil, nil, false
+	}
+	entry := s.schedules[idx]
+
+	s.resultsLock.RLock()
+	cached := s.results[name]
+	s.resultsLock.RUnlock()
+
+	timezone, _ = time.LoadLocation("Local")
+	if cached == nil {
+		return &CheckResult{
+			State:  CheckExitUnknown,
+			Output: fmt.Sprintf("${status} - no result for scheduled check %s available yet", name),
+		}, timezone, true
+	}
+	if cached.timezone != nil {
+		timezone = cached.timezone
+	}
+
+	age := time.Since(cached.lastRun)
+	if age > entry.staleAfter {
+		return &CheckResult{
+			State: CheckExitUnknown,
+			Output: fmt.Sprintf("${status} - last result for scheduled check %s is outdated (last run %s ago)",
+				name, utils.DurationString(age.Truncate(time.Second))),
+		}, timezone, true
+	}
+
+	return cached.result.Clone(), timezone, true
+}
+
+// LastRun returns the time of the last run for given schedule.
+func (s *SchedulerHandler) LastRun(name string) (lastRun time.Time, ok bool) {
+	s.resultsLock.RLock()
+	defer s.resultsLock.RUnlock()
+
+	cached, ok := s.results[name]
+	if !ok {
+		return lastRun, false
+	}
+
+	return cached.lastRun, true
+}
+
+// getScheduler returns the scheduler task or nil if not enabled.
+func (snc *Agent) getScheduler() *SchedulerHandler {
+	if snc.runSet == nil || snc.runSet.tasks == nil {
+		return nil
+	}
+
+	if scheduler, ok := snc.runSet.tasks.Get("Scheduler").(*SchedulerHandler); ok {
+		return scheduler
+	}
+
+	return nil
+}
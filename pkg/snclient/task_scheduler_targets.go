@@ -0,0 +1,186 @@
+package snclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/nsca"
+)
+
+// DefaultNSCAPort sets the default port for nsca targets
+const DefaultNSCAPort = "5667"
+
+// passiveTarget receives passive check results
+type passiveTarget interface {
+	Submit(ctx context.Context, result *passiveResult) error
+}
+
+// passiveResult contains a check result which will be submitted to a passive target
+type passiveResult struct {
+	Hostname  string `json:"hostname"`
+	Service   string `json:"service"`
+	Command   string `json:"command"`
+	State     int64  `json:"state"`
+	Output    string `json:"output"`
+	Perfdata  string `json:"perfdata"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func newPassiveResult(entry *schedulerEntry, res *CheckResult) *passiveResult {
+	output := res.Output
+	if res.Details != "" {
+		output += "\n" + res.Details
+	}
+	perf := make([]string, 0, len(res.Metrics))
+	for _, m := range res.Metrics {
+		perf = append(perf, m.String())
+	}
+
+	return &passiveResult{
+		Hostname:  entry.hostname,
+		Service:   entry.service,
+		Command:   entry.command,
+		State:     res.State,
+		Output:    output,
+		Perfdata:  strings.Join(perf, " "),
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// PluginOutput returns output and performance data in the usual plugin format
+func (r *passiveResult) PluginOutput() string {
+	if r.Perfdata == "" {
+		return r.Output
+	}
+	lines := strings.SplitN(r.Output, "\n", 2)
+	lines[0] += " |" + r.Perfdata
+
+	return strings.Join(lines, "\n")
+}
+
+func newPassiveTarget(snc *Agent, section *ConfigSection) (passiveTarget, error) {
+	targetType, _ := section.GetString("type")
+	switch strings.ToLower(targetType) {
+	case "nsca":
+		return newPassiveTargetNSCA(section)
+	case "http":
+		return newPassiveTargetHTTP(snc, section)
+	default:
+		return nil, fmt.Errorf("unknown type: %s (supported are: nsca, http)", targetType)
+	}
+}
+
+// passiveTargetNSCA sends results by the nsca v2 protocol
+type passiveTargetNSCA struct {
+	address    string
+	encryption int
+	password   string
+	timeout    time.Duration
+}
+
+func newPassiveTargetNSCA(section *ConfigSection) (*passiveTargetNSCA, error) {
+	target := &passiveTargetNSCA{}
+
+	address, _ := section.GetString("address")
+	if address == "" {
+		return nil, fmt.Errorf("missing address")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, DefaultNSCAPort)
+	}
+	target.address = address
+
+	encryption, _ := section.GetString("encryption")
+	method, err := nsca.ParseEncryption(encryption)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %s", err.Error())
+	}
+	target.encryption = method
+
+	if password, ok := section.GetString("password"); ok {
+		target.password = password
+	}
+
+	timeout, _, err := section.GetDuration("timeout")
+	if err != nil {
+		return nil, fmt.Errorf("timeout: %s", err.Error())
+	}
+	target.timeout = time.Duration(timeout * float64(time.Second))
+
+	return target, nil
+}
+
+func (t *passiveTargetNSCA) Submit(ctx context.Context, result *passiveResult) error {
+	dialer := &net.Dialer{Timeout: t.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return fmt.Errorf("connecting to %s failed: %s", t.address, err.Error())
+	}
+	defer conn.Close()
+
+	if t.timeout > 0 {
+		LogDebug(conn.SetDeadline(time.Now().Add(t.timeout)))
+	}
+
+	return nsca.Send(conn, t.encryption, t.password, &nsca.Packet{
+		State:    uint16(result.State), //nolint:gosec // state is always between 0 and 3
+		Hostname: result.Hostname,
+		Service:  result.Service,
+		Output:   result.PluginOutput(),
+	})
+}
+
+// passiveTargetHTTP sends results as json by http post requests
+type passiveTargetHTTP struct {
+	snc         *Agent
+	url         string
+	httpOptions *HTTPClientOptions
+}
+
+func newPassiveTargetHTTP(snc *Agent, section *ConfigSection) (*passiveTargetHTTP, error) {
+	target := &passiveTargetHTTP{snc: snc}
+
+	url, _ := section.GetString("url")
+	if url == "" {
+		return nil, fmt.Errorf("missing url")
+	}
+	target.url = url
+
+	httpOptions, err := snc.buildClientHTTPOptions(section)
+	if err != nil {
+		return nil, err
+	}
+	target.httpOptions = httpOptions
+
+	return target, nil
+}
+
+func (t *passiveTargetHTTP) Submit(ctx context.Context, result *passiveResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("json error: %s", err.Error())
+	}
+
+	header := map[string]string{
+		"Content-Type": "application/json",
+	}
+	resp, err := t.snc.httpDoBody(ctx, t.httpOptions, http.MethodPost, t.url, header, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http post failed %s: %s", t.url, resp.Status)
+	}
+
+	return nil
+}
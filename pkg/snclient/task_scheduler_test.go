@@ -0,0 +1,234 @@
+package snclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/consol-monitoring/snclient/pkg/nrpe"
+	"github.com/consol-monitoring/snclient/pkg/nsca"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerPassiveSubmission(t *testing.T) {
+	// nsca stand-in receiver
+	nscaListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoErrorf(t, err, "nsca listener started")
+	defer nscaListener.Close()
+
+	nscaResults := make(chan *nsca.Packet, 10)
+	go func() {
+		for {
+			conn, err2 := nscaListener.Accept()
+			if err2 != nil {
+				return
+			}
+			initPacket := &nsca.InitPacket{IV: make([]byte, nsca.InitPacketIVSize), Timestamp: uint32(time.Now().Unix())} //nolint:gosec // timestamp fits
+			_, _ = conn.Write(initPacket.Bytes())
+			data := make([]byte, nsca.PacketLength)
+			_, err2 = io.ReadFull(conn, data)
+			conn.Close()
+			if err2 != nil {
+				continue
+			}
+			if nsca.Decrypt(data, nsca.EncryptXOR, initPacket.IV, "secret") != nil {
+				continue
+			}
+			packet, err2 := nsca.ParsePacket(data)
+			if err2 == nil {
+				nscaResults <- packet
+			}
+		}
+	}()
+
+	// http json stand-in receiver
+	httpResults := make(chan map[string]interface{}, 10)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		result := map[string]interface{}{}
+		if json.NewDecoder(req.Body).Decode(&result) == nil {
+			httpResults <- result
+		}
+		res.WriteHeader(http.StatusAccepted)
+	}))
+	defer httpServer.Close()
+
+	config := fmt.Sprintf(`
+[/modules]
+Scheduler = enabled
+
+[/settings/scheduler]
+hostname = testhost
+
+[/settings/scheduler/targets/nsca]
+address = %s
+encryption = xor
+password = secret
+
+[/settings/scheduler/targets/web]
+type = http
+url = %s/results
+
+[/settings/scheduler/schedules]
+dummy = check_dummy 1 "warning text"
+
+[/settings/scheduler/schedules/crit]
+command = check_dummy 2 "service is down"
+service = Critical Service
+interval = 1h
+target = web
+`, nscaListener.Addr().String(), httpServer.URL)
+	snc := StartTestAgent(t, config)
+
+	var received *nsca.Packet
+	select {
+	case received = <-nscaResults:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no nsca result received")
+	}
+	assert.Equalf(t, &nsca.Packet{
+		Timestamp: received.Timestamp,
+		State:     1,
+		Hostname:  "testhost",
+		Service:   "dummy",
+		Output:    "warning text",
+	}, received, "nsca result")
+
+	httpReceived := map[string]map[string]interface{}{}
+	for len(httpReceived) < 2 {
+		select {
+		case result := <-httpResults:
+			httpReceived[result["service"].(string)] = result
+		case <-time.After(10 * time.Second):
+			t.Fatalf("no http result received")
+		}
+	}
+	assert.Equalf(t, "testhost", httpReceived["Critical Service"]["hostname"], "http hostname")
+	assert.InDeltaf(t, 2, httpReceived["Critical Service"]["state"], 0, "http state")
+	assert.Equalf(t, "service is down", httpReceived["Critical Service"]["output"], "http output")
+	assert.Equalf(t, "check_dummy", httpReceived["Critical Service"]["command"], "http command")
+	assert.Equalf(t, "warning text", httpReceived["dummy"]["output"], "http output")
+
+	StopTestAgent(t, snc)
+}
+
+func TestSchedulerResultCache(t *testing.T) {
+	config := `
+[/modules]
+Scheduler = enabled
+NRPEServer = enabled
+WEBServer = enabled
+
+[/settings/NRPE/server]
+port = 45667
+use ssl = false
+
+[/settings/WEB/server]
+port = 45668
+use ssl = false
+password = test
+
+[/settings/scheduler/schedules/slow]
+command = check_dummy 1 "cached result"
+interval = 1h
+stale after = 2s
+`
+	snc := StartTestAgent(t, config)
+
+	// wait for first run
+	scheduler := snc.getScheduler()
+	require.NotNilf(t, scheduler, "scheduler is running")
+	require.Eventuallyf(t, func() bool {
+		_, ok := scheduler.LastRun("slow")
+		return ok
+	}, 5*time.Second, 50*time.Millisecond, "schedule has been run")
+
+	res := snc.RunCheck("slow", []string{})
+	assert.Equalf(t, CheckExitWarning, res.State, "state warning")
+	assert.Equalf(t, "cached result", string(res.BuildPluginOutput()), "cached output")
+
+	// cached results are available through nrpe
+	con, err := net.DialTimeout("tcp", "127.0.0.1:45667", 10*time.Second)
+	require.NoErrorf(t, err, "connection established")
+	req := nrpe.BuildPacketV4(nrpe.NrpeQueryPacket, 0, []byte("slow"))
+	require.NoErrorf(t, req.Write(con), "request send")
+	nrpeRes, err := nrpe.ReadNrpePacket(con)
+	require.NoErrorf(t, err, "response read")
+	output, _ := nrpeRes.Data()
+	assert.Equalf(t, "cached result", output, "nrpe response")
+	con.Close()
+
+	// and through the rest api
+	result := map[string]interface{}{}
+	status := schedulerTestRequest(t, snc, "http://127.0.0.1:45668/api/v1/results/slow", &result)
+	assert.Equalf(t, http.StatusOK, status, "http status")
+	assert.Equalf(t, "slow", result["command"], "command name")
+	assert.InDeltaf(t, 1, result["result"], 0, "result state")
+	assert.Contains(t, fmt.Sprintf("%v", result["lines"]), "cached result")
+
+	status = schedulerTestRequest(t, snc, "http://127.0.0.1:45668/api/v1/results/unknown", &result)
+	assert.Equalf(t, http.StatusNotFound, status, "http status")
+
+	// stale results turn unknown
+	time.Sleep(2100 * time.Millisecond)
+	res = snc.RunCheck("slow", []string{})
+	assert.Equalf(t, CheckExitUnknown, res.State, "state unknown")
+	assert.Contains(t, string(res.BuildPluginOutput()), "UNKNOWN - last result for scheduled check slow is outdated")
+
+	StopTestAgent(t, snc)
+}
+
+func TestSchedulerTargets(t *testing.T) {
+	sched, ok := NewSchedulerHandler().(*SchedulerHandler)
+	require.Truef(t, ok, "scheduler created")
+	sched.targets["web"] = &passiveTargetHTTP{}
+
+	conf := NewConfig(false)
+	section := conf.Section("/settings/scheduler/schedules/test")
+	section.Set("command", "check_dummy 0")
+	section.Set("interval", "1m")
+
+	entry, err := sched.newSchedule("test", section)
+	require.NoErrorf(t, err, "schedule created")
+	assert.Equalf(t, []string{"web"}, entry.targets, "all targets used by default")
+
+	section.Set("target", "web, typo")
+	_, err = sched.newSchedule("test", section)
+	require.Errorf(t, err, "unknown target rejected")
+	assert.Contains(t, err.Error(), "unknown target: typo")
+}
+
+func TestSchedulerResultTimezone(t *testing.T) {
+	sched, ok := NewSchedulerHandler().(*SchedulerHandler)
+	require.Truef(t, ok, "scheduler created")
+	sched.schedules = []*schedulerEntry{{name: "test", staleAfter: time.Minute}}
+	sched.results["test"] = &schedulerResult{
+		result:   &CheckResult{State: CheckExitOK, Output: "ok"},
+		timezone: time.UTC,
+		lastRun:  time.Now(),
+	}
+
+	res, timezone, found := sched.GetResult("test")
+	require.Truef(t, found, "result found")
+	assert.Equalf(t, "ok", res.Output, "cached output")
+	assert.Equalf(t, time.UTC, timezone, "timezone from cached check")
+}
+
+func schedulerTestRequest(t *testing.T, snc *Agent, url string, result *map[string]interface{}) int {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, http.NoBody)
+	require.NoErrorf(t, err, "request created")
+	req.Header.Set("password", "test")
+	res, err := snc.httpClient(&HTTPClientOptions{reqTimeout: DefaultSocketTimeout}).Do(req)
+	require.NoErrorf(t, err, "request sent")
+	defer res.Body.Close()
+	require.NoErrorf(t, json.NewDecoder(res.Body).Decode(result), "json response")
+
+	return res.StatusCode
+}
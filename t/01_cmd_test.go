@@ -34,10 +34,16 @@ func TestCommandFlags(t *testing.T) {
 
 	runCmd(t, &cmd{
 		Cmd:  bin,
-		Args: []string{"hash", "test"},
+		Args: []string{"hash", "--algorithm=sha256", "test"},
 		Like: []string{`hash sum: SHA256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08`},
 	})
 
+	runCmd(t, &cmd{
+		Cmd:  bin,
+		Args: []string{"hash", "test"},
+		Like: []string{`hash sum: bcrypt:\$2a\$10\$`},
+	})
+
 	runCmd(t, &cmd{
 		Cmd:  bin,
 		Args: []string{"inventory"},